@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// AccessTokenRepository is the repository interface for personal access token
+type AccessTokenRepository interface {
+	AddAccessToken(token *models.AccessToken) error
+	GetAccessTokensByUserID(userID uint) ([]models.AccessToken, error)
+	GetAccessTokenByHash(tokenHash string, token *models.AccessToken) error
+	DeleteAccessToken(id uint, userID uint) error
+	TouchAccessToken(id uint, usedAt time.Time) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.AccessToken{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewAccessTokenRepository create a new personal access token repository to fiddle around with database
+func NewAccessTokenRepository(db *gorm.DB) AccessTokenRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddAccessToken returns an error if there is any, otherwise creates a new token record into database
+func (r *repo) AddAccessToken(token *models.AccessToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetAccessTokensByUserID returns the user's tokens, newest first
+func (r *repo) GetAccessTokensByUserID(userID uint) ([]models.AccessToken, error) {
+	var tokens []models.AccessToken
+
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error
+
+	return tokens, err
+}
+
+// GetAccessTokenByHash returns an error if there is any, otherwise modifies the token parameter with the found record
+func (r *repo) GetAccessTokenByHash(tokenHash string, token *models.AccessToken) error {
+	return r.db.Where("token_hash = ?", tokenHash).First(token).Error
+}
+
+// DeleteAccessToken revokes one of the user's tokens, returns an error if the user has no such token
+func (r *repo) DeleteAccessToken(id uint, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.AccessToken{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error: not found \"access_tokens_id_key\"")
+	}
+
+	return nil
+}
+
+// TouchAccessToken records when the token was last used
+func (r *repo) TouchAccessToken(id uint, usedAt time.Time) error {
+	return r.db.Model(&models.AccessToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	accessTokenRepo AccessTokenRepository
+	mock            sqlmock.Sqlmock
+	db              *sql.DB
+	gdb             *gorm.DB
+	err             error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	accessTokenRepo = NewAccessTokenRepository(gdb)
+}
+
+func TestAddAccessToken(t *testing.T) {
+	setup()
+
+	expiresAt := time.Now().Add(time.Hour)
+	token := models.AccessToken{UserID: 1, Name: "ci", TokenHash: "hash", Scopes: "posts:read", ExpiresAt: expiresAt}
+	const sqlInsert = `INSERT INTO "access_tokens" ("user_id","name","token_hash","scopes","expires_at","last_used_at","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "access_tokens"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, "ci", "hash", "posts:read", expiresAt, nil, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := accessTokenRepo.AddAccessToken(&token)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(1), token.ID)
+}
+
+func TestGetAccessTokensByUserID(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "access_tokens" WHERE (user_id = $1) ORDER BY created_at desc`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(2, 1, "ci").AddRow(1, 1, "backup"))
+
+	tokens, err := accessTokenRepo.GetAccessTokensByUserID(1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tokens))
+	assert.Equal(t, "ci", tokens[0].Name)
+}
+
+func TestGetAccessTokenByHash(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "access_tokens" WHERE (token_hash = $1) ORDER BY "access_tokens"."id" ASC LIMIT 1`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("hash").WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scopes"}).AddRow(1, 7, "posts:read"))
+
+	var token models.AccessToken
+	err := accessTokenRepo.GetAccessTokenByHash("hash", &token)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(7), token.UserID)
+}
+
+func TestDeleteAccessToken(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "access_tokens"  WHERE (id = $1 AND user_id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := accessTokenRepo.DeleteAccessToken(3, 1)
+
+	assert.Nil(t, err)
+}
+
+func TestDeleteAccessTokenOfOtherUser(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "access_tokens"  WHERE (id = $1 AND user_id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := accessTokenRepo.DeleteAccessToken(3, 2)
+
+	assert.Equal(t, "error: not found \"access_tokens_id_key\"", err.Error())
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// AuditRepository is the repository interface for audit log
+type AuditRepository interface {
+	AddAuditLog(entry *models.AuditLog) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.AuditLog{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository create a new audit repository to fiddle around with database
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddAuditLog returns an error if there is any, otherwise creates a new audit log record into database
+func (r *repo) AddAuditLog(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
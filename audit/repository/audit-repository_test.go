@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	auditRepo AuditRepository
+	mock      sqlmock.Sqlmock
+	db        *sql.DB
+	gdb       *gorm.DB
+	err       error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	auditRepo = NewAuditRepository(gdb)
+}
+
+func TestAddAuditLog(t *testing.T) {
+	setup()
+
+	entry := models.AuditLog{UserID: 1, Action: "login_lockout", IP: "127.0.0.1", Detail: "locked"}
+	newID := uint(1)
+	const sqlInsert = `INSERT INTO "audit_logs" ("user_id","action","ip","detail","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "audit_logs"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(entry.UserID, entry.Action, entry.IP, entry.Detail, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(newID))
+	mock.ExpectCommit()
+
+	err := auditRepo.AddAuditLog(&entry)
+
+	assert.Nil(t, err)
+	assert.Equal(t, newID, entry.ID)
+}
@@ -0,0 +1,66 @@
+// Package blobstore stores uploaded files such as post images, either on the local filesystem or in an S3-compatible
+// object store
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// BlobStore keeps blobs by key, keys are slash separated paths like "posts/1/abc.jpg"
+type BlobStore interface {
+	Put(key string, body io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Open(key string) (Object, error)
+	Delete(key string) error
+}
+
+// Object is an opened blob that can be read from any offset, which is what serving Range requests takes
+type Object interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// ErrNotFound is returned by Get and Open when there is no blob with the key
+var ErrNotFound = errors.New("blob not found")
+
+// NewBlobStore returns the store configured by the environment, BLOB_STORE=s3 stores blobs in the S3_BUCKET bucket,
+// anything else stores them in the BLOB_DIR directory ("uploads" if unset)
+func NewBlobStore() BlobStore {
+	if os.Getenv("BLOB_STORE") == "s3" {
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	}
+
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+
+	return NewLocalStore(dir)
+}
+
+// validKey rejects keys that could escape the store, like absolute paths or paths with ".." segments
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ErrInvalidKey is returned for keys that are empty or not a clean relative path
+var ErrInvalidKey = errors.New("invalid blob key")
@@ -0,0 +1,108 @@
+// Package blobstoretest provides an in-memory S3-compatible server for tests, in the spirit of net/http/httptest and
+// of running a local MinIO
+package blobstoretest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Object is a stored object
+type Object struct {
+	Body        []byte
+	ContentType string
+}
+
+// S3Server answers PutObject, GetObject, HeadObject and DeleteObject of path-style requests signed with the access
+// key, GetObject supports Range requests
+type S3Server struct {
+	Server    *httptest.Server
+	AccessKey string
+
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+// NewS3Server starts a server that accepts requests signed with accessKey
+func NewS3Server(accessKey string) *S3Server {
+	s := &S3Server{AccessKey: accessKey, objects: map[string]Object{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// URL is the endpoint to configure the store with
+func (s *S3Server) URL() string {
+	return s.Server.URL
+}
+
+// Object returns the object stored under the bucket and key
+func (s *S3Server) Object(bucket string, key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[bucket+"/"+key]
+	return object, ok
+}
+
+// Close shuts the server down
+func (s *S3Server) Close() {
+	s.Server.Close()
+}
+
+func (s *S3Server) serve(resp http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/") {
+		http.Error(resp, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/")
+
+	if !strings.Contains(path, "/") {
+		http.Error(resp, "<Error><Code>InvalidRequest</Code></Error>", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Method {
+	case "PUT":
+		body, err := ioutil.ReadAll(req.Body)
+
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+
+		if req.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			http.Error(resp, "<Error><Code>XAmzContentSHA256Mismatch</Code></Error>", http.StatusBadRequest)
+			return
+		}
+
+		s.objects[path] = Object{Body: body, ContentType: req.Header.Get("Content-Type")}
+	case "GET", "HEAD":
+		object, ok := s.objects[path]
+
+		if !ok {
+			http.Error(resp, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+
+		resp.Header().Set("Content-Type", object.ContentType)
+		http.ServeContent(resp, req, "", time.Time{}, bytes.NewReader(object.Body))
+	case "DELETE":
+		delete(s.objects, path)
+		resp.WriteHeader(http.StatusNoContent)
+	default:
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
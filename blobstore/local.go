@@ -0,0 +1,101 @@
+package blobstore
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type localStore struct {
+	dir string
+}
+
+// NewLocalStore stores blobs as files under dir, the directories are created as needed
+func NewLocalStore(dir string) BlobStore {
+	return &localStore{dir: dir}
+}
+
+// Put writes the blob to a temporary file first, so a failed upload never leaves a partial blob behind
+func (s *localStore) Put(key string, body io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	path := s.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStore) Get(key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	file, err := os.Open(s.path(key))
+
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+// Open opens the file of the blob, which already seeks
+func (s *localStore) Open(key string) (Object, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	file, err := os.Open(s.path(key))
+
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Delete removes the blob, deleting a missing blob is not an error
+func (s *localStore) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := os.Remove(s.path(key))
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *localStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
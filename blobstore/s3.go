@@ -0,0 +1,365 @@
+package blobstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points at a bucket of Amazon S3 or of an S3-compatible store such as MinIO
+type S3Config struct {
+	// Endpoint defaults to the Amazon S3 endpoint of the region, buckets are always addressed path-style
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+type s3Store struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Store stores blobs as objects in the configured bucket, requests are signed with AWS Signature Version 4
+func NewS3Store(config S3Config) BlobStore {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+
+	return &s3Store{config: config, client: &http.Client{Timeout: time.Minute}, now: time.Now}
+}
+
+// Put uploads the blob, the body is read into memory because the payload has to be hashed for the signature
+func (s *s3Store) Put(key string, body io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	payload, err := ioutil.ReadAll(body)
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", s.objectURL(key), bytes.NewReader(payload))
+
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req, payload)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func (s *s3Store) Get(key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	req, err := http.NewRequest("GET", s.objectURL(key), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Open looks up the size of the object, the content is only requested once it is read, from the offset seeked to
+func (s *s3Store) Open(key string) (Object, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	req, err := http.NewRequest("HEAD", s.objectURL(key), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	return &s3Object{store: s, key: key, size: resp.ContentLength}, nil
+}
+
+// s3Object reads the object with a ranged GetObject starting at the offset, seeking drops the response being read
+type s3Object struct {
+	store  *s3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+
+	if o.body == nil {
+		req, err := http.NewRequest("GET", o.store.objectURL(o.key), nil)
+
+		if err != nil {
+			return 0, err
+		}
+
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(o.offset, 10)+"-")
+
+		resp, err := o.store.do(req, nil)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if err := checkResponse(resp); err != nil {
+			resp.Body.Close()
+			return 0, err
+		}
+
+		o.body = resp.Body
+	}
+
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("blobstore: seek to negative offset %d", offset)
+	}
+
+	if offset != o.offset {
+		o.Close()
+		o.offset = offset
+	}
+
+	return offset, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+
+	err := o.body.Close()
+	o.body = nil
+
+	return err
+}
+
+// Delete removes the object, deleting a missing object is not an error
+func (s *s3Store) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	req, err := http.NewRequest("DELETE", s.objectURL(key), nil)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return checkResponse(resp)
+}
+
+func (s *s3Store) objectURL(key string) string {
+	return s.config.Endpoint + "/" + uriEncode(s.config.Bucket, false) + "/" + uriEncode(key, true)
+}
+
+func (s *s3Store) do(req *http.Request, payload []byte) (*http.Response, error) {
+	signRequest(req, hashHex(payload), s.config.AccessKey, s.config.SecretKey, s.config.Region, s.now())
+
+	return s.client.Do(req)
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	return fmt.Errorf("blobstore: s3 responded %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+}
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+)
+
+// signRequest adds the x-amz-date, x-amz-content-sha256 and Authorization headers of AWS Signature Version 4. The host
+// header and any content-type, range and x-amz-* headers are signed
+func signRequest(req *http.Request, payloadHash string, accessKey string, secretKey string, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	scope := now.Format("20060102") + "/" + region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, true),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", sigV4Algorithm+" Credential="+accessKey+"/"+scope+",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, false)+"="+uriEncode(value, false))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters of RFC 3986, and slashes if keepSlash is set
+func uriEncode(value string, keepSlash bool) string {
+	var encoded strings.Builder
+
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && keepSlash:
+			encoded.WriteByte(b)
+		default:
+			encoded.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+
+	return encoded.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
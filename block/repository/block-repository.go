@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// BlockRepository is the repository interface for block
+type BlockRepository interface {
+	AddBlock(block *models.Block) error
+	GetBlock(blockerID uint, blockedID uint, block *models.Block) error
+	GetBlocks(blockerID uint) ([]models.Block, error)
+	DeleteBlock(blockerID uint, blockedID uint) error
+	IsBlocked(userID uint, otherID uint) (bool, error)
+	GetBlocksByUserID(userID uint) ([]models.Block, error)
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Block{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewBlockRepository create a new block repository to fiddle around with database
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddBlock returns an error if there is any, otherwise creates a new block record into database
+func (r *repo) AddBlock(block *models.Block) error {
+	return r.db.Create(block).Error
+}
+
+// GetBlock returns an error if the blocker didn't block the user, otherwise modifies the block parameter
+func (r *repo) GetBlock(blockerID uint, blockedID uint, block *models.Block) error {
+	return r.db.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).First(block).Error
+}
+
+// GetBlocks returns the users the blocker blocked, most recent first
+func (r *repo) GetBlocks(blockerID uint) ([]models.Block, error) {
+	var blocks []models.Block
+
+	err := r.db.Where("blocker_id = ?", blockerID).Order("created_at desc").Find(&blocks).Error
+
+	return blocks, err
+}
+
+// GetBlocksByUserID returns the blocks the user made and the blocks of the user by others
+func (r *repo) GetBlocksByUserID(userID uint) ([]models.Block, error) {
+	var blocks []models.Block
+
+	err := r.db.Where("blocker_id = ? OR blocked_id = ?", userID, userID).Find(&blocks).Error
+
+	return blocks, err
+}
+
+// DeleteBlock unblocks the user, returns an error if the user wasn't blocked
+func (r *repo) DeleteBlock(blockerID uint, blockedID uint) error {
+	result := r.db.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&models.Block{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error: not found \"blocks_blocked_id_key\"")
+	}
+
+	return nil
+}
+
+// IsBlocked tells whether either of the users blocked the other
+func (r *repo) IsBlocked(userID uint, otherID uint) (bool, error) {
+	var count int
+
+	err := r.db.Model(&models.Block{}).Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).Count(&count).Error
+
+	return count > 0, err
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	blockRepo BlockRepository
+	mock      sqlmock.Sqlmock
+	db        *sql.DB
+	gdb       *gorm.DB
+	err       error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	blockRepo = NewBlockRepository(gdb)
+}
+
+func TestAddBlock(t *testing.T) {
+	setup()
+
+	block := models.Block{BlockerID: 1, BlockedID: 2}
+	const sqlInsert = `INSERT INTO "blocks" ("blocker_id","blocked_id","created_at") VALUES ($1,$2,$3) RETURNING "blocks"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, 2, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectCommit()
+
+	err := blockRepo.AddBlock(&block)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(4), block.ID)
+}
+
+func TestGetBlocks(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "blocks" WHERE (blocker_id = $1) ORDER BY created_at desc`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "blocker_id", "blocked_id"}).AddRow(4, 1, 2))
+
+	blocks, err := blockRepo.GetBlocks(1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(blocks))
+	assert.Equal(t, uint(2), blocks[0].BlockedID)
+}
+
+func TestDeleteBlockNotFound(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "blocks"  WHERE (blocker_id = $1 AND blocked_id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := blockRepo.DeleteBlock(1, 2)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: not found \"blocks_blocked_id_key\"", err.Error())
+}
+
+func TestIsBlocked(t *testing.T) {
+	setup()
+
+	const sqlCount = `SELECT count(*) FROM "blocks"  WHERE ((blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $3 AND blocked_id = $4))`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCount)).WithArgs(1, 2, 2, 1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	blocked, err := blockRepo.IsBlocked(1, 2)
+
+	assert.Nil(t, err)
+	assert.True(t, blocked)
+}
+
+func TestGetBlocksByUserID(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "blocks"  WHERE (blocker_id = $1 OR blocked_id = $2)`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "blocker_id", "blocked_id"}).AddRow(1, 1, 2).AddRow(2, 3, 1))
+
+	blocks, err := blockRepo.GetBlocksByUserID(1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(blocks))
+}
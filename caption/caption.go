@@ -0,0 +1,138 @@
+// Package caption finds the hashtags and mentions in the captions of posts
+package caption
+
+import (
+	"strings"
+	"summer-web/models"
+	"unicode"
+)
+
+const (
+	// MaxHashtagLength is the longest hashtag recognized, in characters without the #
+	MaxHashtagLength = 100
+	// MaxMentionLength is the longest username recognized, in characters without the @
+	MaxMentionLength = 50
+)
+
+// Parse returns the hashtags and mentions of the caption in the order they appear. A # or @ only starts an entity
+// at the beginning of the caption or after a character that can't be part of one, so "a#b" and "joko@joko.com" are
+// left alone. Hashtags are letters, digits and underscores with at least one letter, mentions are ASCII letters,
+// digits, underscores and inner dots
+func Parse(text string) []models.Entity {
+	runes := []rune(text)
+	entities := []models.Entity{}
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' && runes[i] != '@' {
+			continue
+		}
+
+		if i > 0 && (isHashtagRune(runes[i-1]) || runes[i-1] == '@' || runes[i-1] == '#') {
+			continue
+		}
+
+		var end int
+		var entity models.Entity
+
+		if runes[i] == '#' {
+			end = scan(runes, i+1, isHashtagRune)
+			entity = models.Entity{Type: models.EntityHashtag}
+
+			if end-i-1 > MaxHashtagLength || !hasLetter(runes[i+1:end]) {
+				i = end - 1
+				continue
+			}
+		} else {
+			end = scan(runes, i+1, isMentionRune)
+
+			// a sentence may end right after the mention
+			for end > i+1 && runes[end-1] == '.' {
+				end--
+			}
+
+			entity = models.Entity{Type: models.EntityMention}
+
+			if end == i+1 || end-i-1 > MaxMentionLength || runes[i+1] == '.' {
+				i = end - 1
+				continue
+			}
+		}
+
+		entity.Start = i
+		entity.End = end
+		entity.Text = string(runes[i+1 : end])
+		entities = append(entities, entity)
+
+		i = end - 1
+	}
+
+	return entities
+}
+
+// Hashtags returns the distinct hashtags of the caption, lowercased
+func Hashtags(text string) []string {
+	return distinct(Parse(text), models.EntityHashtag)
+}
+
+// Mentions returns the distinct usernames mentioned in the caption, lowercased
+func Mentions(text string) []string {
+	return distinct(Parse(text), models.EntityMention)
+}
+
+// NormalizeHashtag lowercases the hashtag and drops its #, it returns false if it is not a hashtag Parse would find
+func NormalizeHashtag(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "#")
+	entities := Parse("#" + tag)
+
+	if len(entities) != 1 || entities[0].Text != tag {
+		return "", false
+	}
+
+	return strings.ToLower(tag), true
+}
+
+func distinct(entities []models.Entity, entityType string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+
+	for _, entity := range entities {
+		value := strings.ToLower(entity.Text)
+
+		if entity.Type != entityType || seen[value] {
+			continue
+		}
+
+		seen[value] = true
+		values = append(values, value)
+	}
+
+	return values
+}
+
+func scan(runes []rune, start int, accept func(rune) bool) int {
+	end := start
+
+	for end < len(runes) && accept(runes[end]) {
+		end++
+	}
+
+	return end
+}
+
+func isHashtagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isMentionRune(r rune) bool {
+	return r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func hasLetter(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package caption
+
+import (
+	"summer-web/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	entities := Parse("Sunset at #Bali with @joko_123 and @budi.")
+
+	assert.Equal(t, []models.Entity{
+		{Type: models.EntityHashtag, Start: 10, End: 15, Text: "Bali"},
+		{Type: models.EntityMention, Start: 21, End: 30, Text: "joko_123"},
+		{Type: models.EntityMention, Start: 35, End: 40, Text: "budi"},
+	}, entities)
+}
+
+func TestParseOffsetsAreInCharacters(t *testing.T) {
+	entities := Parse("☀️ #pantai_indah")
+
+	assert.Equal(t, 1, len(entities))
+	assert.Equal(t, 3, entities[0].Start)
+	assert.Equal(t, 16, entities[0].End)
+	assert.Equal(t, "pantai_indah", entities[0].Text)
+}
+
+func TestParseSkipsWhatIsNotAnEntity(t *testing.T) {
+	assert.Empty(t, Parse("mail joko@joko.com, issue a#1, #123, # and @ alone, ##double, @.dot"))
+}
+
+func TestParseUnicodeHashtag(t *testing.T) {
+	entities := Parse("#café #日本")
+
+	assert.Equal(t, 2, len(entities))
+	assert.Equal(t, "café", entities[0].Text)
+	assert.Equal(t, "日本", entities[1].Text)
+}
+
+func TestHashtagsAndMentionsAreDistinct(t *testing.T) {
+	assert.Equal(t, []string{"go", "golang"}, Hashtags("#Go #golang #GO"))
+	assert.Equal(t, []string{"joko"}, Mentions("@Joko @joko"))
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tag, ok := NormalizeHashtag("#GoLang")
+
+	assert.True(t, ok)
+	assert.Equal(t, "golang", tag)
+
+	_, ok = NormalizeHashtag("go lang")
+
+	assert.False(t, ok)
+
+	_, ok = NormalizeHashtag("123")
+
+	assert.False(t, ok)
+}
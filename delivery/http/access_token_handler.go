@@ -0,0 +1,134 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"summer-web/models"
+	"summer-web/usecase"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AccessTokenDelivery interface acts as Personal Access Token Controller
+type AccessTokenDelivery interface {
+	GetAccessTokens(resp http.ResponseWriter, req *http.Request)
+	CreateAccessToken(resp http.ResponseWriter, req *http.Request)
+	RevokeAccessToken(resp http.ResponseWriter, req *http.Request)
+}
+
+type accessTokenDelivery struct{}
+
+var (
+	accessTokenUsecase usecase.AccessTokenUsecase
+)
+
+// NewAccessTokenDelivery returns new accessTokenDelivery struct that implements AccessTokenDelivery
+func NewAccessTokenDelivery(usecaseAccessToken ...usecase.AccessTokenUsecase) AccessTokenDelivery {
+	if len(usecaseAccessToken) > 0 {
+		accessTokenUsecase = usecaseAccessToken[0]
+	} else {
+		accessTokenUsecase = usecase.NewAccessTokenUsecase()
+	}
+	return &accessTokenDelivery{}
+}
+
+func (*accessTokenDelivery) GetAccessTokens(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	tokens, err := accessTokenUsecase.GetAccessTokens(userID)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	if tokens == nil {
+		tokens = []models.AccessToken{}
+	}
+
+	json.NewEncoder(resp).Encode(tokens)
+}
+
+// CreateAccessToken takes a name, comma separated scopes and an optional expires_in_days, the token is only shown in this response
+func (*accessTokenDelivery) CreateAccessToken(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	var expiresIn time.Duration
+
+	if req.FormValue("expires_in_days") != "" {
+		days, err := strconv.Atoi(req.FormValue("expires_in_days"))
+		if err != nil || days <= 0 {
+			resp.WriteHeader(http.StatusBadRequest)
+			resp.Write([]byte(`{"expires_in_days": "must be a positive number of days"}`))
+			return
+		}
+		expiresIn = time.Duration(days) * 24 * time.Hour
+	}
+
+	rawToken, token, err := accessTokenUsecase.CreateAccessToken(userID, req.FormValue("name"), strings.Split(req.FormValue("scopes"), ","), expiresIn)
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	json.NewEncoder(resp).Encode(struct {
+		models.AccessToken
+		Token string `json:"token"`
+	}{token, rawToken})
+}
+
+func (*accessTokenDelivery) RevokeAccessToken(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(req)["id"])
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = accessTokenUsecase.RevokeAccessToken(userID, uint(id))
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "token revoked"}`))
+}
@@ -0,0 +1,170 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccessTokenMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *AccessTokenMockUsecase) CreateAccessToken(userID uint, name string, scopes []string, expiresIn time.Duration) (string, models.AccessToken, error) {
+	args := mock.Called(name, scopes, expiresIn)
+	return args.String(0), args.Get(1).(models.AccessToken), args.Error(2)
+}
+
+func (mock *AccessTokenMockUsecase) GetAccessTokens(userID uint) ([]models.AccessToken, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.AccessToken), args.Error(1)
+}
+
+func (mock *AccessTokenMockUsecase) RevokeAccessToken(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func (mock *AccessTokenMockUsecase) Authenticate(rawToken string) (uint, []string, error) {
+	args := mock.Called(rawToken)
+	return uint(args.Int(0)), args.Get(1).([]string), args.Error(2)
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	form := url.Values{}
+	form.Set("name", "ci")
+	form.Set("scopes", "posts:read,posts:write")
+	form.Set("expires_in_days", "7")
+
+	req, err := http.NewRequest("POST", "/users/me/tokens", strings.NewReader(form.Encode()))
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(AccessTokenMockUsecase)
+
+	mockUsecase.On("CreateAccessToken", "ci", []string{"posts:read", "posts:write"}, 7*24*time.Hour).Return("swp_abc", models.AccessToken{ID: 1, Name: "ci", Scopes: "posts:read posts:write"}, nil)
+
+	accessTokenDeliv := NewAccessTokenDelivery(mockUsecase)
+
+	accessTokenDeliv.CreateAccessToken(resp, req)
+
+	receivedResponse := map[string]interface{}{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "swp_abc", receivedResponse["token"])
+	assert.Equal(t, "ci", receivedResponse["name"])
+}
+
+func TestCreateAccessTokenInvalidScope(t *testing.T) {
+	form := url.Values{}
+	form.Set("name", "ci")
+	form.Set("scopes", "admin")
+
+	req, err := http.NewRequest("POST", "/users/me/tokens", strings.NewReader(form.Encode()))
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(AccessTokenMockUsecase)
+
+	mockUsecase.On("CreateAccessToken", "ci", []string{"admin"}, time.Duration(0)).Return("", models.AccessToken{}, fmt.Errorf("error: unknown scope \"access_tokens_scopes_key\""))
+
+	accessTokenDeliv := NewAccessTokenDelivery(mockUsecase)
+
+	accessTokenDeliv.CreateAccessToken(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetAccessTokens(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/me/tokens", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(AccessTokenMockUsecase)
+
+	mockUsecase.On("GetAccessTokens").Return([]models.AccessToken{{ID: 1, Name: "ci", TokenHash: "hash"}}, nil)
+
+	accessTokenDeliv := NewAccessTokenDelivery(mockUsecase)
+
+	accessTokenDeliv.GetAccessTokens(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"name":"ci"`)
+	assert.NotContains(t, resp.Body.String(), "hash")
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/users/me/tokens/3", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(AccessTokenMockUsecase)
+
+	mockUsecase.On("RevokeAccessToken", uint(1), uint(3)).Return(nil)
+
+	accessTokenDeliv := NewAccessTokenDelivery(mockUsecase)
+
+	accessTokenDeliv.RevokeAccessToken(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
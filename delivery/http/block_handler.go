@@ -0,0 +1,105 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"summer-web/usecase"
+)
+
+// BlockDelivery interface acts as Block Controller
+type BlockDelivery interface {
+	Block(resp http.ResponseWriter, req *http.Request)
+	Unblock(resp http.ResponseWriter, req *http.Request)
+	GetBlocks(resp http.ResponseWriter, req *http.Request)
+}
+
+type blockDelivery struct{}
+
+var (
+	blockUsecase usecase.BlockUsecase
+)
+
+// NewBlockDelivery returns new blockDelivery struct that implements BlockDelivery
+func NewBlockDelivery(usecaseBlock ...usecase.BlockUsecase) BlockDelivery {
+	if len(usecaseBlock) > 0 {
+		blockUsecase = usecaseBlock[0]
+	} else {
+		blockUsecase = usecase.NewBlockUsecase()
+	}
+	return &blockDelivery{}
+}
+
+// Block blocks the user in the path, any follows between the two users are removed
+func (*blockDelivery) Block(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, blockedID, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = blockUsecase.Block(userID, blockedID)
+
+	if err != nil {
+		key, value := trimError(err)
+		if err == usecase.ErrUserNotFound {
+			resp.WriteHeader(http.StatusNotFound)
+		} else {
+			resp.WriteHeader(http.StatusBadRequest)
+		}
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "user blocked"}`))
+}
+
+func (*blockDelivery) Unblock(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, blockedID, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = blockUsecase.Unblock(userID, blockedID)
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "user unblocked"}`))
+}
+
+func (*blockDelivery) GetBlocks(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	blocks, err := blockUsecase.GetBlocks(userID)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(resp).Encode(blocks)
+}
@@ -0,0 +1,93 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"summer-web/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type BlockMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *BlockMockUsecase) Block(blockerID uint, blockedID uint) error {
+	args := mock.Called(blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (mock *BlockMockUsecase) Unblock(blockerID uint, blockedID uint) error {
+	args := mock.Called(blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (mock *BlockMockUsecase) GetBlocks(blockerID uint) ([]models.Block, error) {
+	args := mock.Called(blockerID)
+	return args.Get(0).([]models.Block), args.Error(1)
+}
+
+func TestBlock(t *testing.T) {
+	req := newFollowRequest("POST", "/users/2/block", "2")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(BlockMockUsecase)
+
+	mockUsecase.On("Block", uint(1), uint(2)).Return(nil)
+
+	blockDeliv := NewBlockDelivery(mockUsecase)
+	blockDeliv.Block(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestBlockUnknownUser(t *testing.T) {
+	req := newFollowRequest("POST", "/users/9/block", "9")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(BlockMockUsecase)
+
+	mockUsecase.On("Block", uint(1), uint(9)).Return(usecase.ErrUserNotFound)
+
+	blockDeliv := NewBlockDelivery(mockUsecase)
+	blockDeliv.Block(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestUnblockNotBlocked(t *testing.T) {
+	req := newFollowRequest("DELETE", "/users/2/block", "2")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(BlockMockUsecase)
+
+	mockUsecase.On("Unblock", uint(1), uint(2)).Return(fmt.Errorf("error: not found \"blocks_blocked_id_key\""))
+
+	blockDeliv := NewBlockDelivery(mockUsecase)
+	blockDeliv.Unblock(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"blocks_blocked_id_key": "not found"}`, resp.Body.String())
+}
+
+func TestGetBlocks(t *testing.T) {
+	req := newFollowRequest("GET", "/users/me/blocks", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(BlockMockUsecase)
+
+	mockUsecase.On("GetBlocks", uint(1)).Return([]models.Block{{ID: 4, BlockerID: 1, BlockedID: 2}}, nil)
+
+	blockDeliv := NewBlockDelivery(mockUsecase)
+	blockDeliv.GetBlocks(resp, req)
+
+	blocks := []models.Block{}
+
+	json.NewDecoder(resp.Body).Decode(&blocks)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, len(blocks))
+	assert.Equal(t, uint(2), blocks[0].BlockedID)
+}
@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"net/http"
+	"summer-web/usecase"
+)
+
+// EmailDelivery interface acts as Email Verification Controller
+type EmailDelivery interface {
+	VerifyEmail(resp http.ResponseWriter, req *http.Request)
+	ResendVerificationEmail(resp http.ResponseWriter, req *http.Request)
+}
+
+type emailDelivery struct{}
+
+var (
+	emailUsecase usecase.EmailUsecase
+)
+
+// NewEmailDelivery returns new emailDelivery struct that implements EmailDelivery
+func NewEmailDelivery(usecaseEmail ...usecase.EmailUsecase) EmailDelivery {
+	if len(usecaseEmail) > 0 {
+		emailUsecase = usecaseEmail[0]
+	} else {
+		emailUsecase = usecase.NewEmailUsecase()
+	}
+	return &emailDelivery{}
+}
+
+func (*emailDelivery) VerifyEmail(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	err := emailUsecase.VerifyEmail(req.FormValue("token"))
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "email verified"}`))
+}
+
+func (*emailDelivery) ResendVerificationEmail(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = emailUsecase.SendVerificationEmail(userID)
+
+	if err != nil {
+		// errors of the mailer are ours, not the user's
+		status := http.StatusInternalServerError
+
+		if hasErrorKey(err) {
+			status = http.StatusBadRequest
+		}
+
+		writeError(resp, status, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "verification email sent"}`))
+}
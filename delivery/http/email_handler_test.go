@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type EmailMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *EmailMockUsecase) SendVerificationEmail(userID uint) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *EmailMockUsecase) VerifyEmail(token string) error {
+	args := mock.Called(token)
+	return args.Error(0)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	req, err := http.NewRequest("GET", "/verify_email?token=abc", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(EmailMockUsecase)
+
+	mockUsecase.On("VerifyEmail", "abc").Return(nil)
+
+	emailDeliv := NewEmailDelivery(mockUsecase)
+
+	emailDeliv.VerifyEmail(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestVerifyEmailInvalidToken(t *testing.T) {
+	req, err := http.NewRequest("GET", "/verify_email?token=abc", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(EmailMockUsecase)
+
+	mockUsecase.On("VerifyEmail", "abc").Return(fmt.Errorf("error: invalid \"token\""))
+
+	emailDeliv := NewEmailDelivery(mockUsecase)
+
+	emailDeliv.VerifyEmail(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid", receivedResponse["token"])
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	req, err := http.NewRequest("POST", "/verify_email/resend", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(EmailMockUsecase)
+
+	mockUsecase.On("SendVerificationEmail").Return(nil)
+
+	emailDeliv := NewEmailDelivery(mockUsecase)
+
+	emailDeliv.ResendVerificationEmail(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestResendVerificationEmailMailerError(t *testing.T) {
+	req, err := http.NewRequest("POST", "/verify_email/resend", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(EmailMockUsecase)
+
+	mockUsecase.On("SendVerificationEmail").Return(fmt.Errorf("dial tcp: connection refused"))
+
+	emailDeliv := NewEmailDelivery(mockUsecase)
+
+	emailDeliv.ResendVerificationEmail(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, "dial tcp: connection refused", receivedResponse["error"])
+}
@@ -0,0 +1,164 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"summer-web/usecase"
+
+	"github.com/gorilla/mux"
+)
+
+// FollowDelivery interface acts as Follow Controller
+type FollowDelivery interface {
+	Follow(resp http.ResponseWriter, req *http.Request)
+	Unfollow(resp http.ResponseWriter, req *http.Request)
+	GetFollowRequests(resp http.ResponseWriter, req *http.Request)
+	ApproveFollowRequest(resp http.ResponseWriter, req *http.Request)
+	RejectFollowRequest(resp http.ResponseWriter, req *http.Request)
+}
+
+type followDelivery struct{}
+
+var (
+	followUsecase usecase.FollowUsecase
+)
+
+// NewFollowDelivery returns new followDelivery struct that implements FollowDelivery
+func NewFollowDelivery(usecaseFollow ...usecase.FollowUsecase) FollowDelivery {
+	if len(usecaseFollow) > 0 {
+		followUsecase = usecaseFollow[0]
+	} else {
+		followUsecase = usecase.NewFollowUsecase()
+	}
+	return &followDelivery{}
+}
+
+// Follow follows the user in the path, the returned status is pending when the account is private
+func (*followDelivery) Follow(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, followeeID, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	follow, err := followUsecase.Follow(userID, followeeID)
+
+	if err != nil {
+		key, value := trimError(err)
+		if err == usecase.ErrUserNotFound {
+			resp.WriteHeader(http.StatusNotFound)
+		} else {
+			resp.WriteHeader(http.StatusBadRequest)
+		}
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	json.NewEncoder(resp).Encode(follow)
+}
+
+// Unfollow stops following the user in the path, or cancels the pending request
+func (*followDelivery) Unfollow(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, followeeID, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = followUsecase.Unfollow(userID, followeeID)
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "unfollowed"}`))
+}
+
+// GetFollowRequests lists the pending requests to follow the user
+func (*followDelivery) GetFollowRequests(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	follows, err := followUsecase.GetFollowRequests(userID)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(resp).Encode(follows)
+}
+
+func (*followDelivery) ApproveFollowRequest(resp http.ResponseWriter, req *http.Request) {
+	answerFollowRequest(resp, req, followUsecase.ApproveFollowRequest, "follow request approved")
+}
+
+func (*followDelivery) RejectFollowRequest(resp http.ResponseWriter, req *http.Request) {
+	answerFollowRequest(resp, req, followUsecase.RejectFollowRequest, "follow request rejected")
+}
+
+func answerFollowRequest(resp http.ResponseWriter, req *http.Request, answer func(userID uint, id uint) error, message string) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, id, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = answer(userID, id)
+
+	if err != nil {
+		key, value := trimError(err)
+		if err == usecase.ErrFollowRequestNotFound {
+			resp.WriteHeader(http.StatusNotFound)
+		} else {
+			resp.WriteHeader(http.StatusInternalServerError)
+		}
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "` + message + `"}`))
+}
+
+// getUserIDAndPathID returns the authorized user and the id in the path
+func getUserIDAndPathID(req *http.Request) (uint, uint, error) {
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(req)["id"])
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return userID, uint(id), nil
+}
@@ -0,0 +1,154 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"summer-web/usecase"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type FollowMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *FollowMockUsecase) Follow(followerID uint, followeeID uint) (models.Follow, error) {
+	args := mock.Called(followerID, followeeID)
+	return args.Get(0).(models.Follow), args.Error(1)
+}
+
+func (mock *FollowMockUsecase) Unfollow(followerID uint, followeeID uint) error {
+	args := mock.Called(followerID, followeeID)
+	return args.Error(0)
+}
+
+func (mock *FollowMockUsecase) GetFollowRequests(userID uint) ([]models.Follow, error) {
+	args := mock.Called(userID)
+	return args.Get(0).([]models.Follow), args.Error(1)
+}
+
+func (mock *FollowMockUsecase) ApproveFollowRequest(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func (mock *FollowMockUsecase) RejectFollowRequest(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func newFollowRequest(method string, url string, id string) *http.Request {
+	req, err := http.NewRequest(method, url, nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	return mux.SetURLVars(req, map[string]string{"id": id})
+}
+
+func TestFollowPrivateAccount(t *testing.T) {
+	req := newFollowRequest("POST", "/users/2/follow", "2")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(FollowMockUsecase)
+
+	mockUsecase.On("Follow", uint(1), uint(2)).Return(models.Follow{ID: 3, FollowerID: 1, FolloweeID: 2, Status: models.FollowPending}, nil)
+
+	followDeliv := NewFollowDelivery(mockUsecase)
+	followDeliv.Follow(resp, req)
+
+	var follow models.Follow
+
+	json.NewDecoder(resp.Body).Decode(&follow)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, models.FollowPending, follow.Status)
+}
+
+func TestFollowUnknownUser(t *testing.T) {
+	req := newFollowRequest("POST", "/users/9/follow", "9")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(FollowMockUsecase)
+
+	mockUsecase.On("Follow", uint(1), uint(9)).Return(models.Follow{}, usecase.ErrUserNotFound)
+
+	followDeliv := NewFollowDelivery(mockUsecase)
+	followDeliv.Follow(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestUnfollow(t *testing.T) {
+	req := newFollowRequest("DELETE", "/users/2/follow", "2")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(FollowMockUsecase)
+
+	mockUsecase.On("Unfollow", uint(1), uint(2)).Return(nil)
+
+	followDeliv := NewFollowDelivery(mockUsecase)
+	followDeliv.Unfollow(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestGetFollowRequests(t *testing.T) {
+	req := newFollowRequest("GET", "/users/me/follow-requests", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(FollowMockUsecase)
+
+	mockUsecase.On("GetFollowRequests", uint(1)).Return([]models.Follow{{ID: 3, FollowerID: 2, FolloweeID: 1, Status: models.FollowPending}}, nil)
+
+	followDeliv := NewFollowDelivery(mockUsecase)
+	followDeliv.GetFollowRequests(resp, req)
+
+	follows := []models.Follow{}
+
+	json.NewDecoder(resp.Body).Decode(&follows)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, len(follows))
+	assert.Equal(t, uint(2), follows[0].FollowerID)
+}
+
+func TestApproveFollowRequest(t *testing.T) {
+	req := newFollowRequest("POST", "/users/me/follow-requests/3/approve", "3")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(FollowMockUsecase)
+
+	mockUsecase.On("ApproveFollowRequest", uint(1), uint(3)).Return(nil)
+
+	followDeliv := NewFollowDelivery(mockUsecase)
+	followDeliv.ApproveFollowRequest(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRejectUnknownFollowRequest(t *testing.T) {
+	req := newFollowRequest("POST", "/users/me/follow-requests/3/reject", "3")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(FollowMockUsecase)
+
+	mockUsecase.On("RejectFollowRequest", uint(1), uint(3)).Return(usecase.ErrFollowRequestNotFound)
+
+	followDeliv := NewFollowDelivery(mockUsecase)
+	followDeliv.RejectFollowRequest(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"follows_id_key": "not found"}`, resp.Body.String())
+}
@@ -0,0 +1,141 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+	"summer-web/usecase"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+// IdentityDelivery interface acts as OpenID Connect Login Controller
+type IdentityDelivery interface {
+	Login(resp http.ResponseWriter, req *http.Request)
+	Link(resp http.ResponseWriter, req *http.Request)
+	Callback(resp http.ResponseWriter, req *http.Request)
+}
+
+type identityDelivery struct{}
+
+const oidcStateCookie = "oidc_state"
+
+var (
+	identityUsecase usecase.IdentityUsecase
+)
+
+// NewIdentityDelivery returns new identityDelivery struct that implements IdentityDelivery
+func NewIdentityDelivery(usecaseIdentity ...usecase.IdentityUsecase) IdentityDelivery {
+	if len(usecaseIdentity) > 0 {
+		identityUsecase = usecaseIdentity[0]
+	} else {
+		identityUsecase = usecase.NewIdentityUsecase()
+	}
+	return &identityDelivery{}
+}
+
+// Login redirects to the provider to log in or sign up with the external account
+func (*identityDelivery) Login(resp http.ResponseWriter, req *http.Request) {
+	startOIDCLogin(resp, req, 0)
+}
+
+// Link redirects to the provider to link the external account to the logged in user
+func (*identityDelivery) Link(resp http.ResponseWriter, req *http.Request) {
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(http.StatusUnauthorized)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	startOIDCLogin(resp, req, userID)
+}
+
+// Callback is where the provider redirects back to, it answers like /login does
+func (*identityDelivery) Callback(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	provider := mux.Vars(req)["provider"]
+
+	cookie, err := req.Cookie(oidcStateCookie)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"state": "missing, please start the login again"}`))
+		return
+	}
+
+	// the state is single use, the browser should forget it whatever the outcome
+	http.SetCookie(resp, &http.Cookie{Name: oidcStateCookie, Path: "/auth/" + provider, MaxAge: -1, HttpOnly: true})
+
+	if providerError := req.FormValue("error"); providerError != "" {
+		resp.WriteHeader(http.StatusUnauthorized)
+		resp.Write([]byte(`{"error": "provider returned ` + providerError + `"}`))
+		return
+	}
+
+	token, mfaRequired, err := identityUsecase.FinishLogin(provider, req.FormValue("code"), req.FormValue("state"), cookie.Value, clientIP(req), req.UserAgent())
+
+	switch {
+	case err == nil:
+	case err == usecase.ErrUnknownProvider:
+		writeError(resp, http.StatusNotFound, err)
+		return
+	case gorm.IsRecordNotFoundError(err):
+		// the identity is linked to an account that was deleted in the meantime
+		writeError(resp, http.StatusUnauthorized, err)
+		return
+	case hasErrorKey(err):
+		writeError(resp, http.StatusUnauthorized, err)
+		return
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	if mfaRequired {
+		resp.WriteHeader(http.StatusOK)
+		resp.Write([]byte(`{"mfa_required": true, "mfa_token": "` + token + `"}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"auth_token": "` + token + `"}`))
+}
+
+// startOIDCLogin keeps the signed state in a cookie scoped to the provider's routes and redirects to the provider
+func startOIDCLogin(resp http.ResponseWriter, req *http.Request, linkUserID uint) {
+	provider := mux.Vars(req)["provider"]
+
+	authURL, stateToken, err := identityUsecase.StartLogin(provider, linkUserID)
+
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		switch err {
+		case usecase.ErrUnknownProvider:
+			status = http.StatusNotFound
+		case usecase.ErrProviderUnavailable:
+			status = http.StatusBadGateway
+		}
+
+		resp.Header().Set("Content-Type", "application/json")
+		writeError(resp, status, err)
+		return
+	}
+
+	http.SetCookie(resp, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		Path:     "/auth/" + provider,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(req.Header.Get("X-Forwarded-Proto"), "https") || req.TLS != nil,
+		// Lax still sends the cookie on the top level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(resp, req, authURL, http.StatusFound)
+}
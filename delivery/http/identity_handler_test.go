@@ -0,0 +1,246 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/usecase"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type IdentityMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *IdentityMockUsecase) StartLogin(provider string, linkUserID uint) (string, string, error) {
+	args := mock.Called(provider, linkUserID)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (mock *IdentityMockUsecase) FinishLogin(provider string, code string, state string, stateToken string, ip string, userAgent string) (string, bool, error) {
+	args := mock.Called(code, state, stateToken)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/login", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("StartLogin", "google", uint(0)).Return("https://accounts.example.com/authorize?state=abc", "signed-state", nil)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Login(resp, req)
+
+	cookies := resp.Result().Cookies()
+
+	assert.Equal(t, http.StatusFound, resp.Code)
+	assert.Equal(t, "https://accounts.example.com/authorize?state=abc", resp.Header().Get("Location"))
+	assert.Equal(t, "oidc_state", cookies[0].Name)
+	assert.Equal(t, "signed-state", cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+}
+
+func TestOIDCLoginUnknownProvider(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/nope/login", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"provider": "nope"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("StartLogin", "nope", uint(0)).Return("", "", usecase.ErrUnknownProvider)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Login(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestOIDCLoginProviderUnavailable(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/login", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("StartLogin", "google", uint(0)).Return("", "", usecase.ErrProviderUnavailable)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Login(resp, req)
+
+	assert.Equal(t, http.StatusBadGateway, resp.Code)
+}
+
+func TestOIDCLink(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/link", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("StartLogin", "google", uint(1)).Return("https://accounts.example.com/authorize", "signed-state", nil)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Link(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusFound, resp.Code)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/callback?code=thecode&state=abc", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("FinishLogin", "thecode", "abc", "signed-state").Return("token", false, nil)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Callback(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "token", receivedResponse["auth_token"])
+	assert.Equal(t, -1, resp.Result().Cookies()[0].MaxAge)
+}
+
+func TestOIDCCallbackWithoutState(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/callback?code=thecode&state=abc", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Callback(resp, req)
+
+	mockUsecase.AssertNotCalled(t, "FinishLogin", "thecode", "abc", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestOIDCCallbackInvalidState(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/callback?code=thecode&state=forged", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("FinishLogin", "thecode", "forged", "signed-state").Return("", false, fmt.Errorf("error: invalid \"state\""))
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Callback(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestOIDCCallbackDeletedUser(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/callback?code=thecode&state=abc", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("FinishLogin", "thecode", "abc", "signed-state").Return("", false, gorm.ErrRecordNotFound)
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Callback(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "record not found", receivedResponse["error"])
+}
+
+func TestOIDCCallbackServerError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/auth/google/callback?code=thecode&state=abc", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+	req = mux.SetURLVars(req, map[string]string{"provider": "google"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(IdentityMockUsecase)
+
+	mockUsecase.On("FinishLogin", "thecode", "abc", "signed-state").Return("", false, fmt.Errorf("connection reset by peer"))
+
+	identityDeliv := NewIdentityDelivery(mockUsecase)
+
+	identityDeliv.Callback(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
@@ -0,0 +1,78 @@
+package delivery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"summer-web/usecase"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// MediaDelivery interface acts as Media Controller
+type MediaDelivery interface {
+	GetMedia(resp http.ResponseWriter, req *http.Request)
+}
+
+type mediaDelivery struct{}
+
+var (
+	mediaUsecase usecase.MediaUsecase
+)
+
+// NewMediaDelivery returns new mediaDelivery struct that implements MediaDelivery
+func NewMediaDelivery(usecaseMedia ...usecase.MediaUsecase) MediaDelivery {
+	if len(usecaseMedia) > 0 {
+		mediaUsecase = usecaseMedia[0]
+	} else {
+		mediaUsecase = usecase.NewMediaUsecase()
+	}
+	return &mediaDelivery{}
+}
+
+// GetMedia streams the image stored under the key in the path to anyone holding a signed URL that didn't expire yet,
+// so that URLs can be used in img tags. Range and conditional requests are answered by http.ServeContent
+func (*mediaDelivery) GetMedia(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	object, item, err := mediaUsecase.GetMedia(mux.Vars(req)["key"], query.Get("expires"), query.Get("signature"))
+
+	if err != nil {
+		resp.Header().Set("Content-Type", "application/json")
+		switch err {
+		case usecase.ErrMediaNotFound:
+			writeError(resp, http.StatusNotFound, err)
+		case usecase.ErrMediaURLInvalid, usecase.ErrMediaURLExpired:
+			writeError(resp, http.StatusForbidden, err)
+		default:
+			writeError(resp, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	defer object.Close()
+
+	// the URL stops working when it expires, so it may be cached until then
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	maxAge := expires - time.Now().Unix()
+
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	resp.Header().Set("Content-Type", item.ContentType)
+	resp.Header().Set("ETag", mediaETag(item.Key))
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+	resp.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(maxAge, 10))
+
+	http.ServeContent(resp, req, "", item.CreatedAt, object)
+}
+
+// mediaETag is derived from the key, because uploads are never overwritten but stored under a new key
+func mediaETag(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,137 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"summer-web/blobstore"
+	"summer-web/models"
+	"summer-web/usecase"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MediaMockUsecase struct {
+	mock.Mock
+}
+
+// memoryObject is a blob held in memory
+type memoryObject struct {
+	*strings.Reader
+}
+
+func (memoryObject) Close() error {
+	return nil
+}
+
+func (mock *MediaMockUsecase) GetMedia(key string, expires string, signature string) (blobstore.Object, models.Media, error) {
+	args := mock.Called(key, expires, signature)
+
+	if args.Error(1) != nil {
+		return nil, models.Media{}, args.Error(1)
+	}
+
+	item := args.Get(0).(models.Media)
+
+	return memoryObject{strings.NewReader("png image")}, item, nil
+}
+
+func (mock *MediaMockUsecase) GenerateVariants() error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func newMediaRequest(key string, expires string, signature string) *http.Request {
+	req, err := http.NewRequest("GET", "/media/"+key+"?expires="+expires+"&signature="+signature, nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return mux.SetURLVars(req, map[string]string{"key": key})
+}
+
+func TestGetMedia(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MediaMockUsecase)
+	expires := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	mockUsecase.On("GetMedia", "posts/2/a.png", expires, "abc").Return(models.Media{Key: "posts/2/a.png", ContentType: "image/png", Size: 9}, nil)
+
+	mediaDeliv := NewMediaDelivery(mockUsecase)
+	mediaDeliv.GetMedia(resp, newMediaRequest("posts/2/a.png", expires, "abc"))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "9", resp.Header().Get("Content-Length"))
+	assert.Equal(t, "nosniff", resp.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "bytes", resp.Header().Get("Accept-Ranges"))
+	assert.Equal(t, mediaETag("posts/2/a.png"), resp.Header().Get("ETag"))
+	assert.True(t, strings.HasPrefix(resp.Header().Get("Cache-Control"), "private, max-age=3"))
+	assert.Equal(t, "png image", resp.Body.String())
+}
+
+func TestGetMediaRange(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MediaMockUsecase)
+
+	mockUsecase.On("GetMedia", "posts/2/a.png", "1", "abc").Return(models.Media{Key: "posts/2/a.png", ContentType: "image/png"}, nil)
+
+	req := newMediaRequest("posts/2/a.png", "1", "abc")
+	req.Header.Set("Range", "bytes=4-")
+
+	mediaDeliv := NewMediaDelivery(mockUsecase)
+	mediaDeliv.GetMedia(resp, req)
+
+	assert.Equal(t, http.StatusPartialContent, resp.Code)
+	assert.Equal(t, "bytes 4-8/9", resp.Header().Get("Content-Range"))
+	assert.Equal(t, "private, max-age=0", resp.Header().Get("Cache-Control"))
+	assert.Equal(t, "image", resp.Body.String())
+}
+
+func TestGetMediaNotModified(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MediaMockUsecase)
+
+	mockUsecase.On("GetMedia", "posts/2/a.png", "1", "abc").Return(models.Media{Key: "posts/2/a.png", ContentType: "image/png"}, nil)
+
+	req := newMediaRequest("posts/2/a.png", "1", "abc")
+	req.Header.Set("If-None-Match", mediaETag("posts/2/a.png"))
+
+	mediaDeliv := NewMediaDelivery(mockUsecase)
+	mediaDeliv.GetMedia(resp, req)
+
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+	assert.Equal(t, "", resp.Body.String())
+}
+
+func TestGetMediaNotFound(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MediaMockUsecase)
+
+	mockUsecase.On("GetMedia", "posts/2/a.png", "1", "abc").Return(nil, usecase.ErrMediaNotFound)
+
+	mediaDeliv := NewMediaDelivery(mockUsecase)
+	mediaDeliv.GetMedia(resp, newMediaRequest("posts/2/a.png", "1", "abc"))
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"media_key_key": "not found"}`, resp.Body.String())
+}
+
+func TestGetMediaExpired(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MediaMockUsecase)
+
+	mockUsecase.On("GetMedia", "posts/2/a.png", "1", "abc").Return(nil, usecase.ErrMediaURLExpired)
+
+	mediaDeliv := NewMediaDelivery(mockUsecase)
+	mediaDeliv.GetMedia(resp, newMediaRequest("posts/2/a.png", "1", "abc"))
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(t, `{"media_expires_key": "expired"}`, resp.Body.String())
+}
@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"summer-web/usecase"
+)
+
+// MFADelivery interface acts as Two Factor Authentication Controller
+type MFADelivery interface {
+	EnrollTOTP(resp http.ResponseWriter, req *http.Request)
+	ConfirmTOTP(resp http.ResponseWriter, req *http.Request)
+	VerifyLogin(resp http.ResponseWriter, req *http.Request)
+}
+
+type mfaDelivery struct{}
+
+var (
+	mfaUsecase usecase.MFAUsecase
+)
+
+// NewMFADelivery returns new mfaDelivery struct that implements MFADelivery
+func NewMFADelivery(usecaseMFA ...usecase.MFAUsecase) MFADelivery {
+	if len(usecaseMFA) > 0 {
+		mfaUsecase = usecaseMFA[0]
+	} else {
+		mfaUsecase = usecase.NewMFAUsecase()
+	}
+	return &mfaDelivery{}
+}
+
+func (*mfaDelivery) EnrollTOTP(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	secret, uri, err := mfaUsecase.EnrollTOTP(userID)
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	json.NewEncoder(resp).Encode(map[string]string{"secret": secret, "otpauth_uri": uri})
+}
+
+func (*mfaDelivery) ConfirmTOTP(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	recoveryCodes, err := mfaUsecase.ConfirmTOTP(userID, req.FormValue("code"))
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	json.NewEncoder(resp).Encode(map[string][]string{"recovery_codes": recoveryCodes})
+}
+
+// VerifyLogin is the second step of /login for users with two factor login enabled
+func (*mfaDelivery) VerifyLogin(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	token, err := mfaUsecase.VerifyLogin(req.FormValue("mfa_token"), req.FormValue("code"), clientIP(req), req.UserAgent())
+
+	if lockedOut, ok := err.(*usecase.TooManyAttemptsError); ok {
+		resp.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedOut.RetryAfter.Seconds()))))
+		resp.WriteHeader(http.StatusTooManyRequests)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusUnauthorized)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"auth_token": "` + token + `"}`))
+}
@@ -0,0 +1,142 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MFAMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *MFAMockUsecase) EnrollTOTP(userID uint) (string, string, error) {
+	args := mock.Called()
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (mock *MFAMockUsecase) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	args := mock.Called(code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (mock *MFAMockUsecase) VerifyLogin(mfaToken string, code string, ip string, userAgent string) (string, error) {
+	args := mock.Called(mfaToken, code)
+	return args.String(0), args.Error(1)
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	req, err := http.NewRequest("POST", "/users/me/2fa/enroll", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MFAMockUsecase)
+
+	mockUsecase.On("EnrollTOTP").Return("SECRET", "otpauth://totp/summer-web:us1?secret=SECRET", nil)
+
+	mfaDeliv := NewMFADelivery(mockUsecase)
+
+	mfaDeliv.EnrollTOTP(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "SECRET", receivedResponse["secret"])
+	assert.Equal(t, "otpauth://totp/summer-web:us1?secret=SECRET", receivedResponse["otpauth_uri"])
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	req, err := http.NewRequest("POST", "/users/me/2fa/confirm?code=123456", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MFAMockUsecase)
+
+	mockUsecase.On("ConfirmTOTP", "123456").Return([]string{"abcde-fghij"}, nil)
+
+	mfaDeliv := NewMFADelivery(mockUsecase)
+
+	mfaDeliv.ConfirmTOTP(resp, req)
+
+	receivedResponse := map[string][]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"abcde-fghij"}, receivedResponse["recovery_codes"])
+}
+
+func TestVerifyLogin(t *testing.T) {
+	req, err := http.NewRequest("POST", "/login/2fa?mfa_token=pending&code=123456", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MFAMockUsecase)
+
+	mockUsecase.On("VerifyLogin", "pending", "123456").Return("valid token", nil)
+
+	mfaDeliv := NewMFADelivery(mockUsecase)
+
+	mfaDeliv.VerifyLogin(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "valid token", receivedResponse["auth_token"])
+}
+
+func TestVerifyLoginInvalidCode(t *testing.T) {
+	req, err := http.NewRequest("POST", "/login/2fa?mfa_token=pending&code=000000", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MFAMockUsecase)
+
+	mockUsecase.On("VerifyLogin", "pending", "000000").Return("", fmt.Errorf("error: invalid \"code\""))
+
+	mfaDeliv := NewMFADelivery(mockUsecase)
+
+	mfaDeliv.VerifyLogin(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
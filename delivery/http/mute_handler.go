@@ -0,0 +1,105 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"summer-web/usecase"
+)
+
+// MuteDelivery interface acts as Mute Controller
+type MuteDelivery interface {
+	Mute(resp http.ResponseWriter, req *http.Request)
+	Unmute(resp http.ResponseWriter, req *http.Request)
+	GetMutes(resp http.ResponseWriter, req *http.Request)
+}
+
+type muteDelivery struct{}
+
+var (
+	muteUsecase usecase.MuteUsecase
+)
+
+// NewMuteDelivery returns new muteDelivery struct that implements MuteDelivery
+func NewMuteDelivery(usecaseMute ...usecase.MuteUsecase) MuteDelivery {
+	if len(usecaseMute) > 0 {
+		muteUsecase = usecaseMute[0]
+	} else {
+		muteUsecase = usecase.NewMuteUsecase()
+	}
+	return &muteDelivery{}
+}
+
+// Mute leaves the posts of the user in the path out of the feeds, the user can still be followed and looked up
+func (*muteDelivery) Mute(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, mutedID, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = muteUsecase.Mute(userID, mutedID)
+
+	if err != nil {
+		key, value := trimError(err)
+		if err == usecase.ErrUserNotFound {
+			resp.WriteHeader(http.StatusNotFound)
+		} else {
+			resp.WriteHeader(http.StatusBadRequest)
+		}
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "user muted"}`))
+}
+
+func (*muteDelivery) Unmute(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, mutedID, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = muteUsecase.Unmute(userID, mutedID)
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "user unmuted"}`))
+}
+
+func (*muteDelivery) GetMutes(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	mutes, err := muteUsecase.GetMutes(userID)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(resp).Encode(mutes)
+}
@@ -0,0 +1,77 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MuteMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *MuteMockUsecase) Mute(muterID uint, mutedID uint) error {
+	args := mock.Called(muterID, mutedID)
+	return args.Error(0)
+}
+
+func (mock *MuteMockUsecase) Unmute(muterID uint, mutedID uint) error {
+	args := mock.Called(muterID, mutedID)
+	return args.Error(0)
+}
+
+func (mock *MuteMockUsecase) GetMutes(muterID uint) ([]models.Mute, error) {
+	args := mock.Called(muterID)
+	return args.Get(0).([]models.Mute), args.Error(1)
+}
+
+func TestMute(t *testing.T) {
+	req := newFollowRequest("POST", "/users/2/mute", "2")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MuteMockUsecase)
+
+	mockUsecase.On("Mute", uint(1), uint(2)).Return(nil)
+
+	muteDeliv := NewMuteDelivery(mockUsecase)
+	muteDeliv.Mute(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestUnmute(t *testing.T) {
+	req := newFollowRequest("DELETE", "/users/2/mute", "2")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MuteMockUsecase)
+
+	mockUsecase.On("Unmute", uint(1), uint(2)).Return(nil)
+
+	muteDeliv := NewMuteDelivery(mockUsecase)
+	muteDeliv.Unmute(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestGetMutes(t *testing.T) {
+	req := newFollowRequest("GET", "/users/me/mutes", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(MuteMockUsecase)
+
+	mockUsecase.On("GetMutes", uint(1)).Return([]models.Mute{{ID: 5, MuterID: 1, MutedID: 2}}, nil)
+
+	muteDeliv := NewMuteDelivery(mockUsecase)
+	muteDeliv.GetMutes(resp, req)
+
+	mutes := []models.Mute{}
+
+	json.NewDecoder(resp.Body).Decode(&mutes)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, len(mutes))
+}
@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"summer-web/usecase"
+)
+
+// NotificationDelivery interface acts as Notification Controller
+type NotificationDelivery interface {
+	GetNotifications(resp http.ResponseWriter, req *http.Request)
+	MarkRead(resp http.ResponseWriter, req *http.Request)
+}
+
+type notificationDelivery struct{}
+
+var (
+	notificationUsecase usecase.NotificationUsecase
+
+	// ids must be a comma separated list of notification ids
+	errInvalidNotificationIDs = fmt.Errorf("error: invalid \"ids\"")
+)
+
+// NewNotificationDelivery returns new notificationDelivery struct that implements NotificationDelivery
+func NewNotificationDelivery(usecaseNotification ...usecase.NotificationUsecase) NotificationDelivery {
+	if len(usecaseNotification) > 0 {
+		notificationUsecase = usecaseNotification[0]
+	} else {
+		notificationUsecase = usecase.NewNotificationUsecase()
+	}
+	return &notificationDelivery{}
+}
+
+// GetNotifications lists up to "limit" groups of the latest notifications of the user and how many are unread
+func (*notificationDelivery) GetNotifications(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	limit, err := queryInt(req, "limit")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	groups, unread, err := notificationUsecase.GetNotifications(userID, limit)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(NotificationsResponse{UnreadCount: unread, Notifications: newNotificationResponses(groups)})
+}
+
+// MarkRead marks the notifications in "ids" as read, or all of them if "ids" is empty, and returns how many are left
+// unread
+func (*notificationDelivery) MarkRead(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	var ids []uint
+
+	if req.FormValue("ids") != "" {
+		for _, value := range strings.Split(req.FormValue("ids"), ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+
+			if err != nil {
+				writeError(resp, http.StatusBadRequest, errInvalidNotificationIDs)
+				return
+			}
+
+			ids = append(ids, uint(id))
+		}
+	}
+
+	unread, err := notificationUsecase.MarkRead(userID, ids)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(NotificationsResponse{UnreadCount: unread})
+}
@@ -0,0 +1,78 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *NotificationMockUsecase) GetNotifications(userID uint, limit int) ([]models.NotificationGroup, int, error) {
+	args := mock.Called(userID, limit)
+	return args.Get(0).([]models.NotificationGroup), args.Int(1), args.Error(2)
+}
+
+func (mock *NotificationMockUsecase) MarkRead(userID uint, ids []uint) (int, error) {
+	args := mock.Called(userID, ids)
+	return args.Int(0), args.Error(1)
+}
+
+func TestGetNotifications(t *testing.T) {
+	req := newFollowRequest("GET", "/notifications?limit=5", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(NotificationMockUsecase)
+
+	groups := []models.NotificationGroup{{IDs: []uint{3, 2}, Type: models.NotificationFollow, Actors: []models.User{{ID: 2, Username: "budi"}, {ID: 3, Username: "andi"}}, ActorCount: 2, Unread: true, Text: "budi and andi followed you"}}
+
+	mockUsecase.On("GetNotifications", uint(1), 5).Return(groups, 2, nil)
+
+	var notifications NotificationsResponse
+
+	notificationDeliv := NewNotificationDelivery(mockUsecase)
+	notificationDeliv.GetNotifications(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&notifications)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 2, notifications.UnreadCount)
+	assert.Equal(t, "budi and andi followed you", notifications.Notifications[0].Text)
+	assert.Equal(t, "budi", notifications.Notifications[0].Actors[0].Username)
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	req := newFollowRequest("POST", "/notifications/read?ids=5,6", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(NotificationMockUsecase)
+
+	mockUsecase.On("MarkRead", uint(1), []uint{5, 6}).Return(1, nil)
+
+	var notifications NotificationsResponse
+
+	notificationDeliv := NewNotificationDelivery(mockUsecase)
+	notificationDeliv.MarkRead(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&notifications)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, notifications.UnreadCount)
+}
+
+func TestMarkNotificationsReadInvalidIDs(t *testing.T) {
+	req := newFollowRequest("POST", "/notifications/read?ids=5,x", "")
+	resp := httptest.NewRecorder()
+
+	notificationDeliv := NewNotificationDelivery(new(NotificationMockUsecase))
+	notificationDeliv.MarkRead(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,49 @@
+package delivery
+
+import (
+	"summer-web/models"
+	"time"
+)
+
+// NotificationResponse is a group of notifications as shown to the user, the IDs are what marks the group as read
+type NotificationResponse struct {
+	IDs        []uint       `json:"ids"`
+	Type       string       `json:"type"`
+	PostID     uint         `json:"post_id,omitempty"`
+	Actors     []PostAuthor `json:"actors"`
+	ActorCount int          `json:"actor_count"`
+	Unread     bool         `json:"unread"`
+	Text       string       `json:"text"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// NotificationsResponse is the latest notifications of the user along with how many of them are unread
+type NotificationsResponse struct {
+	UnreadCount   int                    `json:"unread_count"`
+	Notifications []NotificationResponse `json:"notifications"`
+}
+
+func newNotificationResponses(groups []models.NotificationGroup) []NotificationResponse {
+	responses := make([]NotificationResponse, len(groups))
+
+	for i, group := range groups {
+		actors := make([]PostAuthor, len(group.Actors))
+
+		for j, actor := range group.Actors {
+			actors[j] = newPostAuthor(actor)
+		}
+
+		responses[i] = NotificationResponse{
+			IDs:        group.IDs,
+			Type:       group.Type,
+			PostID:     group.PostID,
+			Actors:     actors,
+			ActorCount: group.ActorCount,
+			Unread:     group.Unread,
+			Text:       group.Text,
+			CreatedAt:  group.CreatedAt,
+		}
+	}
+
+	return responses
+}
@@ -0,0 +1,57 @@
+package delivery
+
+import (
+	"log"
+	"net/http"
+	"summer-web/usecase"
+)
+
+// PasswordDelivery interface acts as Password Reset Controller
+type PasswordDelivery interface {
+	ForgotPassword(resp http.ResponseWriter, req *http.Request)
+	ResetPassword(resp http.ResponseWriter, req *http.Request)
+}
+
+type passwordDelivery struct{}
+
+var (
+	passwordUsecase usecase.PasswordUsecase
+)
+
+// NewPasswordDelivery returns new passwordDelivery struct that implements PasswordDelivery
+func NewPasswordDelivery(usecasePassword ...usecase.PasswordUsecase) PasswordDelivery {
+	if len(usecasePassword) > 0 {
+		passwordUsecase = usecasePassword[0]
+	} else {
+		passwordUsecase = usecase.NewPasswordUsecase()
+	}
+	return &passwordDelivery{}
+}
+
+// ForgotPassword always responds with 200 so the endpoint can't be used to find out which emails are registered
+func (*passwordDelivery) ForgotPassword(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	if err := passwordUsecase.ForgotPassword(req.FormValue("email")); err != nil {
+		log.Println("could not send password reset:", err)
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "if the email is registered, a password reset link has been sent to it"}`))
+}
+
+func (*passwordDelivery) ResetPassword(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	err := passwordUsecase.ResetPassword(req.FormValue("token"), req.FormValue("password"))
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "password has been reset, please log in again"}`))
+}
@@ -0,0 +1,109 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *PasswordMockUsecase) ForgotPassword(email string) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *PasswordMockUsecase) ResetPassword(token string, newPassword string) error {
+	args := mock.Called(token, newPassword)
+	return args.Error(0)
+}
+
+func TestForgotPasswordAlwaysOK(t *testing.T) {
+	req, err := http.NewRequest("POST", "/password/forgot", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PasswordMockUsecase)
+
+	mockUsecase.On("ForgotPassword").Return(fmt.Errorf("smtp is down"))
+
+	passwordDeliv := NewPasswordDelivery(mockUsecase)
+
+	passwordDeliv.ForgotPassword(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestResetPassword(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	err := w.WriteField("token", "abc")
+	if err != nil {
+		panic(err)
+	}
+
+	err = w.WriteField("password", "newpassword1")
+	if err != nil {
+		panic(err)
+	}
+
+	w.Close()
+
+	req, err := http.NewRequest("POST", "/password/reset", buf)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PasswordMockUsecase)
+
+	mockUsecase.On("ResetPassword", "abc", "newpassword1").Return(nil)
+
+	passwordDeliv := NewPasswordDelivery(mockUsecase)
+
+	passwordDeliv.ResetPassword(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	req, err := http.NewRequest("POST", "/password/reset?token=abc&password=newpassword1", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PasswordMockUsecase)
+
+	mockUsecase.On("ResetPassword", "abc", "newpassword1").Return(fmt.Errorf("error: invalid \"token\""))
+
+	passwordDeliv := NewPasswordDelivery(mockUsecase)
+
+	passwordDeliv.ResetPassword(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid", receivedResponse["token"])
+}
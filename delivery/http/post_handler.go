@@ -3,18 +3,25 @@ package delivery
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
-	"os"
+	"strconv"
+	"summer-web/media"
 	"summer-web/models"
 	"summer-web/usecase"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
 )
 
 // PostDelivery interface acts as Post Controller
 type PostDelivery interface {
 	GetPosts(resp http.ResponseWriter, req *http.Request)
+	GetUserPosts(resp http.ResponseWriter, req *http.Request)
+	GetFeed(resp http.ResponseWriter, req *http.Request)
+	GetTagPosts(resp http.ResponseWriter, req *http.Request)
 	AddPost(resp http.ResponseWriter, req *http.Request)
+	UpdatePost(resp http.ResponseWriter, req *http.Request)
 }
 
 type postDelivery struct{}
@@ -33,10 +40,19 @@ func NewPostDelivery(usecasePost ...usecase.PostUsecase) PostDelivery {
 	return &postDelivery{}
 }
 
+// GetPosts lists the posts the user may see, posts of private accounts the user doesn't follow are left out
 func (*postDelivery) GetPosts(resp http.ResponseWriter, req *http.Request) {
 	resp.Header().Set("Content-Type", "application/json")
 
-	posts, err := postUsecase.GetPosts()
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	posts, err := postUsecase.GetPosts(userID)
 
 	if err != nil {
 		key, value := trimError(err)
@@ -45,22 +61,167 @@ func (*postDelivery) GetPosts(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	json.NewEncoder(resp).Encode(posts)
+	json.NewEncoder(resp).Encode(newPostResponses(posts))
+}
+
+// GetUserPosts lists the posts of the user in the path, private accounts only show them to approved followers
+func (*postDelivery) GetUserPosts(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	viewerID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(req)["id"])
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	posts, err := postUsecase.GetPostsByUserID(viewerID, uint(userID))
+
+	if err != nil {
+		switch err {
+		case usecase.ErrUserNotFound:
+			writeError(resp, http.StatusNotFound, err)
+		case usecase.ErrPrivateAccount:
+			writeError(resp, http.StatusForbidden, err)
+		default:
+			writeError(resp, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	json.NewEncoder(resp).Encode(newPostResponses(posts))
+}
+
+// GetFeed lists the posts of the user and of the accounts the user follows
+func (*postDelivery) GetFeed(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	posts, err := postUsecase.GetFeed(userID)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(newPostResponses(posts))
+}
+
+// GetTagPosts lists a page of the posts tagged with the tag in the path, newest first. The "before" query parameter is
+// the ID of the last post of the previous page and "limit" the size of the page, the next page is linked in the Link
+// header when the page is full
+func (*postDelivery) GetTagPosts(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	beforeID, err := queryInt(req, "before")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	limit, err := queryInt(req, "limit")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	posts, err := postUsecase.GetPostsByTag(userID, mux.Vars(req)["tag"], uint(beforeID), limit)
+
+	switch err {
+	case nil:
+	case usecase.ErrInvalidTag:
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	pageSize := usecase.PageSize(limit)
+
+	if len(posts) == pageSize {
+		next := req.URL.Path + "?before=" + strconv.Itoa(int(posts[len(posts)-1].ID)) + "&limit=" + strconv.Itoa(pageSize)
+		resp.Header().Set("Link", "<"+next+">; rel=\"next\"")
+	}
+
+	json.NewEncoder(resp).Encode(newPostResponses(posts))
 }
 
+// queryInt returns the non-negative integer in the query parameter, or 0 if it is missing
+func queryInt(req *http.Request, name string) (int, error) {
+	value := req.URL.Query().Get(name)
+
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+
+	if err == nil && n < 0 {
+		err = fmt.Errorf("error: invalid \"%s\"", name)
+	}
+
+	return n, err
+}
+
+// maxPostRequestSize leaves room for the caption and the multipart framing next to the largest images allowed
+const maxPostRequestSize = usecase.MaxPostImages*media.MaxImageSize + 1<<20
+
+// AddPost creates a post from the caption and up to usecase.MaxPostImages files sent as "images" in a multipart form
 func (*postDelivery) AddPost(resp http.ResponseWriter, req *http.Request) {
 	resp.Header().Set("Content-Type", "application/json")
 
+	req.Body = http.MaxBytesReader(resp, req.Body, maxPostRequestSize)
+
+	if err := req.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
 	var newPost models.Post
 
 	addDataToPost(&newPost, req)
 
-	token, err := jwt.Parse(req.Header["Authorization"][0], func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
-	})
+	images, err := readImages(req)
+
+	if err == media.ErrTooLarge {
+		writeError(resp, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := getUserIDFromToken(req)
 
 	if err != nil {
 		resp.WriteHeader(http.StatusInternalServerError)
@@ -68,24 +229,85 @@ func (*postDelivery) AddPost(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
+	newPost.UserID = userID
 
-	userID := uint(claims["user_id"].(float64))
+	err = postUsecase.AddPost(&newPost, images...)
 
-	newPost.UserID = userID
+	switch err {
+	case nil:
+		json.NewEncoder(resp).Encode(newPost)
+	case usecase.ErrEmailNotVerified:
+		writeError(resp, http.StatusForbidden, err)
+	case media.ErrTooLarge:
+		writeError(resp, http.StatusRequestEntityTooLarge, err)
+	case media.ErrUnsupportedType:
+		writeError(resp, http.StatusUnsupportedMediaType, err)
+	case media.ErrCorrupt, usecase.ErrTooManyImages, usecase.ErrEmptyPost:
+		writeError(resp, http.StatusBadRequest, err)
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+	}
+}
+
+// UpdatePost changes the caption of the post in the path, only its author may edit it
+func (*postDelivery) UpdatePost(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
 
-	err = postUsecase.AddPost(&newPost)
+	userID, id, err := getUserIDAndPathID(req)
 
 	if err != nil {
-		key, value := trimError(err)
-		resp.WriteHeader(http.StatusInternalServerError)
-		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		writeError(resp, http.StatusBadRequest, err)
 		return
 	}
 
-	json.NewEncoder(resp).Encode(newPost)
+	post, err := postUsecase.UpdatePost(userID, id, req.FormValue("caption"))
+
+	switch err {
+	case nil:
+		json.NewEncoder(resp).Encode(newPostResponse(post))
+	case usecase.ErrPostNotFound:
+		writeError(resp, http.StatusNotFound, err)
+	case usecase.ErrNotPostAuthor:
+		writeError(resp, http.StatusForbidden, err)
+	case usecase.ErrEmptyPost:
+		writeError(resp, http.StatusBadRequest, err)
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+	}
 }
 
 func addDataToPost(post *models.Post, data *http.Request) {
 	post.Caption = data.FormValue("caption")
 }
+
+// readImages returns the contents of the "images" files, files above media.MaxImageSize are rejected without reading them
+func readImages(req *http.Request) ([][]byte, error) {
+	if req.MultipartForm == nil {
+		return nil, nil
+	}
+
+	images := [][]byte{}
+
+	for _, header := range req.MultipartForm.File["images"] {
+		if header.Size > media.MaxImageSize {
+			return nil, media.ErrTooLarge
+		}
+
+		file, err := header.Open()
+
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadAll(io.LimitReader(file, media.MaxImageSize+1))
+		file.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, data)
+	}
+
+	return images, nil
+}
@@ -3,31 +3,68 @@ package delivery
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"summer-web/media"
 	"summer-web/models"
+	"summer-web/usecase"
 	"testing"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 type PostMockUsecase struct {
 	mock.Mock
+	images [][]byte
 }
 
-func (mock *PostMockUsecase) GetPosts() ([]models.Post, error) {
-	args := mock.Called()
+func (mock *PostMockUsecase) GetPosts(viewerID uint) ([]models.Post, error) {
+	args := mock.Called(viewerID)
+
+	result := args.Get(0)
+
+	return result.([]models.Post), args.Error(1)
+}
+
+func (mock *PostMockUsecase) GetPostsByUserID(viewerID uint, userID uint) ([]models.Post, error) {
+	args := mock.Called(viewerID, userID)
 
 	result := args.Get(0)
 
 	return result.([]models.Post), args.Error(1)
 }
 
-func (mock *PostMockUsecase) AddPost(post *models.Post) error {
+func (mock *PostMockUsecase) GetFeed(viewerID uint) ([]models.Post, error) {
+	args := mock.Called(viewerID)
+
+	result := args.Get(0)
+
+	return result.([]models.Post), args.Error(1)
+}
+
+func (mock *PostMockUsecase) GetPostsByTag(viewerID uint, tag string, beforeID uint, limit int) ([]models.Post, error) {
+	args := mock.Called(tag, beforeID, limit)
+
+	return args.Get(0).([]models.Post), args.Error(1)
+}
+
+func (mock *PostMockUsecase) UpdatePost(userID uint, id uint, caption string) (models.Post, error) {
+	args := mock.Called(userID, id, caption)
+
+	return models.Post{ID: id, UserID: userID, Caption: caption}, args.Error(0)
+}
+
+func (mock *PostMockUsecase) AddPost(post *models.Post, images ...[]byte) error {
 	args := mock.Called()
 
+	mock.images = images
+
 	return args.Error(0)
 }
 
@@ -50,7 +87,7 @@ func TestGetPosts(t *testing.T) {
 	post := models.Post{Caption: "ADD", UserID: 123}
 	mockUsecase := new(PostMockUsecase)
 
-	mockUsecase.On("GetPosts").Return([]models.Post{post}, nil)
+	mockUsecase.On("GetPosts", uint(1)).Return([]models.Post{post}, nil)
 
 	posts := []models.Post{}
 
@@ -66,6 +103,224 @@ func TestGetPosts(t *testing.T) {
 	assert.Equal(t, post.UserID, posts[0].UserID)
 }
 
+func newPostRequest(caption string, images ...[]byte) *http.Request {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	w.WriteField("caption", caption)
+
+	for i, image := range images {
+		file, err := w.CreateFormFile("images", "image"+strconv.Itoa(i)+".png")
+
+		if err != nil {
+			panic(err)
+		}
+
+		file.Write(image)
+	}
+
+	w.Close()
+
+	req, err := http.NewRequest("POST", "/posts", buf)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return req
+}
+
+func TestAddPostWithImages(t *testing.T) {
+	req := newPostRequest("look", []byte("first image"), []byte("second image"))
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("AddPost").Return(nil)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.AddPost(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 2, len(mockUsecase.images))
+	assert.Equal(t, "second image", string(mockUsecase.images[1]))
+}
+
+func TestAddPostUnsupportedImage(t *testing.T) {
+	req := newPostRequest("look", []byte("<html></html>"))
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("AddPost").Return(media.ErrUnsupportedType)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.AddPost(resp, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+	assert.JSONEq(t, `{"media_content_type_key": "unsupported type"}`, resp.Body.String())
+}
+
+func TestAddPostImageTooLarge(t *testing.T) {
+	req := newPostRequest("look", make([]byte, media.MaxImageSize+1))
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.AddPost(resp, req)
+
+	mockUsecase.AssertNotCalled(t, "AddPost")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+}
+
+func TestGetUserPosts(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/2/posts", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("GetPostsByUserID", uint(1), uint(2)).Return([]models.Post{{Caption: "ADD", UserID: 2}}, nil)
+
+	posts := []models.Post{}
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetUserPosts(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&posts)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, len(posts))
+}
+
+func TestGetUserPostsPrivateAccount(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/2/posts", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("GetPostsByUserID", uint(1), uint(2)).Return([]models.Post(nil), usecase.ErrPrivateAccount)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetUserPosts(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(t, `{"users_is_private_key": "private"}`, resp.Body.String())
+}
+
+func TestGetUserPostsDatabaseError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/2/posts", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("GetPostsByUserID", uint(1), uint(2)).Return([]models.Post(nil), fmt.Errorf("pq: connection refused"))
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetUserPosts(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"error": "pq: connection refused"}`, resp.Body.String())
+}
+
+func TestGetFeed(t *testing.T) {
+	req, err := http.NewRequest("GET", "/feed", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	author := models.User{ID: 2, Username: "budi", Email: "budi@budi.com", Password: "123", AvatarKey: "avatars/2/abc.jpg"}
+
+	mockUsecase.On("GetFeed", uint(1)).Return([]models.Post{{Caption: "ADD", UserID: 2, Author: &author}}, nil)
+
+	posts := []map[string]interface{}{}
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetFeed(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&posts)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, len(posts))
+
+	result := posts[0]["author"].(map[string]interface{})
+
+	assert.Equal(t, "budi", result["username"])
+	assert.True(t, strings.HasPrefix(result["avatar_urls"].(map[string]interface{})["small"].(string), "/media/avatars/2/abc_small.jpg?expires="))
+	assert.NotContains(t, result, "email")
+	assert.NotContains(t, result, "password")
+}
+
 func TestAddPost(t *testing.T) {
 	buf := new(bytes.Buffer)
 
@@ -114,3 +369,223 @@ func TestAddPost(t *testing.T) {
 	assert.Equal(t, uint(1), receivedResponse.UserID)
 	assert.Equal(t, "hello world!", receivedResponse.Caption)
 }
+
+func TestAddPostUnverifiedEmail(t *testing.T) {
+	req, err := http.NewRequest("POST", "/posts", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("AddPost").Return(usecase.ErrEmailNotVerified)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.AddPost(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestGetTagPosts(t *testing.T) {
+	req, err := http.NewRequest("GET", "/tags/go/posts?before=9&limit=2", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"tag": "go"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("GetPostsByTag", "go", uint(9), 2).Return([]models.Post{{ID: 8, Caption: "#go"}, {ID: 5, Caption: "#Go"}}, nil)
+
+	posts := []models.Post{}
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetTagPosts(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&posts)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 2, len(posts))
+	assert.Equal(t, `</tags/go/posts?before=5&limit=2>; rel="next"`, resp.Header().Get("Link"))
+}
+
+func TestGetTagPostsLastPage(t *testing.T) {
+	req, err := http.NewRequest("GET", "/tags/go/posts", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"tag": "go"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("GetPostsByTag", "go", uint(0), 0).Return([]models.Post{{ID: 8, Caption: "#go"}}, nil)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetTagPosts(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "", resp.Header().Get("Link"))
+}
+
+func TestGetTagPostsInvalidTag(t *testing.T) {
+	req, err := http.NewRequest("GET", "/tags/a-b/posts", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"tag": "a-b"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("GetPostsByTag", "a-b", uint(0), 0).Return([]models.Post(nil), usecase.ErrInvalidTag)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.GetTagPosts(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestUpdatePost(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/posts/4", strings.NewReader("caption=now+%23rust"))
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "4"})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("UpdatePost", uint(1), uint(4), "now #rust").Return(nil)
+
+	var post models.Post
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.UpdatePost(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&post)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "now #rust", post.Caption)
+}
+
+func TestUpdatePostEmptyCaption(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/posts/4", strings.NewReader("caption="))
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "4"})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("UpdatePost", uint(1), uint(4), "").Return(usecase.ErrEmptyPost)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.UpdatePost(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, `{"posts_caption_key":"can't be null"}`, strings.TrimSpace(resp.Body.String()))
+}
+
+func TestUpdatePostNotAuthor(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/posts/4", strings.NewReader("caption=mine"))
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "4"})
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(PostMockUsecase)
+
+	mockUsecase.On("UpdatePost", uint(1), uint(4), "mine").Return(usecase.ErrNotPostAuthor)
+
+	postDeliv := NewPostDelivery(mockUsecase)
+
+	postDeliv.UpdatePost(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"summer-web/models"
+	"summer-web/usecase"
+)
+
+// PostAuthor is what listings show of the author of each post
+type PostAuthor struct {
+	ID         uint              `json:"id"`
+	Username   string            `json:"username"`
+	Name       string            `json:"name"`
+	AvatarURL  string            `json:"avatar_url"`
+	AvatarURLs map[string]string `json:"avatar_urls"`
+}
+
+// PostResponse is a post along with its author, if the author was loaded
+type PostResponse struct {
+	models.Post
+	Author *PostAuthor `json:"author"`
+}
+
+func newPostResponse(post models.Post) PostResponse {
+	response := PostResponse{Post: post}
+
+	if post.Author != nil {
+		author := newPostAuthor(*post.Author)
+		response.Author = &author
+	}
+
+	return response
+}
+
+func newPostAuthor(user models.User) PostAuthor {
+	return PostAuthor{
+		ID:         user.ID,
+		Username:   user.Username,
+		Name:       user.Name,
+		AvatarURL:  usecase.AvatarURL(user),
+		AvatarURLs: usecase.AvatarURLs(user),
+	}
+}
+
+func newPostResponses(posts []models.Post) []PostResponse {
+	responses := make([]PostResponse, len(posts))
+
+	for i, post := range posts {
+		responses[i] = newPostResponse(post)
+	}
+
+	return responses
+}
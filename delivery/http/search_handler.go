@@ -0,0 +1,110 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"summer-web/usecase"
+)
+
+// SearchDelivery interface acts as Search Controller
+type SearchDelivery interface {
+	Search(resp http.ResponseWriter, req *http.Request)
+}
+
+type searchDelivery struct{}
+
+var (
+	searchUsecase usecase.SearchUsecase
+)
+
+// errInvalidSearchType is returned when the type parameter is neither posts nor users
+var errInvalidSearchType = fmt.Errorf("error: invalid \"search_type_key\"")
+
+// NewSearchDelivery returns new searchDelivery struct that implements SearchDelivery
+func NewSearchDelivery(usecaseSearch ...usecase.SearchUsecase) SearchDelivery {
+	if len(usecaseSearch) > 0 {
+		searchUsecase = usecaseSearch[0]
+	} else {
+		searchUsecase = usecase.NewSearchUsecase()
+	}
+	return &searchDelivery{}
+}
+
+// Search lists a page of the posts, or of the public profiles with type=users, that match the q parameter, best
+// match first. Pages are picked with "offset" and "limit", the next page is linked in the Link header when the page
+// is full
+func (*searchDelivery) Search(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	offset, err := queryInt(req, "offset")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	limit, err := queryInt(req, "limit")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	query := req.URL.Query().Get("q")
+	searchType := req.URL.Query().Get("type")
+
+	var results interface{}
+	var count int
+
+	switch searchType {
+	case "", "posts":
+		searchType = "posts"
+		posts, searchErr := searchUsecase.SearchPosts(userID, query, offset, limit)
+		results, count, err = newPostResponses(posts), len(posts), searchErr
+	case "users":
+		users, searchErr := searchUsecase.SearchUsers(userID, query, offset, limit)
+		profiles := make([]PublicProfile, len(users))
+
+		for i, user := range users {
+			profiles[i] = newPublicProfile(user)
+		}
+
+		results, count, err = profiles, len(users), searchErr
+	default:
+		err = errInvalidSearchType
+	}
+
+	switch err {
+	case nil:
+	case errInvalidSearchType, usecase.ErrEmptyQuery, usecase.ErrQueryTooLong:
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	pageSize := usecase.PageSize(limit)
+
+	if count == pageSize {
+		next := url.Values{}
+		next.Set("q", query)
+		next.Set("type", searchType)
+		next.Set("offset", strconv.Itoa(offset+pageSize))
+		next.Set("limit", strconv.Itoa(pageSize))
+		resp.Header().Set("Link", "<"+req.URL.Path+"?"+next.Encode()+">; rel=\"next\"")
+	}
+
+	json.NewEncoder(resp).Encode(results)
+}
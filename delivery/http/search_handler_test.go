@@ -0,0 +1,91 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"summer-web/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SearchMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *SearchMockUsecase) SearchPosts(viewerID uint, query string, offset int, limit int) ([]models.Post, error) {
+	args := mock.Called(viewerID, query, offset, limit)
+	return args.Get(0).([]models.Post), args.Error(1)
+}
+
+func (mock *SearchMockUsecase) SearchUsers(viewerID uint, query string, offset int, limit int) ([]models.User, error) {
+	args := mock.Called(viewerID, query, offset, limit)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func TestSearchPosts(t *testing.T) {
+	req := newFollowRequest("GET", "/search?q=summer+beach&limit=1", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SearchMockUsecase)
+
+	mockUsecase.On("SearchPosts", uint(1), "summer beach", 0, 1).Return([]models.Post{{ID: 3, Caption: "summer at the beach"}}, nil)
+
+	posts := []models.Post{}
+
+	searchDeliv := NewSearchDelivery(mockUsecase)
+	searchDeliv.Search(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&posts)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, len(posts))
+	assert.Equal(t, `</search?limit=1&offset=1&q=summer+beach&type=posts>; rel="next"`, resp.Header().Get("Link"))
+}
+
+func TestSearchUsers(t *testing.T) {
+	req := newFollowRequest("GET", "/search?q=jo&type=users&offset=20", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SearchMockUsecase)
+
+	mockUsecase.On("SearchUsers", uint(1), "jo", 20, 0).Return([]models.User{{ID: 2, Username: "joko", Email: "joko@joko.com"}}, nil)
+
+	var profiles []map[string]interface{}
+
+	searchDeliv := NewSearchDelivery(mockUsecase)
+	searchDeliv.Search(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&profiles)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "joko", profiles[0]["username"])
+	assert.NotContains(t, profiles[0], "email")
+	assert.Equal(t, "", resp.Header().Get("Link"))
+}
+
+func TestSearchInvalidType(t *testing.T) {
+	req := newFollowRequest("GET", "/search?q=jo&type=tags", "")
+	resp := httptest.NewRecorder()
+
+	searchDeliv := NewSearchDelivery(new(SearchMockUsecase))
+	searchDeliv.Search(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	req := newFollowRequest("GET", "/search?q=", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SearchMockUsecase)
+
+	mockUsecase.On("SearchPosts", uint(1), "", 0, 0).Return([]models.Post(nil), usecase.ErrEmptyQuery)
+
+	searchDeliv := NewSearchDelivery(mockUsecase)
+	searchDeliv.Search(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,127 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"summer-web/delivery/middleware"
+	"summer-web/models"
+	"summer-web/usecase"
+
+	"github.com/gorilla/mux"
+)
+
+// SessionDelivery interface acts as Session Controller
+type SessionDelivery interface {
+	GetSessions(resp http.ResponseWriter, req *http.Request)
+	RevokeSession(resp http.ResponseWriter, req *http.Request)
+	RefreshToken(resp http.ResponseWriter, req *http.Request)
+}
+
+type sessionDelivery struct{}
+
+var (
+	sessionUsecase usecase.SessionUsecase
+)
+
+// NewSessionDelivery returns new sessionDelivery struct that implements SessionDelivery
+func NewSessionDelivery(usecaseSession ...usecase.SessionUsecase) SessionDelivery {
+	if len(usecaseSession) > 0 {
+		sessionUsecase = usecaseSession[0]
+	} else {
+		sessionUsecase = usecase.NewSessionUsecase()
+	}
+	return &sessionDelivery{}
+}
+
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessions lists where the user is logged in, the session of the request is marked as current
+func (*sessionDelivery) GetSessions(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	sessions, err := sessionUsecase.GetSessions(userID)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	currentID, _ := middleware.SessionID(req)
+	result := make([]sessionResponse, len(sessions))
+
+	for i, session := range sessions {
+		result[i] = sessionResponse{Session: session, Current: session.ID == currentID}
+	}
+
+	json.NewEncoder(resp).Encode(result)
+}
+
+// RevokeSession logs out one device, revoking the current session works as a logout
+func (*sessionDelivery) RevokeSession(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(req)["id"])
+
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = sessionUsecase.RevokeSession(userID, uint(id))
+
+	if err != nil {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "session revoked"}`))
+}
+
+// RefreshToken trades the auth token of the request, expired or not, for a new one while its session is active
+func (*sessionDelivery) RefreshToken(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	rawToken := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	token, err := sessionUsecase.RefreshToken(rawToken)
+
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		if hasErrorKey(err) {
+			status = http.StatusUnauthorized
+		}
+
+		writeError(resp, status, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"auth_token": "` + token + `"}`))
+}
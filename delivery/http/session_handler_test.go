@@ -0,0 +1,176 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *SessionMockUsecase) GetSessions(userID uint) ([]models.Session, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (mock *SessionMockUsecase) RevokeSession(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func (mock *SessionMockUsecase) ValidateSession(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func (mock *SessionMockUsecase) RefreshToken(rawToken string) (string, error) {
+	args := mock.Called(rawToken)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *SessionMockUsecase) PurgeExpiredSessions() error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func TestGetSessions(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/me/sessions", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SessionMockUsecase)
+
+	mockUsecase.On("GetSessions").Return([]models.Session{{ID: 1, UserAgent: "curl/7.68.0", IP: "127.0.0.1"}}, nil)
+
+	sessionDeliv := NewSessionDelivery(mockUsecase)
+
+	sessionDeliv.GetSessions(resp, req)
+
+	var receivedResponse []map[string]interface{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "curl/7.68.0", receivedResponse[0]["user_agent"])
+	assert.Equal(t, false, receivedResponse[0]["current"])
+}
+
+func TestRevokeSession(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/users/me/sessions/2", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SessionMockUsecase)
+
+	mockUsecase.On("RevokeSession", uint(1), uint(2)).Return(nil)
+
+	sessionDeliv := NewSessionDelivery(mockUsecase)
+
+	sessionDeliv.RevokeSession(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRevokeSessionNotFound(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/users/me/sessions/2", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SessionMockUsecase)
+
+	mockUsecase.On("RevokeSession", uint(1), uint(2)).Return(fmt.Errorf("error: not found \"sessions_id_key\""))
+
+	sessionDeliv := NewSessionDelivery(mockUsecase)
+
+	sessionDeliv.RevokeSession(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestRefreshToken(t *testing.T) {
+	req, err := http.NewRequest("POST", "/sessions/refresh", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer expired")
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SessionMockUsecase)
+
+	mockUsecase.On("RefreshToken", "expired").Return("fresh", nil)
+
+	sessionDeliv := NewSessionDelivery(mockUsecase)
+
+	sessionDeliv.RefreshToken(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"auth_token": "fresh"}`, resp.Body.String())
+}
+
+func TestRefreshTokenOfRevokedSession(t *testing.T) {
+	req, err := http.NewRequest("POST", "/sessions/refresh", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer expired")
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SessionMockUsecase)
+
+	mockUsecase.On("RefreshToken", "expired").Return("", fmt.Errorf("error: revoked \"session\""))
+
+	sessionDeliv := NewSessionDelivery(mockUsecase)
+
+	sessionDeliv.RefreshToken(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
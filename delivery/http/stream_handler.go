@@ -0,0 +1,116 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"summer-web/delivery/middleware"
+	"summer-web/models"
+	"summer-web/stream"
+	"summer-web/usecase"
+	"time"
+)
+
+// StreamDelivery interface acts as Stream Controller
+type StreamDelivery interface {
+	Stream(resp http.ResponseWriter, req *http.Request)
+}
+
+type streamDelivery struct{}
+
+var (
+	streamUsecase usecase.StreamUsecase
+
+	// comments are sent this often so that proxies keep idle streams open and dead connections are noticed
+	streamHeartbeat = 15 * time.Second
+
+	// the stream is checked on each heartbeat, its session or access token may have been revoked or have expired since
+	revalidateStream = middleware.Revalidate
+
+	errStreamingUnsupported = fmt.Errorf("error: unsupported \"stream\"")
+)
+
+// NewStreamDelivery returns new streamDelivery struct that implements StreamDelivery
+func NewStreamDelivery(usecaseStream ...usecase.StreamUsecase) StreamDelivery {
+	if len(usecaseStream) > 0 {
+		streamUsecase = usecaseStream[0]
+	} else {
+		streamUsecase = usecase.NewStreamUsecase()
+	}
+	return &streamDelivery{}
+}
+
+// Stream pushes the new notifications and feed posts of the user as server-sent events until the client goes away or
+// its session ends. Clients that fall behind are disconnected and should reconnect and catch up through /notifications
+// and /feed
+func (*streamDelivery) Stream(resp http.ResponseWriter, req *http.Request) {
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	flusher, ok := resp.(http.Flusher)
+
+	if !ok {
+		writeError(resp, http.StatusInternalServerError, errStreamingUnsupported)
+		return
+	}
+
+	subscription := streamUsecase.Subscribe(userID)
+	defer streamUsecase.Unsubscribe(subscription)
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("X-Accel-Buffering", "no")
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case event, open := <-subscription.Events:
+			if !open {
+				return
+			}
+
+			if err := writeEvent(resp, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if revalidateStream(req) != nil {
+				return
+			}
+
+			if _, err := fmt.Fprint(resp, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event in the server-sent events format, posts are shown the way listings show them
+func writeEvent(resp http.ResponseWriter, event stream.Event) error {
+	data := event.Data
+
+	if post, ok := data.(models.Post); ok {
+		data = newPostResponse(post)
+	}
+
+	encoded, err := json.Marshal(data)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", event.Type, encoded)
+
+	return err
+}
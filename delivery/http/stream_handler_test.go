@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"summer-web/stream"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// StreamMockUsecase streams from a real hub and keeps the last subscription
+type StreamMockUsecase struct {
+	hub          stream.Hub
+	subscription *stream.Subscription
+}
+
+func (mock *StreamMockUsecase) Subscribe(userID uint) *stream.Subscription {
+	mock.subscription = mock.hub.Subscribe(userID)
+	return mock.subscription
+}
+
+func (mock *StreamMockUsecase) Unsubscribe(subscription *stream.Subscription) {
+	mock.hub.Unsubscribe(subscription)
+}
+
+func openStream(t *testing.T, sessionErr ...error) (*StreamMockUsecase, *http.Response, func()) {
+	revalidateStream = func(req *http.Request) error {
+		if len(sessionErr) > 0 {
+			return sessionErr[0]
+		}
+		return nil
+	}
+
+	mockUsecase := &StreamMockUsecase{hub: stream.NewHub(stream.DefaultBufferSize)}
+	streamDeliv := NewStreamDelivery(mockUsecase)
+	server := httptest.NewServer(http.HandlerFunc(streamDeliv.Stream))
+
+	req, _ := http.NewRequest("GET", server.URL+"/stream", nil)
+	token, _ := generateToken()
+	req.Header.Set("Authorization", token)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mockUsecase, resp, func() {
+		resp.Body.Close()
+		server.Close()
+	}
+}
+
+func TestStream(t *testing.T) {
+	mockUsecase, resp, closeStream := openStream(t)
+	defer closeStream()
+
+	mockUsecase.hub.Publish(stream.Event{Type: stream.EventPost, Data: models.Post{ID: 7, Caption: "hello", Author: &models.User{ID: 2, Username: "budi"}}}, 1)
+
+	reader := bufio.NewReader(resp.Body)
+	event, _ := reader.ReadString('\n')
+	data, _ := reader.ReadString('\n')
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, uint(1), mockUsecase.subscription.UserID)
+	assert.Equal(t, "event: post\n", event)
+	assert.Contains(t, data, `"caption":"hello"`)
+	assert.Contains(t, data, `"username":"budi"`)
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	streamHeartbeat = time.Millisecond
+	defer func() { streamHeartbeat = 15 * time.Second }()
+
+	_, resp, closeStream := openStream(t)
+	defer closeStream()
+
+	line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+
+	assert.Equal(t, ": heartbeat\n", line)
+}
+
+func TestStreamEndsWhenDropped(t *testing.T) {
+	mockUsecase, resp, closeStream := openStream(t)
+	defer closeStream()
+
+	mockUsecase.hub.Unsubscribe(mockUsecase.subscription)
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	assert.Nil(t, err)
+	assert.Empty(t, body)
+	assert.Equal(t, 0, mockUsecase.hub.Connected())
+}
+
+func TestStreamEndsWhenSessionRevoked(t *testing.T) {
+	streamHeartbeat = time.Millisecond
+	defer func() { streamHeartbeat = 15 * time.Second }()
+
+	mockUsecase, resp, closeStream := openStream(t, fmt.Errorf("error: revoked \"session\""))
+	defer closeStream()
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	assert.Nil(t, err)
+	assert.Empty(t, body)
+	assert.Equal(t, 0, mockUsecase.hub.Connected())
+}
@@ -0,0 +1,57 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"summer-web/usecase"
+)
+
+// SuggestionDelivery interface acts as Suggestion Controller
+type SuggestionDelivery interface {
+	GetSuggestions(resp http.ResponseWriter, req *http.Request)
+}
+
+type suggestionDelivery struct{}
+
+var (
+	suggestionUsecase usecase.SuggestionUsecase
+)
+
+// NewSuggestionDelivery returns new suggestionDelivery struct that implements SuggestionDelivery
+func NewSuggestionDelivery(usecaseSuggestion ...usecase.SuggestionUsecase) SuggestionDelivery {
+	if len(usecaseSuggestion) > 0 {
+		suggestionUsecase = usecaseSuggestion[0]
+	} else {
+		suggestionUsecase = usecase.NewSuggestionUsecase()
+	}
+	return &suggestionDelivery{}
+}
+
+// GetSuggestions lists up to "limit" public profiles of accounts followed by the accounts the user follows
+func (*suggestionDelivery) GetSuggestions(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	limit, err := queryInt(req, "limit")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	suggestions, err := suggestionUsecase.GetSuggestions(userID, limit)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(newSuggestionResponses(suggestions))
+}
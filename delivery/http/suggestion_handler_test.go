@@ -0,0 +1,50 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SuggestionMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *SuggestionMockUsecase) GetSuggestions(userID uint, limit int) ([]models.Suggestion, error) {
+	args := mock.Called(userID, limit)
+	return args.Get(0).([]models.Suggestion), args.Error(1)
+}
+
+func (mock *SuggestionMockUsecase) PrecomputeSuggestions() error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func TestGetSuggestions(t *testing.T) {
+	req := newFollowRequest("GET", "/users/suggestions?limit=5", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(SuggestionMockUsecase)
+
+	suggested := models.User{ID: 4, Username: "andi", Email: "andi@andi.com"}
+
+	mockUsecase.On("GetSuggestions", uint(1), 5).Return([]models.Suggestion{{SuggestedID: 4, MutualCount: 3, RecentPosts: 2, Suggested: &suggested}}, nil)
+
+	var suggestions []map[string]interface{}
+
+	suggestionDeliv := NewSuggestionDelivery(mockUsecase)
+	suggestionDeliv.GetSuggestions(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&suggestions)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "andi", suggestions[0]["username"])
+	assert.Equal(t, float64(3), suggestions[0]["mutual_count"])
+	assert.Equal(t, float64(2), suggestions[0]["recent_posts"])
+	assert.NotContains(t, suggestions[0], "email")
+}
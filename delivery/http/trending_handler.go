@@ -0,0 +1,70 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"summer-web/usecase"
+)
+
+// TrendingDelivery interface acts as Trending Controller
+type TrendingDelivery interface {
+	GetTrending(resp http.ResponseWriter, req *http.Request)
+}
+
+type trendingDelivery struct{}
+
+var (
+	trendingUsecase usecase.TrendingUsecase
+)
+
+// NewTrendingDelivery returns new trendingDelivery struct that implements TrendingDelivery
+func NewTrendingDelivery(usecaseTrending ...usecase.TrendingUsecase) TrendingDelivery {
+	if len(usecaseTrending) > 0 {
+		trendingUsecase = usecaseTrending[0]
+	} else {
+		trendingUsecase = usecase.NewTrendingUsecase()
+	}
+	return &trendingDelivery{}
+}
+
+// GetTrending lists up to "limit" hashtags growing fastest in the last "hours", up to usecase.MaxTrendingHours, or
+// else in the last usecase.TrendingWindow, as of the last run of the trending worker
+func (*trendingDelivery) GetTrending(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	limit, err := queryInt(req, "limit")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	hours, err := queryInt(req, "hours")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	tags, err := trendingUsecase.GetTrending(userID, hours, limit)
+
+	if err == usecase.ErrInvalidTrendingWindow {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(tags)
+}
@@ -0,0 +1,69 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"summer-web/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type TrendingMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *TrendingMockUsecase) ComputeTrending() error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *TrendingMockUsecase) GetTrending(viewerID uint, hours int, limit int) ([]models.TrendingTag, error) {
+	args := mock.Called(viewerID, hours, limit)
+	return args.Get(0).([]models.TrendingTag), args.Error(1)
+}
+
+func TestGetTrending(t *testing.T) {
+	req := newFollowRequest("GET", "/trending?limit=5&hours=6", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(TrendingMockUsecase)
+
+	mockUsecase.On("GetTrending", uint(1), 6, 5).Return([]models.TrendingTag{{Tag: "summer", Score: 4.5, Count: 7}}, nil)
+
+	var tags []models.TrendingTag
+
+	trendingDeliv := NewTrendingDelivery(mockUsecase)
+	trendingDeliv.GetTrending(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&tags)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "summer", tags[0].Tag)
+}
+
+func TestGetTrendingInvalidLimit(t *testing.T) {
+	req := newFollowRequest("GET", "/trending?limit=-1", "")
+	resp := httptest.NewRecorder()
+
+	trendingDeliv := NewTrendingDelivery(new(TrendingMockUsecase))
+	trendingDeliv.GetTrending(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetTrendingInvalidHours(t *testing.T) {
+	req := newFollowRequest("GET", "/trending?hours=1000", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(TrendingMockUsecase)
+
+	mockUsecase.On("GetTrending", uint(1), 1000, 0).Return([]models.TrendingTag{}, usecase.ErrInvalidTrendingWindow)
+
+	trendingDeliv := NewTrendingDelivery(mockUsecase)
+	trendingDeliv.GetTrending(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
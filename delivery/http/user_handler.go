@@ -3,10 +3,16 @@ package delivery
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"summer-web/delivery/middleware"
+	"summer-web/media"
 	"summer-web/models"
 	"summer-web/usecase"
 
@@ -17,9 +23,14 @@ import (
 // UserDelivery interface acts as Post Controller
 type UserDelivery interface {
 	GetUserByID(resp http.ResponseWriter, req *http.Request)
+	GetUserByUsername(resp http.ResponseWriter, req *http.Request)
+	GetCurrentUser(resp http.ResponseWriter, req *http.Request)
 	AddUser(resp http.ResponseWriter, req *http.Request)
 	Login(resp http.ResponseWriter, req *http.Request)
 	UpdateUser(resp http.ResponseWriter, req *http.Request)
+	UploadAvatar(resp http.ResponseWriter, req *http.Request)
+	DeleteUser(resp http.ResponseWriter, req *http.Request)
+	ChangePassword(resp http.ResponseWriter, req *http.Request)
 }
 
 type userDelivery struct{}
@@ -41,33 +52,57 @@ func NewUserDelivery(usecaseUser ...usecase.UserUsecase) UserDelivery {
 func (*userDelivery) GetUserByID(resp http.ResponseWriter, req *http.Request) {
 	resp.Header().Set("Content-Type", "application/json")
 
-	vars := mux.Vars(req)
-	id, err := strconv.Atoi(vars["id"])
+	viewerID, id, err := getUserIDAndPathID(req)
 
 	if err != nil {
-		key, value := trimError(err)
-		resp.WriteHeader(http.StatusInternalServerError)
-		resp.Write([]byte(`{` + key + `:` + value + `}`))
-		fmt.Println(err)
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
 		return
 	}
 
-	uid := uint(id)
+	var user models.User
+
+	err = userUsecase.GetUserProfile(viewerID, id, &user)
+
+	writeUserResponse(resp, req, user, err)
+}
+
+// GetUserByUsername resolves a handle, the username is matched case-insensitively
+func (*userDelivery) GetUserByUsername(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	viewerID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
 
 	var user models.User
 
-	err = userUsecase.GetUserByID(uid, &user)
+	err = userUsecase.GetUserByUsername(viewerID, mux.Vars(req)["username"], &user)
+
+	writeUserResponse(resp, req, user, err)
+}
+
+// GetCurrentUser returns the self profile of the user the request is authorized for
+func (*userDelivery) GetCurrentUser(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
 
 	if err != nil {
-		key, value := trimError(err)
 		resp.WriteHeader(http.StatusInternalServerError)
-		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
 		return
 	}
 
-	sanitizePassword(&user)
+	var user models.User
 
-	json.NewEncoder(resp).Encode(user)
+	err = userUsecase.GetUserByID(userID, &user)
+
+	writeUserResponse(resp, req, user, err)
 }
 
 func (*userDelivery) AddUser(resp http.ResponseWriter, req *http.Request) {
@@ -83,6 +118,8 @@ func (*userDelivery) AddUser(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	newUser.Password = req.FormValue("password")
+
 	err = userUsecase.AddUser(&newUser)
 
 	if err != nil {
@@ -92,27 +129,22 @@ func (*userDelivery) AddUser(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sanitizePassword(&newUser)
-
-	json.NewEncoder(resp).Encode(newUser)
+	json.NewEncoder(resp).Encode(newSelfProfile(newUser))
 }
 
 func (*userDelivery) UpdateUser(resp http.ResponseWriter, req *http.Request) {
 	resp.Header().Set("Content-Type", "application/json")
 
-	token, err := jwt.Parse(req.Header["Authorization"][0], func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
-	})
+	userID, err := getUserIDFromToken(req)
 
-	claims := token.Claims.(jwt.MapClaims)
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
 
 	var user models.User
 
-	userID := uint(claims["user_id"].(float64))
-
 	err = userUsecase.GetUserByID(userID, &user)
 	if err != nil {
 		key, value := trimError(err)
@@ -138,9 +170,158 @@ func (*userDelivery) UpdateUser(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sanitizePassword(&user)
+	json.NewEncoder(resp).Encode(newSelfProfile(user))
+}
+
+// maxAvatarRequestSize leaves room for the multipart framing next to the largest image allowed
+const maxAvatarRequestSize = media.MaxImageSize + 1<<20
+
+// errAvatarMissing is returned when the request carries no image
+var errAvatarMissing = fmt.Errorf("error: can't be null \"users_avatar_key\"")
+
+// UploadAvatar replaces the avatar of the user with the image sent as the "avatar" file of a multipart form, or as
+// the request body
+func (*userDelivery) UploadAvatar(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	req.Body = http.MaxBytesReader(resp, req.Body, maxAvatarRequestSize)
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	image, err := readAvatar(req)
+
+	if err == media.ErrTooLarge {
+		writeError(resp, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var user models.User
+
+	err = userUsecase.SetAvatar(userID, image, &user)
+
+	switch err {
+	case nil:
+		json.NewEncoder(resp).Encode(newSelfProfile(user))
+	case usecase.ErrUserNotFound:
+		writeError(resp, http.StatusNotFound, err)
+	case media.ErrTooLarge:
+		writeError(resp, http.StatusRequestEntityTooLarge, err)
+	case media.ErrUnsupportedType:
+		writeError(resp, http.StatusUnsupportedMediaType, err)
+	case media.ErrCorrupt:
+		writeError(resp, http.StatusBadRequest, err)
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+	}
+}
+
+// readAvatar returns the "avatar" file of a multipart form, or else the whole body, images above media.MaxImageSize
+// are rejected
+func readAvatar(req *http.Request) ([]byte, error) {
+	var body io.Reader = req.Body
+
+	err := req.ParseMultipartForm(32 << 20)
+
+	if err != nil && err != http.ErrNotMultipart {
+		return nil, err
+	}
+
+	if err == nil {
+		file, header, err := req.FormFile("avatar")
+
+		if err != nil {
+			return nil, errAvatarMissing
+		}
+
+		defer file.Close()
+
+		if header.Size > media.MaxImageSize {
+			return nil, media.ErrTooLarge
+		}
+
+		body = file
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, media.MaxImageSize+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > media.MaxImageSize {
+		return nil, media.ErrTooLarge
+	}
+
+	if len(data) == 0 {
+		return nil, errAvatarMissing
+	}
+
+	return data, nil
+}
+
+func (*userDelivery) DeleteUser(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = userUsecase.DeleteUser(userID, req.FormValue("password"))
+
+	if err == usecase.ErrIncorrectPassword {
+		writeError(resp, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"message": "account scheduled for deletion, log in again before the grace period ends to reactivate it"}`))
+}
+
+func (*userDelivery) ChangePassword(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
 
-	json.NewEncoder(resp).Encode(user)
+	token, err := userUsecase.ChangePassword(userID, req.FormValue("old_password"), req.FormValue("new_password"), clientIP(req), req.UserAgent())
+
+	switch err {
+	case nil:
+	case usecase.ErrIncorrectPassword, usecase.ErrUnchangedPassword, usecase.ErrWeakPassword:
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(`{"auth_token": "` + token + `"}`))
 }
 
 func (*userDelivery) Login(resp http.ResponseWriter, req *http.Request) {
@@ -148,10 +329,21 @@ func (*userDelivery) Login(resp http.ResponseWriter, req *http.Request) {
 
 	var loginData models.User
 
+	// username accepts either the username or the email of the account
 	loginData.Username = req.FormValue("username")
+	if loginData.Username == "" {
+		loginData.Username = req.FormValue("email")
+	}
 	loginData.Password = req.FormValue("password")
 
-	token, err := userUsecase.Login(loginData)
+	token, mfaRequired, err := userUsecase.Login(loginData, clientIP(req), req.UserAgent())
+
+	if lockedOut, ok := err.(*usecase.TooManyAttemptsError); ok {
+		resp.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedOut.RetryAfter.Seconds()))))
+		resp.WriteHeader(http.StatusTooManyRequests)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
 
 	if err != nil {
 		resp.WriteHeader(http.StatusInternalServerError)
@@ -159,12 +351,86 @@ func (*userDelivery) Login(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if mfaRequired {
+		resp.WriteHeader(http.StatusOK)
+		resp.Write([]byte(`{"mfa_required": true, "mfa_token": "` + token + `"}`))
+		return
+	}
+
 	resp.WriteHeader(http.StatusOK)
 	resp.Write([]byte(`{"auth_token": "` + token + `"}`))
 }
 
-func sanitizePassword(user *models.User) {
-	user.Password = ""
+// writeUserResponse writes the view of the looked up user that the viewer is allowed to see, or the lookup error
+func writeUserResponse(resp http.ResponseWriter, req *http.Request, user models.User, err error) {
+	if err == usecase.ErrUserNotFound {
+		key, value := trimError(err)
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{` + key + `:` + value + `}`))
+		return
+	}
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	viewerID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	viewer := user
+
+	// a viewer that can't be loaded only gets the public profile
+	if viewerID != user.ID && userUsecase.GetUserByID(viewerID, &viewer) != nil {
+		viewer = models.User{ID: viewerID}
+	}
+
+	json.NewEncoder(resp).Encode(newUserResponse(user, viewer))
+}
+
+// clientIP returns the IP address of the remote end of the connection
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// getUserIDFromToken returns the user the middleware authorized, or else the user_id claim of the JWT in the Authorization header
+func getUserIDFromToken(req *http.Request) (uint, error) {
+	if userID, ok := middleware.UserID(req); ok {
+		return userID, nil
+	}
+
+	token, err := jwt.Parse(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	userID, ok := claims["user_id"].(float64)
+
+	if !ok {
+		return 0, fmt.Errorf("invalid token claims")
+	}
+
+	return uint(userID), nil
 }
 
 func trimError(err error) (string, string) {
@@ -182,27 +448,29 @@ func trimError(err error) (string, string) {
 	return key, value
 }
 
-func addDataToUser(user *models.User, data *http.Request) error {
-	var err error
+// writeError writes usecase errors in the same shape as trimError, errors without a quoted key go under "error"
+func writeError(resp http.ResponseWriter, status int, err error) {
+	resp.WriteHeader(status)
 
-	if data.FormValue("follower_count") != "" {
-		user.FollowerCount, err = strconv.Atoi(data.FormValue("follower_count"))
-		if err != nil {
-			return err
-		}
+	if !hasErrorKey(err) {
+		json.NewEncoder(resp).Encode(map[string]string{"error": err.Error()})
+		return
 	}
 
-	if data.FormValue("following_count") != "" {
-		user.FollowingCount, err = strconv.Atoi(data.FormValue("following_count"))
-		if err != nil {
-			return err
-		}
-	}
+	key, value := trimError(err)
+	resp.Write([]byte(`{` + key + `:` + value + `}`))
+}
 
-	if data.FormValue("password") != "" {
-		user.Password = data.FormValue("password")
-	}
+// hasErrorKey tells usecase errors like `error: invalid "users_email_key"` apart from errors of the database, the
+// mailer and other libraries, which trimError can't take apart
+func hasErrorKey(err error) bool {
+	return strings.Count(err.Error(), "\"") >= 2 && strings.Contains(err.Error(), ": ")
+}
 
+func addDataToUser(user *models.User, data *http.Request) error {
+	var err error
+
+	// follower and following counts are kept up to date by following, they can't be set
 	if data.FormValue("username") != "" {
 		user.Username = data.FormValue("username")
 	}
@@ -215,5 +483,37 @@ func addDataToUser(user *models.User, data *http.Request) error {
 		user.Email = data.FormValue("email")
 	}
 
+	// profile fields can be cleared, so a field sent empty is applied too
+	if value, ok := formValue(data, "bio"); ok {
+		user.Bio = value
+	}
+
+	if value, ok := formValue(data, "website"); ok {
+		user.Website = value
+	}
+
+	if value, ok := formValue(data, "location"); ok {
+		user.Location = value
+	}
+
+	if value, ok := formValue(data, "avatar_url"); ok {
+		user.AvatarURL = value
+	}
+
+	if value, ok := formValue(data, "is_private"); ok {
+		user.IsPrivate, err = strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// formValue returns the form value and whether the key was sent at all
+func formValue(data *http.Request, key string) (string, bool) {
+	value := data.FormValue(key)
+	_, ok := data.Form[key]
+
+	return value, ok
+}
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
+	"summer-web/media"
 	"summer-web/models"
+	"summer-web/usecase"
 	"testing"
 	"time"
 
@@ -29,16 +33,29 @@ func (mock *UserMockUsecase) GetUserByID(id uint, user *models.User) error {
 	return args.Error(0)
 }
 
+func (mock *UserMockUsecase) GetUserProfile(viewerID uint, id uint, user *models.User) error {
+	args := mock.Called()
+	user.ID = id
+	return args.Error(0)
+}
+
+func (mock *UserMockUsecase) GetUserByUsername(viewerID uint, username string, user *models.User) error {
+	args := mock.Called(username)
+	user.ID = 2
+	user.Username = username
+	return args.Error(0)
+}
+
 func (mock *UserMockUsecase) AddUser(user *models.User) error {
 	args := mock.Called()
 	return args.Error(0)
 }
 
-func (mock *UserMockUsecase) Login(loginData models.User) (string, error) {
+func (mock *UserMockUsecase) Login(loginData models.User, ip string, userAgent string) (string, bool, error) {
 	args := mock.Called()
 	result := args.Get(0)
 
-	return result.(string), args.Error(1)
+	return result.(string), args.Bool(1), args.Error(2)
 }
 
 func (mock *UserMockUsecase) UpdateUser(updatedData models.User) error {
@@ -46,6 +63,28 @@ func (mock *UserMockUsecase) UpdateUser(updatedData models.User) error {
 	return args.Error(0)
 }
 
+func (mock *UserMockUsecase) SetAvatar(id uint, image []byte, user *models.User) error {
+	args := mock.Called(id, string(image))
+	user.ID = id
+	user.AvatarKey = "avatars/1/abc.png"
+	return args.Error(0)
+}
+
+func (mock *UserMockUsecase) DeleteUser(id uint, password string) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *UserMockUsecase) PurgeDeletedUsers() error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *UserMockUsecase) ChangePassword(id uint, oldPassword string, newPassword string, ip string, userAgent string) (string, error) {
+	args := mock.Called(oldPassword, newPassword)
+	return args.String(0), args.Error(1)
+}
+
 func TestLoginSuccess(t *testing.T) {
 	buf := new(bytes.Buffer)
 
@@ -74,7 +113,7 @@ func TestLoginSuccess(t *testing.T) {
 	resp := httptest.NewRecorder()
 	mockUsecase := new(UserMockUsecase)
 
-	mockUsecase.On("Login").Return("valid token", nil)
+	mockUsecase.On("Login").Return("valid token", false, nil)
 
 	userDeliv := NewUserDelivery(mockUsecase)
 
@@ -122,7 +161,7 @@ func TestLoginFailed(t *testing.T) {
 	resp := httptest.NewRecorder()
 	mockUsecase := new(UserMockUsecase)
 
-	mockUsecase.On("Login").Return("", fmt.Errorf("please provide a correct credentials"))
+	mockUsecase.On("Login").Return("", false, fmt.Errorf("please provide a correct credentials"))
 
 	userDeliv := NewUserDelivery(mockUsecase)
 
@@ -140,6 +179,56 @@ func TestLoginFailed(t *testing.T) {
 	assert.Equal(t, "please provide a correct credentials", receivedResponse.Error)
 }
 
+func TestLoginLockedOut(t *testing.T) {
+	req, err := http.NewRequest("POST", "/login?username=us1&password=pw1", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("Login").Return("", false, &usecase.TooManyAttemptsError{RetryAfter: 90*time.Second + time.Millisecond})
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.Login(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "91", resp.Header().Get("Retry-After"))
+}
+
+func TestLoginRequiresMFA(t *testing.T) {
+	req, err := http.NewRequest("POST", "/login?username=us1&password=pw1", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("Login").Return("pending token", true, nil)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.Login(resp, req)
+
+	receivedResponse := struct {
+		AuthToken   string `json:"auth_token"`
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "", receivedResponse.AuthToken)
+	assert.True(t, receivedResponse.MFARequired)
+	assert.Equal(t, "pending token", receivedResponse.MFAToken)
+}
+
 func TestSignUp(t *testing.T) {
 	buf := new(bytes.Buffer)
 	w := multipart.NewWriter(buf)
@@ -224,7 +313,7 @@ func TestGetUserByID(t *testing.T) {
 	resp := httptest.NewRecorder()
 	mockUsecase := new(UserMockUsecase)
 
-	mockUsecase.On("GetUserByID").Return(nil)
+	mockUsecase.On("GetUserProfile").Return(nil)
 
 	userDeliv := NewUserDelivery(mockUsecase)
 
@@ -238,6 +327,170 @@ func TestGetUserByID(t *testing.T) {
 	assert.Equal(t, uint(searchID), receivedResponse.ID)
 }
 
+func TestGetOtherUserByID(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/2", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("GetUserProfile").Return(nil)
+	mockUsecase.On("GetUserByID").Return(nil)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.GetUserByID(resp, req)
+
+	receivedResponse := map[string]interface{}{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, float64(2), receivedResponse["id"])
+	assert.NotContains(t, receivedResponse, "email")
+	mockUsecase.AssertNumberOfCalls(t, "GetUserByID", 1)
+}
+
+func TestGetUserByIDNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/3", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("GetUserProfile").Return(usecase.ErrUserNotFound)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.GetUserByID(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestGetUserByUsername(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/by-username/Joko", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"username": "Joko"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("GetUserByUsername", "Joko").Return(nil)
+	mockUsecase.On("GetUserByID").Return(nil)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.GetUserByUsername(resp, req)
+
+	receivedResponse := map[string]interface{}{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "Joko", receivedResponse["username"])
+	assert.NotContains(t, receivedResponse, "email")
+}
+
+func TestGetUserByUsernameNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/by-username/nobody", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"username": "nobody"})
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("GetUserByUsername", "nobody").Return(usecase.ErrUserNotFound)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.GetUserByUsername(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users/me", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("GetUserByID").Return(nil)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.GetCurrentUser(resp, req)
+
+	receivedResponse := map[string]interface{}{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, float64(1), receivedResponse["id"])
+	assert.Contains(t, receivedResponse, "email")
+}
+
 func TestUpdateUser(t *testing.T) {
 	buf := new(bytes.Buffer)
 	w := multipart.NewWriter(buf)
@@ -281,6 +534,242 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, uint(1), receivedResponse.ID)
 }
 
+func TestDeleteUser(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	err := w.WriteField("password", "123")
+
+	if err != nil {
+		panic(err)
+	}
+
+	w.Close()
+
+	req, err := http.NewRequest("DELETE", "/users/me", buf)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+	mockUsecase.On("DeleteUser").Return(nil)
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.DeleteUser(resp, req)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestDeleteUserWrongPassword(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/users/me", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+	mockUsecase.On("DeleteUser").Return(usecase.ErrIncorrectPassword)
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.DeleteUser(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "incorrect", receivedResponse["users_password_key"])
+}
+
+func TestDeleteUserDatabaseError(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/users/me", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+	mockUsecase.On("DeleteUser").Return(fmt.Errorf("record not found"))
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.DeleteUser(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestChangePassword(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	err := w.WriteField("old_password", "123")
+	if err != nil {
+		panic(err)
+	}
+
+	err = w.WriteField("new_password", "newpassword1")
+	if err != nil {
+		panic(err)
+	}
+
+	w.Close()
+
+	req, err := http.NewRequest("POST", "/users/me/password", buf)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+	mockUsecase.On("ChangePassword", "123", "newpassword1").Return("new token", nil)
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.ChangePassword(resp, req)
+
+	receivedResponse := map[string]string{}
+
+	json.NewDecoder(resp.Body).Decode(&receivedResponse)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "new token", receivedResponse["auth_token"])
+}
+
+func TestChangePasswordErrors(t *testing.T) {
+	statuses := map[error]int{
+		usecase.ErrIncorrectPassword:     http.StatusBadRequest,
+		usecase.ErrWeakPassword:          http.StatusBadRequest,
+		fmt.Errorf("record not found"):   http.StatusInternalServerError,
+		fmt.Errorf("pq: deadlock found"): http.StatusInternalServerError,
+	}
+
+	for changeErr, status := range statuses {
+		req, err := http.NewRequest("POST", "/users/me/password", nil)
+
+		if err != nil {
+			panic(err)
+		}
+
+		token, err := generateToken()
+
+		if err != nil {
+			panic(err)
+		}
+
+		req.Header.Set("Authorization", token)
+
+		resp := httptest.NewRecorder()
+		mockUsecase := new(UserMockUsecase)
+		mockUsecase.On("ChangePassword", "", "").Return("", changeErr)
+		userDeliv := NewUserDelivery(mockUsecase)
+
+		userDeliv.ChangePassword(resp, req)
+
+		assert.Equal(t, status, resp.Code, changeErr.Error())
+	}
+}
+
+func TestUpdateUserIgnoresPassword(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/users/update?password=hijacked&name=joko", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	user := models.User{Password: "123"}
+
+	err = addDataToUser(&user, req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "123", user.Password)
+	assert.Equal(t, "joko", user.Name)
+}
+
+func TestUpdateUserIgnoresFollowCounts(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/users/update?follower_count=1000&following_count=5", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	user := models.User{FollowerCount: 3, FollowingCount: 4}
+
+	err = addDataToUser(&user, req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, user.FollowerCount)
+	assert.Equal(t, 4, user.FollowingCount)
+}
+
+func TestAddDataToUserProfile(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/users/update?bio=&location=Jakarta&is_private=true", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	user := models.User{Bio: "old bio", Website: "https://joko.dev"}
+
+	err = addDataToUser(&user, req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", user.Bio)
+	assert.Equal(t, "https://joko.dev", user.Website)
+	assert.Equal(t, "Jakarta", user.Location)
+	assert.True(t, user.IsPrivate)
+}
+
+func TestAddDataToUserInvalidPrivateFlag(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/users/update?is_private=maybe", nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = addDataToUser(&models.User{}, req)
+
+	assert.NotNil(t, err)
+}
+
 func generateToken() (string, error) {
 	atClaims := jwt.MapClaims{}
 	atClaims["authorized"] = true
@@ -293,3 +782,93 @@ func generateToken() (string, error) {
 	}
 	return token, err
 }
+
+func newAvatarRequest(body io.Reader, contentType string) *http.Request {
+	req, err := http.NewRequest("PUT", "/users/me/avatar", body)
+
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := generateToken()
+
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", contentType)
+
+	return req
+}
+
+func TestUploadAvatar(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	file, err := w.CreateFormFile("avatar", "me.png")
+
+	if err != nil {
+		panic(err)
+	}
+
+	file.Write([]byte("png"))
+	w.Close()
+
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("SetAvatar", uint(1), "png").Return(nil)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.UploadAvatar(resp, newAvatarRequest(buf, w.FormDataContentType()))
+
+	result := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, strings.HasPrefix(result["avatar_url"].(string), "/media/avatars/1/abc_large.png?expires="))
+	assert.True(t, strings.HasPrefix(result["avatar_urls"].(map[string]interface{})["medium"].(string), "/media/avatars/1/abc_medium.png?expires="))
+}
+
+func TestUploadAvatarAsBody(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("SetAvatar", uint(1), "png").Return(nil)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.UploadAvatar(resp, newAvatarRequest(strings.NewReader("png"), "image/png"))
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestUploadAvatarMissing(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.UploadAvatar(resp, newAvatarRequest(strings.NewReader(""), "image/png"))
+
+	mockUsecase.AssertNotCalled(t, "SetAvatar", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "users_avatar_key")
+}
+
+func TestUploadAvatarUnsupportedType(t *testing.T) {
+	resp := httptest.NewRecorder()
+	mockUsecase := new(UserMockUsecase)
+
+	mockUsecase.On("SetAvatar", uint(1), "%PDF").Return(media.ErrUnsupportedType)
+
+	userDeliv := NewUserDelivery(mockUsecase)
+
+	userDeliv.UploadAvatar(resp, newAvatarRequest(strings.NewReader("%PDF"), "application/pdf"))
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+}
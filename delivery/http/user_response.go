@@ -0,0 +1,107 @@
+package delivery
+
+import (
+	"summer-web/models"
+	"summer-web/usecase"
+	"time"
+)
+
+// PublicProfile is what any authenticated caller sees of another user
+type PublicProfile struct {
+	ID             uint              `json:"id"`
+	Username       string            `json:"username"`
+	Name           string            `json:"name"`
+	FollowerCount  int               `json:"follower_count"`
+	FollowingCount int               `json:"following_count"`
+	Bio            string            `json:"bio"`
+	Website        string            `json:"website"`
+	Location       string            `json:"location"`
+	AvatarURL      string            `json:"avatar_url"`
+	AvatarURLs     map[string]string `json:"avatar_urls"`
+	IsPrivate      bool              `json:"is_private"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// SelfProfile is what users see of their own account
+type SelfProfile struct {
+	PublicProfile
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// AdminUserView is what admins see of any account
+type AdminUserView struct {
+	SelfProfile
+	IsAdmin   bool       `json:"is_admin"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// SuggestionResponse is an account suggested to follow, with why it is suggested
+type SuggestionResponse struct {
+	PublicProfile
+	MutualCount int `json:"mutual_count"`
+	RecentPosts int `json:"recent_posts"`
+}
+
+func newPublicProfile(user models.User) PublicProfile {
+	return PublicProfile{
+		ID:             user.ID,
+		Username:       user.Username,
+		Name:           user.Name,
+		FollowerCount:  user.FollowerCount,
+		FollowingCount: user.FollowingCount,
+		Bio:            user.Bio,
+		Website:        user.Website,
+		Location:       user.Location,
+		AvatarURL:      usecase.AvatarURL(user),
+		AvatarURLs:     usecase.AvatarURLs(user),
+		IsPrivate:      user.IsPrivate,
+		CreatedAt:      user.CreatedAt,
+	}
+}
+
+func newSelfProfile(user models.User) SelfProfile {
+	return SelfProfile{
+		PublicProfile:   newPublicProfile(user),
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		TOTPEnabled:     user.TOTPEnabled,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+func newAdminUserView(user models.User) AdminUserView {
+	return AdminUserView{
+		SelfProfile: newSelfProfile(user),
+		IsAdmin:     user.IsAdmin,
+		DeletedAt:   user.DeletedAt,
+	}
+}
+
+// newUserResponse picks the view of user that the viewer is allowed to see
+func newUserResponse(user models.User, viewer models.User) interface{} {
+	switch {
+	case viewer.IsAdmin:
+		return newAdminUserView(user)
+	case viewer.ID == user.ID:
+		return newSelfProfile(user)
+	default:
+		return newPublicProfile(user)
+	}
+}
+
+func newSuggestionResponses(suggestions []models.Suggestion) []SuggestionResponse {
+	responses := make([]SuggestionResponse, len(suggestions))
+
+	for i, suggestion := range suggestions {
+		responses[i] = SuggestionResponse{
+			PublicProfile: newPublicProfile(*suggestion.Suggested),
+			MutualCount:   suggestion.MutualCount,
+			RecentPosts:   suggestion.RecentPosts,
+		}
+	}
+
+	return responses
+}
@@ -0,0 +1,73 @@
+package delivery
+
+import (
+	"encoding/json"
+	"strings"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeToMap(v interface{}) map[string]interface{} {
+	b, _ := json.Marshal(v)
+	result := map[string]interface{}{}
+	json.Unmarshal(b, &result)
+	return result
+}
+
+func TestUserResponseForOtherUser(t *testing.T) {
+	deletedAt := time.Now()
+	user := models.User{ID: 2, Username: "joko", Email: "joko@joko.com", Password: "123", DeletedAt: &deletedAt}
+
+	result := encodeToMap(newUserResponse(user, models.User{ID: 1}))
+
+	assert.Equal(t, "joko", result["username"])
+	assert.NotContains(t, result, "email")
+	assert.NotContains(t, result, "password")
+	assert.NotContains(t, result, "deleted_at")
+	assert.NotContains(t, result, "updated_at")
+}
+
+func TestUserResponseForSelf(t *testing.T) {
+	user := models.User{ID: 2, Username: "joko", Email: "joko@joko.com", Password: "123"}
+
+	result := encodeToMap(newUserResponse(user, user))
+
+	assert.Equal(t, "joko@joko.com", result["email"])
+	assert.Contains(t, result, "totp_enabled")
+	assert.NotContains(t, result, "password")
+	assert.NotContains(t, result, "is_admin")
+}
+
+func TestUserResponseForAdmin(t *testing.T) {
+	user := models.User{ID: 2, Username: "joko", Email: "joko@joko.com", Password: "123", TOTPSecret: "SECRET"}
+
+	result := encodeToMap(newUserResponse(user, models.User{ID: 1, IsAdmin: true}))
+
+	assert.Equal(t, "joko@joko.com", result["email"])
+	assert.Contains(t, result, "deleted_at")
+	assert.Contains(t, result, "is_admin")
+	assert.NotContains(t, result, "password")
+	assert.NotContains(t, result, "totp_secret")
+}
+
+func TestUserResponseWithUploadedAvatar(t *testing.T) {
+	user := models.User{ID: 2, Username: "joko", AvatarURL: "https://example.com/old.png", AvatarKey: "avatars/2/abc.png"}
+
+	result := encodeToMap(newUserResponse(user, models.User{ID: 1}))
+
+	assert.True(t, strings.HasPrefix(result["avatar_url"].(string), "/media/avatars/2/abc_large.png?expires="))
+	assert.True(t, strings.HasPrefix(result["avatar_urls"].(map[string]interface{})["small"].(string), "/media/avatars/2/abc_small.png?expires="))
+	assert.NotContains(t, result, "avatar_key")
+}
+
+func TestUserResponseWithAvatarURL(t *testing.T) {
+	user := models.User{ID: 2, Username: "joko", AvatarURL: "https://example.com/old.png"}
+
+	result := encodeToMap(newUserResponse(user, models.User{ID: 1}))
+
+	assert.Equal(t, "https://example.com/old.png", result["avatar_url"])
+	assert.Nil(t, result["avatar_urls"])
+}
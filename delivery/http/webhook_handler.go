@@ -0,0 +1,152 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"summer-web/models"
+	"summer-web/usecase"
+)
+
+// WebhookDelivery interface acts as Webhook Controller, only admins manage webhooks
+type WebhookDelivery interface {
+	AddWebhook(resp http.ResponseWriter, req *http.Request)
+	GetWebhooks(resp http.ResponseWriter, req *http.Request)
+	DeleteWebhook(resp http.ResponseWriter, req *http.Request)
+	GetDeliveries(resp http.ResponseWriter, req *http.Request)
+}
+
+type webhookDelivery struct{}
+
+var (
+	webhookUsecase usecase.WebhookUsecase
+)
+
+// NewWebhookDelivery returns new webhookDelivery struct that implements WebhookDelivery
+func NewWebhookDelivery(usecaseWebhook ...usecase.WebhookUsecase) WebhookDelivery {
+	if len(usecaseWebhook) > 0 {
+		webhookUsecase = usecaseWebhook[0]
+	} else {
+		webhookUsecase = usecase.NewWebhookUsecase()
+	}
+	return &webhookDelivery{}
+}
+
+// AddWebhook takes a url and comma separated events, the signing secret is only shown in this response
+func (*webhookDelivery) AddWebhook(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	webhook, err := webhookUsecase.AddWebhook(userID, req.FormValue("url"), strings.Split(req.FormValue("events"), ","))
+
+	switch err {
+	case nil:
+		resp.WriteHeader(http.StatusCreated)
+		json.NewEncoder(resp).Encode(struct {
+			models.Webhook
+			Secret string `json:"secret"`
+		}{webhook, webhook.Secret})
+	case usecase.ErrInvalidWebhookURL, usecase.ErrInvalidWebhookEvents:
+		writeError(resp, http.StatusBadRequest, err)
+	default:
+		writeWebhookError(resp, err)
+	}
+}
+
+func (*webhookDelivery) GetWebhooks(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, err := getUserIDFromToken(req)
+
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	webhooks, err := webhookUsecase.GetWebhooks(userID)
+
+	if err != nil {
+		writeWebhookError(resp, err)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(webhooks)
+}
+
+func (*webhookDelivery) DeleteWebhook(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, id, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := webhookUsecase.DeleteWebhook(userID, id); err != nil {
+		writeWebhookError(resp, err)
+		return
+	}
+
+	resp.Write([]byte(`{"message": "webhook deleted"}`))
+}
+
+// GetDeliveries lists the delivery log of the webhook in the path, newest first, with "before" and "limit" paging
+func (*webhookDelivery) GetDeliveries(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "application/json")
+
+	userID, id, err := getUserIDAndPathID(req)
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	beforeID, err := queryInt(req, "before")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	limit, err := queryInt(req, "limit")
+
+	if err != nil {
+		writeError(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	deliveries, err := webhookUsecase.GetDeliveries(userID, id, uint(beforeID), limit)
+
+	if err != nil {
+		writeWebhookError(resp, err)
+		return
+	}
+
+	pageSize := usecase.PageSize(limit)
+
+	if len(deliveries) == pageSize {
+		next := req.URL.Path + "?before=" + strconv.Itoa(int(deliveries[len(deliveries)-1].ID)) + "&limit=" + strconv.Itoa(pageSize)
+		resp.Header().Set("Link", "<"+next+">; rel=\"next\"")
+	}
+
+	json.NewEncoder(resp).Encode(deliveries)
+}
+
+func writeWebhookError(resp http.ResponseWriter, err error) {
+	switch err {
+	case usecase.ErrNotAdmin:
+		writeError(resp, http.StatusForbidden, err)
+	case usecase.ErrWebhookNotFound:
+		writeError(resp, http.StatusNotFound, err)
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+	}
+}
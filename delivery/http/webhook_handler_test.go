@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"summer-web/models"
+	"summer-web/usecase"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *WebhookMockUsecase) AddWebhook(userID uint, rawURL string, events []string) (models.Webhook, error) {
+	args := mock.Called(userID, rawURL, events)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (mock *WebhookMockUsecase) GetWebhooks(userID uint) ([]models.Webhook, error) {
+	args := mock.Called(userID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (mock *WebhookMockUsecase) DeleteWebhook(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func (mock *WebhookMockUsecase) GetDeliveries(userID uint, webhookID uint, beforeID uint, limit int) ([]models.WebhookDelivery, error) {
+	args := mock.Called(userID, webhookID, beforeID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (mock *WebhookMockUsecase) DeliverWebhooks() error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func TestAddWebhook(t *testing.T) {
+	req := newFollowRequest("POST", "/webhooks?url=https://partner.example/hooks&events=post.created,follow.created", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(WebhookMockUsecase)
+
+	webhook := models.Webhook{ID: 3, UserID: 1, URL: "https://partner.example/hooks", Events: "post.created,follow.created", Secret: "s3cret"}
+
+	mockUsecase.On("AddWebhook", uint(1), "https://partner.example/hooks", []string{"post.created", "follow.created"}).Return(webhook, nil)
+
+	var body map[string]interface{}
+
+	webhookDeliv := NewWebhookDelivery(mockUsecase)
+	webhookDeliv.AddWebhook(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "s3cret", body["secret"])
+	assert.Equal(t, "post.created,follow.created", body["events"])
+}
+
+func TestAddWebhookNotAdmin(t *testing.T) {
+	req := newFollowRequest("POST", "/webhooks?url=https://partner.example/hooks&events=post.created", "")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(WebhookMockUsecase)
+
+	mockUsecase.On("AddWebhook", uint(1), "https://partner.example/hooks", []string{"post.created"}).Return(models.Webhook{}, usecase.ErrNotAdmin)
+
+	webhookDeliv := NewWebhookDelivery(mockUsecase)
+	webhookDeliv.AddWebhook(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestDeleteWebhookNotFound(t *testing.T) {
+	req := newFollowRequest("DELETE", "/webhooks/3", "3")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(WebhookMockUsecase)
+
+	mockUsecase.On("DeleteWebhook", uint(1), uint(3)).Return(usecase.ErrWebhookNotFound)
+
+	webhookDeliv := NewWebhookDelivery(mockUsecase)
+	webhookDeliv.DeleteWebhook(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	req := newFollowRequest("GET", "/webhooks/3/deliveries?before=9&limit=2", "3")
+	resp := httptest.NewRecorder()
+	mockUsecase := new(WebhookMockUsecase)
+
+	deliveries := []models.WebhookDelivery{{ID: 8, WebhookID: 3, Status: models.WebhookDeliveryDead}, {ID: 7, WebhookID: 3, Status: models.WebhookDeliveryDelivered}}
+
+	mockUsecase.On("GetDeliveries", uint(1), uint(3), uint(9), 2).Return(deliveries, nil)
+
+	var body []models.WebhookDelivery
+
+	webhookDeliv := NewWebhookDelivery(mockUsecase)
+	webhookDeliv.GetDeliveries(resp, req)
+
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	mockUsecase.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, models.WebhookDeliveryDead, body[0].Status)
+	assert.Equal(t, `</webhooks/3/deliveries?before=7&limit=2>; rel="next"`, resp.Header().Get("Link"))
+}
@@ -1,30 +1,70 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"summer-web/usecase"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
 // Middleware interfaces for authorizing, etc (if there is any)
 type Middleware interface {
-	IsAuthorized(endpoint func(resp http.ResponseWriter, req *http.Request)) http.Handler
+	IsAuthorized(endpoint func(resp http.ResponseWriter, req *http.Request), scopes ...string) http.Handler
 }
 
 type middleware struct{}
 
+type contextKey string
+
+const (
+	userIDKey    contextKey = "user_id"
+	sessionIDKey contextKey = "session_id"
+)
+
+var (
+	sessionUsecase     usecase.SessionUsecase
+	accessTokenUsecase usecase.AccessTokenUsecase
+)
+
 // NewMiddleware returns middleware struct that implements Middleware interface
-func NewMiddleware() Middleware {
+func NewMiddleware(usecaseSession ...usecase.SessionUsecase) Middleware {
+	if len(usecaseSession) > 0 {
+		sessionUsecase = usecaseSession[0]
+	} else {
+		sessionUsecase = usecase.NewSessionUsecase()
+	}
+	if accessTokenUsecase == nil {
+		accessTokenUsecase = usecase.NewAccessTokenUsecase()
+	}
 	return &middleware{}
 }
 
-func (*middleware) IsAuthorized(endpoint func(resp http.ResponseWriter, req *http.Request)) http.Handler {
+// IsAuthorized lets the request through with a valid session JWT, or with a personal access token that was granted every
+// one of the scopes. Endpoints that don't list any scopes only accept session JWTs
+func (*middleware) IsAuthorized(endpoint func(resp http.ResponseWriter, req *http.Request), scopes ...string) http.Handler {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		resp.Header().Set("Content-Type", "application/json")
 		if req.Header["Authorization"] != nil {
-			token, err := jwt.Parse(req.Header["Authorization"][0], func(token *jwt.Token) (interface{}, error) {
+			rawToken := strings.TrimPrefix(req.Header["Authorization"][0], "Bearer ")
+
+			if strings.HasPrefix(rawToken, usecase.AccessTokenPrefix) {
+				userID, granted, err := accessTokenUsecase.Authenticate(rawToken)
+
+				if err != nil || len(scopes) == 0 || !hasScopes(granted, scopes) {
+					resp.WriteHeader(http.StatusUnauthorized)
+					resp.Write([]byte(`{"error": "Not authorized"}`))
+					return
+				}
+
+				endpoint(resp, req.WithContext(context.WithValue(req.Context(), userIDKey, userID)))
+				return
+			}
+
+			token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
 				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, fmt.Errorf("Unexpected signing method")
 				}
@@ -34,10 +74,21 @@ func (*middleware) IsAuthorized(endpoint func(resp http.ResponseWriter, req *htt
 			if err != nil {
 				resp.WriteHeader(http.StatusInternalServerError)
 				resp.Write([]byte(`{"error": "` + err.Error() + `"}`))
+				return
 			}
 
-			if token.Valid {
-				endpoint(resp, req)
+			claims := token.Claims.(jwt.MapClaims)
+			userID, hasUserID := claims["user_id"].(float64)
+			sessionID, hasSessionID := claims["session_id"].(float64)
+
+			// tokens of revoked sessions stay signed and unexpired, the session record has the last word
+			if token.Valid && hasUserID && hasSessionID && sessionUsecase.ValidateSession(uint(userID), uint(sessionID)) == nil {
+				ctx := context.WithValue(req.Context(), userIDKey, uint(userID))
+				ctx = context.WithValue(ctx, sessionIDKey, uint(sessionID))
+				endpoint(resp, req.WithContext(ctx))
+			} else {
+				resp.WriteHeader(http.StatusUnauthorized)
+				resp.Write([]byte(`{"error": "Not authorized"}`))
 			}
 		} else {
 			resp.WriteHeader(http.StatusUnauthorized)
@@ -45,3 +96,53 @@ func (*middleware) IsAuthorized(endpoint func(resp http.ResponseWriter, req *htt
 		}
 	})
 }
+
+// UserID returns the id of the user IsAuthorized let the request through for
+func UserID(req *http.Request) (uint, bool) {
+	userID, ok := req.Context().Value(userIDKey).(uint)
+	return userID, ok
+}
+
+// SessionID returns the session of the auth token IsAuthorized let the request through for, personal access tokens have none
+func SessionID(req *http.Request) (uint, bool) {
+	sessionID, ok := req.Context().Value(sessionIDKey).(uint)
+	return sessionID, ok
+}
+
+// Revalidate checks again that the session or personal access token IsAuthorized let the request through with has not
+// been revoked or expired since, for requests such as streams that stay open long after they were authorized
+func Revalidate(req *http.Request) error {
+	userID, ok := UserID(req)
+
+	if !ok {
+		return fmt.Errorf("error: not authorized \"token\"")
+	}
+
+	if sessionID, ok := SessionID(req); ok {
+		return sessionUsecase.ValidateSession(userID, sessionID)
+	}
+
+	tokenUserID, _, err := accessTokenUsecase.Authenticate(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+
+	if err == nil && tokenUserID != userID {
+		return fmt.Errorf("error: invalid \"token\"")
+	}
+
+	return err
+}
+
+func hasScopes(granted []string, required []string) bool {
+	for _, scope := range required {
+		found := false
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
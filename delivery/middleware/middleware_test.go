@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *SessionMockUsecase) GetSessions(userID uint) ([]models.Session, error) {
+	return nil, nil
+}
+
+func (mock *SessionMockUsecase) RevokeSession(userID uint, id uint) error {
+	return nil
+}
+
+func (mock *SessionMockUsecase) ValidateSession(userID uint, id uint) error {
+	args := mock.Called(userID, id)
+	return args.Error(0)
+}
+
+func (mock *SessionMockUsecase) RefreshToken(rawToken string) (string, error) {
+	return "", nil
+}
+
+func (mock *SessionMockUsecase) PurgeExpiredSessions() error {
+	return nil
+}
+
+type AccessTokenMockUsecase struct {
+	mock.Mock
+}
+
+func (mock *AccessTokenMockUsecase) CreateAccessToken(userID uint, name string, scopes []string, expiresIn time.Duration) (string, models.AccessToken, error) {
+	return "", models.AccessToken{}, nil
+}
+
+func (mock *AccessTokenMockUsecase) GetAccessTokens(userID uint) ([]models.AccessToken, error) {
+	return nil, nil
+}
+
+func (mock *AccessTokenMockUsecase) RevokeAccessToken(userID uint, id uint) error {
+	return nil
+}
+
+func (mock *AccessTokenMockUsecase) Authenticate(rawToken string) (uint, []string, error) {
+	args := mock.Called(rawToken)
+	return uint(args.Int(0)), args.Get(1).([]string), args.Error(2)
+}
+
+func setup() (*SessionMockUsecase, *AccessTokenMockUsecase, Middleware) {
+	mockSessionUsecase := new(SessionMockUsecase)
+	mockAccessTokenUsecase := new(AccessTokenMockUsecase)
+
+	accessTokenUsecase = mockAccessTokenUsecase
+
+	return mockSessionUsecase, mockAccessTokenUsecase, NewMiddleware(mockSessionUsecase)
+}
+
+func generateToken(claims jwt.MapClaims) string {
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("SECRET_JWT_KEY")))
+	return token
+}
+
+func serve(m Middleware, authorization string, scopes ...string) (*httptest.ResponseRecorder, uint) {
+	var seenUserID uint
+
+	handler := m.IsAuthorized(func(resp http.ResponseWriter, req *http.Request) {
+		seenUserID, _ = UserID(req)
+	}, scopes...)
+
+	req, _ := http.NewRequest("GET", "/browse", nil)
+	req.Header.Set("Authorization", authorization)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	return resp, seenUserID
+}
+
+func TestIsAuthorizedWithJWT(t *testing.T) {
+	mockSessionUsecase, _, m := setup()
+
+	mockSessionUsecase.On("ValidateSession", uint(4), uint(9)).Return(nil)
+
+	resp, userID := serve(m, generateToken(jwt.MapClaims{"user_id": 4, "session_id": 9}), "posts:read")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, uint(4), userID)
+}
+
+func TestIsAuthorizedRevokedSession(t *testing.T) {
+	mockSessionUsecase, _, m := setup()
+
+	mockSessionUsecase.On("ValidateSession", uint(4), uint(9)).Return(fmt.Errorf("error: revoked \"session\""))
+
+	resp, _ := serve(m, generateToken(jwt.MapClaims{"user_id": 4, "session_id": 9}))
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestIsAuthorizedJWTWithoutSession(t *testing.T) {
+	_, _, m := setup()
+
+	resp, _ := serve(m, generateToken(jwt.MapClaims{"user_id": 4}))
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestIsAuthorizedWithAccessToken(t *testing.T) {
+	_, mockAccessTokenUsecase, m := setup()
+
+	mockAccessTokenUsecase.On("Authenticate", "swp_abc").Return(7, []string{"posts:read", "posts:write"}, nil)
+
+	resp, userID := serve(m, "Bearer swp_abc", "posts:write")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, uint(7), userID)
+}
+
+func TestIsAuthorizedWithAccessTokenMissingScope(t *testing.T) {
+	_, mockAccessTokenUsecase, m := setup()
+
+	mockAccessTokenUsecase.On("Authenticate", "swp_abc").Return(7, []string{"posts:read"}, nil)
+
+	resp, _ := serve(m, "swp_abc", "posts:write")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestIsAuthorizedAccessTokenOnSessionOnlyEndpoint(t *testing.T) {
+	_, mockAccessTokenUsecase, m := setup()
+
+	mockAccessTokenUsecase.On("Authenticate", "swp_abc").Return(7, []string{"posts:read"}, nil)
+
+	resp, _ := serve(m, "swp_abc")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestIsAuthorizedInvalidAccessToken(t *testing.T) {
+	_, mockAccessTokenUsecase, m := setup()
+
+	mockAccessTokenUsecase.On("Authenticate", "swp_abc").Return(0, []string{}, fmt.Errorf("error: expired \"token\""))
+
+	resp, _ := serve(m, "swp_abc", "posts:read")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func revalidate(m Middleware, authorization string, scopes ...string) error {
+	var err error
+
+	handler := m.IsAuthorized(func(resp http.ResponseWriter, req *http.Request) {
+		err = Revalidate(req)
+	}, scopes...)
+
+	req, _ := http.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Authorization", authorization)
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	return err
+}
+
+func TestRevalidateRevokedSession(t *testing.T) {
+	mockSessionUsecase, _, m := setup()
+
+	mockSessionUsecase.On("ValidateSession", uint(4), uint(9)).Return(nil).Once()
+	mockSessionUsecase.On("ValidateSession", uint(4), uint(9)).Return(fmt.Errorf("error: revoked \"session\""))
+
+	err := revalidate(m, generateToken(jwt.MapClaims{"user_id": 4, "session_id": 9}))
+
+	assert.EqualError(t, err, "error: revoked \"session\"")
+}
+
+func TestRevalidateRevokedAccessToken(t *testing.T) {
+	_, mockAccessTokenUsecase, m := setup()
+
+	mockAccessTokenUsecase.On("Authenticate", "swp_abc").Return(7, []string{"posts:read"}, nil).Once()
+	mockAccessTokenUsecase.On("Authenticate", "swp_abc").Return(0, []string{}, fmt.Errorf("error: revoked \"token\""))
+
+	err := revalidate(m, "Bearer swp_abc", "posts:read")
+
+	assert.EqualError(t, err, "error: revoked \"token\"")
+}
+
+func TestRevalidateUnauthorizedRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/stream", nil)
+
+	assert.NotNil(t, Revalidate(req))
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"log"
+	"time"
+)
+
+// Worker interface for jobs that run periodically in the background
+type Worker interface {
+	Run(stop <-chan struct{})
+}
+
+type worker struct {
+	name     string
+	interval time.Duration
+	job      func() error
+}
+
+// NewWorker returns worker struct that runs job every interval until stopped
+func NewWorker(name string, interval time.Duration, job func() error) Worker {
+	return &worker{name: name, interval: interval, job: job}
+}
+
+// Run blocks and runs the job on every tick, a nil stop channel keeps it running forever
+func (w *worker) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := w.job(); err != nil {
+				log.Println(w.name, "failed:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUntilStopped(t *testing.T) {
+	runs := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	testWorker := NewWorker("test", time.Millisecond, func() error {
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+		return fmt.Errorf("failures are logged, not fatal")
+	})
+
+	go func() {
+		testWorker.Run(stop)
+		close(done)
+	}()
+
+	<-runs
+	<-runs
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "worker did not stop")
+	}
+}
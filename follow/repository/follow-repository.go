@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// FollowRepository is the repository interface for follow
+type FollowRepository interface {
+	AddFollow(follow *models.Follow) error
+	GetFollow(followerID uint, followeeID uint, follow *models.Follow) error
+	GetFollowByID(id uint, follow *models.Follow) error
+	GetFollowRequests(followeeID uint) ([]models.Follow, error)
+	SetFollowStatus(id uint, from string, to string) (bool, error)
+	DeleteFollow(id uint) (bool, error)
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Follow{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewFollowRepository create a new follow repository to fiddle around with database
+func NewFollowRepository(db *gorm.DB) FollowRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddFollow returns an error if there is any, otherwise creates a new follow record into database
+func (r *repo) AddFollow(follow *models.Follow) error {
+	return r.db.Create(follow).Error
+}
+
+// GetFollow returns an error if the follower never tried to follow the user, otherwise modifies the follow parameter
+func (r *repo) GetFollow(followerID uint, followeeID uint, follow *models.Follow) error {
+	return r.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).First(follow).Error
+}
+
+// GetFollowByID returns an error if there is any, otherwise modifies the follow parameter with the found record
+func (r *repo) GetFollowByID(id uint, follow *models.Follow) error {
+	return r.db.Where("id = ?", id).First(follow).Error
+}
+
+// GetFollowRequests returns the pending requests to follow the user, oldest first
+func (r *repo) GetFollowRequests(followeeID uint) ([]models.Follow, error) {
+	var follows []models.Follow
+
+	err := r.db.Where("followee_id = ? AND status = ?", followeeID, models.FollowPending).Order("created_at").Find(&follows).Error
+
+	return follows, err
+}
+
+// SetFollowStatus accepts or rejects a follow that still has the status from, it returns false when the follow was
+// changed in the meantime, like by an approval running at the same time
+func (r *repo) SetFollowStatus(id uint, from string, to string) (bool, error) {
+	result := r.db.Model(&models.Follow{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteFollow removes the follow, used when unfollowing or cancelling a request. It returns false when the follow was
+// already removed, like by an unfollow running at the same time
+func (r *repo) DeleteFollow(id uint) (bool, error) {
+	result := r.db.Where("id = ?", id).Delete(&models.Follow{})
+
+	return result.RowsAffected == 1, result.Error
+}
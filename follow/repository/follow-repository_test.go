@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	followRepo FollowRepository
+	mock       sqlmock.Sqlmock
+	db         *sql.DB
+	gdb        *gorm.DB
+	err        error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	followRepo = NewFollowRepository(gdb)
+}
+
+func TestAddFollow(t *testing.T) {
+	setup()
+
+	follow := models.Follow{FollowerID: 1, FolloweeID: 2, Status: models.FollowPending}
+	const sqlInsert = `INSERT INTO "follows" ("follower_id","followee_id","status","created_at","updated_at") VALUES ($1,$2,$3,$4,$5) RETURNING "follows"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, 2, models.FollowPending, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	err := followRepo.AddFollow(&follow)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(3), follow.ID)
+}
+
+func TestGetFollow(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "follows" WHERE (follower_id = $1 AND followee_id = $2) ORDER BY "follows"."id" ASC LIMIT 1`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id", "follower_id", "followee_id", "status"}).AddRow(3, 1, 2, models.FollowAccepted))
+
+	var follow models.Follow
+
+	err := followRepo.GetFollow(1, 2, &follow)
+
+	assert.Nil(t, err)
+	assert.Equal(t, models.FollowAccepted, follow.Status)
+}
+
+func TestGetFollowRequests(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "follows" WHERE (followee_id = $1 AND status = $2) ORDER BY created_at`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(2, models.FollowPending).WillReturnRows(sqlmock.NewRows([]string{"id", "follower_id", "followee_id", "status"}).AddRow(3, 1, 2, models.FollowPending))
+
+	follows, err := followRepo.GetFollowRequests(2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(follows))
+	assert.Equal(t, uint(1), follows[0].FollowerID)
+}
+
+func TestSetFollowStatus(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "follows" SET "status" = $1, "updated_at" = $2 WHERE (id = $3 AND status = $4)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(models.FollowAccepted, sqlmock.AnyArg(), 3, models.FollowPending).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	updated, err := followRepo.SetFollowStatus(3, models.FollowPending, models.FollowAccepted)
+
+	assert.Nil(t, err)
+	assert.True(t, updated)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSetFollowStatusChangedMeanwhile(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "follows" SET "status" = $1, "updated_at" = $2 WHERE (id = $3 AND status = $4)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(models.FollowAccepted, sqlmock.AnyArg(), 3, models.FollowPending).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	updated, err := followRepo.SetFollowStatus(3, models.FollowPending, models.FollowAccepted)
+
+	assert.Nil(t, err)
+	assert.False(t, updated)
+}
+
+func TestDeleteFollow(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "follows"  WHERE (id = $1)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := followRepo.DeleteFollow(3)
+
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteFollowDeletedMeanwhile(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "follows"  WHERE (id = $1)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	deleted, err := followRepo.DeleteFollow(3)
+
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+}
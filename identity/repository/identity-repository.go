@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// IdentityRepository is the repository interface for identity
+type IdentityRepository interface {
+	AddIdentity(identity *models.Identity) error
+	GetIdentity(provider string, subject string, identity *models.Identity) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Identity{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository create a new identity repository to fiddle around with database
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddIdentity links an external identity to a user
+func (r *repo) AddIdentity(identity *models.Identity) error {
+	return r.db.Create(identity).Error
+}
+
+// GetIdentity finds the identity the provider knows by subject
+func (r *repo) GetIdentity(provider string, subject string, identity *models.Identity) error {
+	return r.db.Where("provider = ? AND subject = ?", provider, subject).First(identity).Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	identityRepo IdentityRepository
+	mock         sqlmock.Sqlmock
+	db           *sql.DB
+	gdb          *gorm.DB
+	err          error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	identityRepo = NewIdentityRepository(gdb)
+}
+
+func TestAddIdentity(t *testing.T) {
+	setup()
+
+	identity := models.Identity{UserID: 1, Provider: "google", Subject: "sub", Email: "joko@joko.com"}
+	const sqlInsert = `INSERT INTO "identities" ("user_id","provider","subject","email","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "identities"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, "google", "sub", "joko@joko.com", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := identityRepo.AddIdentity(&identity)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(1), identity.ID)
+}
+
+func TestGetIdentity(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "identities" WHERE (provider = $1 AND subject = $2) ORDER BY "identities"."id" ASC LIMIT 1`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("google", "sub").WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "provider", "subject"}).AddRow(1, 7, "google", "sub"))
+
+	var identity models.Identity
+	err := identityRepo.GetIdentity("google", "sub", &identity)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(7), identity.UserID)
+}
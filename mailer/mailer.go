@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mailer interface sends plain text emails to users
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// NewMailer returns an SMTP mailer when SMTP_HOST is set, otherwise a mailer that writes to MAIL_LOG_PATH or the log
+func NewMailer() Mailer {
+	if os.Getenv("SMTP_HOST") != "" {
+		return NewSMTPMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("MAIL_FROM"))
+	}
+	return NewLogMailer(os.Getenv("MAIL_LOG_PATH"))
+}
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a mailer that delivers through an SMTP server, auth is skipped when username is empty
+func NewSMTPMailer(host string, port string, username string, password string, from string) Mailer {
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpMailer{addr: host + ":" + port, auth: auth, from: from}
+}
+
+// Send delivers the email through the configured SMTP server
+func (m *smtpMailer) Send(to string, subject string, body string) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(formatMessage(m.from, to, subject, body)))
+}
+
+type logMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer creates a mailer that appends every email to the file at path, or to the log when path is empty
+func NewLogMailer(path string) Mailer {
+	return &logMailer{path: path}
+}
+
+// Send writes the email instead of delivering it, useful for development and tests
+func (m *logMailer) Send(to string, subject string, body string) error {
+	message := formatMessage("summer-web", to, subject, body)
+
+	if m.path == "" {
+		log.Println("mail:", message)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.WriteString(message + "\r\n")
+
+	return err
+}
+
+func formatMessage(from string, to string, subject string, body string) string {
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+
+	return fmt.Sprintf("%s\r\n\r\n%s\r\n", strings.Join(headers, "\r\n"), body)
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailerWritesToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailer")
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mail.log")
+	testMailer := NewLogMailer(path)
+
+	err = testMailer.Send("joko@joko.com", "Hello", "first body")
+	assert.Nil(t, err)
+
+	err = testMailer.Send("joko@joko.com", "Hello again", "second body")
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(path)
+
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(content), "To: joko@joko.com"))
+	assert.True(t, strings.Contains(string(content), "Subject: Hello again"))
+	assert.True(t, strings.Contains(string(content), "first body"))
+	assert.True(t, strings.Contains(string(content), "second body"))
+}
+
+func TestNewMailerDefaultsToLogMailer(t *testing.T) {
+	os.Unsetenv("SMTP_HOST")
+
+	_, ok := NewMailer().(*logMailer)
+
+	assert.True(t, ok)
+}
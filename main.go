@@ -4,15 +4,24 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	delivery "summer-web/delivery/http"
 	"summer-web/delivery/middleware"
+	"summer-web/delivery/worker"
+	"summer-web/usecase"
 
 	"github.com/gorilla/mux"
 )
 
 // 	set SECRET_JWT_KEY=super_secret_key
 // 	set DB_CONNECTION_STRING=host=localhost port=5432 user=postgres dbname=summer_web_development password=password sslmode=disable
+// 	set APP_BASE_URL=http://localhost:8000
+// 	set MAIL_LOG_PATH=mail.log (or SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM to send real emails)
+// 	set BLOB_DIR=uploads (or BLOB_STORE=s3 with S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY)
+// 	set MEDIA_URL_SECRET=another_super_secret_key (media URLs are signed with SECRET_JWT_KEY if unset)
+// 	set SUGGESTIONS_PRECOMPUTE=true (suggestions to follow are computed hourly in the background instead of on request)
+// 	set OIDC_PROVIDERS=[{"name": "google", "issuer": "https://accounts.google.com", "client_id": "...", "client_secret": "...", "redirect_url": "http://localhost:8000/auth/google/callback"}]
 
 func main() {
 	// initializeEnv()
@@ -21,6 +30,22 @@ func main() {
 	var httpMiddleware middleware.Middleware = middleware.NewMiddleware()
 	var postDelivery delivery.PostDelivery = delivery.NewPostDelivery()
 	var userDelivery delivery.UserDelivery = delivery.NewUserDelivery()
+	var emailDelivery delivery.EmailDelivery = delivery.NewEmailDelivery()
+	var passwordDelivery delivery.PasswordDelivery = delivery.NewPasswordDelivery()
+	var mfaDelivery delivery.MFADelivery = delivery.NewMFADelivery()
+	var identityDelivery delivery.IdentityDelivery = delivery.NewIdentityDelivery()
+	var accessTokenDelivery delivery.AccessTokenDelivery = delivery.NewAccessTokenDelivery()
+	var sessionDelivery delivery.SessionDelivery = delivery.NewSessionDelivery()
+	var followDelivery delivery.FollowDelivery = delivery.NewFollowDelivery()
+	var blockDelivery delivery.BlockDelivery = delivery.NewBlockDelivery()
+	var muteDelivery delivery.MuteDelivery = delivery.NewMuteDelivery()
+	var mediaDelivery delivery.MediaDelivery = delivery.NewMediaDelivery()
+	var searchDelivery delivery.SearchDelivery = delivery.NewSearchDelivery()
+	var trendingDelivery delivery.TrendingDelivery = delivery.NewTrendingDelivery()
+	var suggestionDelivery delivery.SuggestionDelivery = delivery.NewSuggestionDelivery()
+	var notificationDelivery delivery.NotificationDelivery = delivery.NewNotificationDelivery()
+	var streamDelivery delivery.StreamDelivery = delivery.NewStreamDelivery()
+	var webhookDelivery delivery.WebhookDelivery = delivery.NewWebhookDelivery()
 
 	const port string = ":8000"
 
@@ -30,12 +55,87 @@ func main() {
 
 	router.HandleFunc("/sign_up", userDelivery.AddUser).Methods("POST")
 	router.HandleFunc("/login", userDelivery.Login).Methods("POST")
+	router.HandleFunc("/login/2fa", mfaDelivery.VerifyLogin).Methods("POST")
+	// auth tokens are refreshed after they expired, so this one goes without IsAuthorized
+	router.HandleFunc("/sessions/refresh", sessionDelivery.RefreshToken).Methods("POST")
+	router.HandleFunc("/verify_email", emailDelivery.VerifyEmail).Methods("GET")
+	router.Handle("/verify_email/resend", httpMiddleware.IsAuthorized(emailDelivery.ResendVerificationEmail)).Methods("POST")
+	router.HandleFunc("/password/forgot", passwordDelivery.ForgotPassword).Methods("POST")
+	router.HandleFunc("/password/reset", passwordDelivery.ResetPassword).Methods("POST")
+	router.HandleFunc("/auth/{provider}/login", identityDelivery.Login).Methods("GET")
+	router.Handle("/auth/{provider}/link", httpMiddleware.IsAuthorized(identityDelivery.Link)).Methods("GET")
+	router.HandleFunc("/auth/{provider}/callback", identityDelivery.Callback).Methods("GET")
 
-	router.Handle("/browse", httpMiddleware.IsAuthorized(postDelivery.GetPosts)).Methods("GET")
-	router.Handle("/posts", httpMiddleware.IsAuthorized(postDelivery.AddPost)).Methods("POST")
+	// media URLs are signed for the viewers allowed to see the media, so they work without a token, e.g. in img tags
+	router.HandleFunc("/media/{key:.+}", mediaDelivery.GetMedia).Methods("GET", "HEAD")
 
-	router.Handle("/users/{id}", httpMiddleware.IsAuthorized(userDelivery.GetUserByID)).Methods("GET")
-	router.Handle("/users/update", httpMiddleware.IsAuthorized(userDelivery.UpdateUser)).Methods("PATCH")
+	// personal access tokens are only accepted by the endpoints that declare the scopes they need
+	router.Handle("/browse", httpMiddleware.IsAuthorized(postDelivery.GetPosts, "posts:read")).Methods("GET")
+	router.Handle("/posts", httpMiddleware.IsAuthorized(postDelivery.AddPost, "posts:write")).Methods("POST")
+	router.Handle("/feed", httpMiddleware.IsAuthorized(postDelivery.GetFeed, "posts:read")).Methods("GET")
+	router.Handle("/posts/{id}", httpMiddleware.IsAuthorized(postDelivery.UpdatePost, "posts:write")).Methods("PATCH")
+	router.Handle("/tags/{tag}/posts", httpMiddleware.IsAuthorized(postDelivery.GetTagPosts, "posts:read")).Methods("GET")
+	router.Handle("/search", httpMiddleware.IsAuthorized(searchDelivery.Search, "users:read")).Methods("GET").Queries("type", "users")
+	router.Handle("/search", httpMiddleware.IsAuthorized(searchDelivery.Search, "posts:read")).Methods("GET")
+	router.Handle("/trending", httpMiddleware.IsAuthorized(trendingDelivery.GetTrending, "posts:read")).Methods("GET")
+
+	router.Handle("/users/me", httpMiddleware.IsAuthorized(userDelivery.GetCurrentUser, "users:read")).Methods("GET")
+	router.Handle("/users/me", httpMiddleware.IsAuthorized(userDelivery.DeleteUser)).Methods("DELETE")
+	router.Handle("/users/me/avatar", httpMiddleware.IsAuthorized(userDelivery.UploadAvatar, "users:write")).Methods("PUT")
+	router.Handle("/users/me/password", httpMiddleware.IsAuthorized(userDelivery.ChangePassword)).Methods("POST")
+	router.Handle("/users/me/2fa/enroll", httpMiddleware.IsAuthorized(mfaDelivery.EnrollTOTP)).Methods("POST")
+	router.Handle("/users/me/2fa/confirm", httpMiddleware.IsAuthorized(mfaDelivery.ConfirmTOTP)).Methods("POST")
+	router.Handle("/users/me/sessions", httpMiddleware.IsAuthorized(sessionDelivery.GetSessions)).Methods("GET")
+	router.Handle("/users/me/sessions/{id}", httpMiddleware.IsAuthorized(sessionDelivery.RevokeSession)).Methods("DELETE")
+	router.Handle("/users/me/tokens", httpMiddleware.IsAuthorized(accessTokenDelivery.GetAccessTokens)).Methods("GET")
+	router.Handle("/users/me/tokens", httpMiddleware.IsAuthorized(accessTokenDelivery.CreateAccessToken)).Methods("POST")
+	router.Handle("/users/me/tokens/{id}", httpMiddleware.IsAuthorized(accessTokenDelivery.RevokeAccessToken)).Methods("DELETE")
+	router.Handle("/users/me/follow-requests", httpMiddleware.IsAuthorized(followDelivery.GetFollowRequests, "users:read")).Methods("GET")
+	router.Handle("/users/me/follow-requests/{id}/approve", httpMiddleware.IsAuthorized(followDelivery.ApproveFollowRequest, "users:write")).Methods("POST")
+	router.Handle("/users/me/follow-requests/{id}/reject", httpMiddleware.IsAuthorized(followDelivery.RejectFollowRequest, "users:write")).Methods("POST")
+	router.Handle("/users/me/blocks", httpMiddleware.IsAuthorized(blockDelivery.GetBlocks, "users:read")).Methods("GET")
+	router.Handle("/users/me/mutes", httpMiddleware.IsAuthorized(muteDelivery.GetMutes, "users:read")).Methods("GET")
+	router.Handle("/notifications", httpMiddleware.IsAuthorized(notificationDelivery.GetNotifications, "users:read")).Methods("GET")
+	router.Handle("/notifications/read", httpMiddleware.IsAuthorized(notificationDelivery.MarkRead, "users:write")).Methods("POST")
+	router.Handle("/stream", httpMiddleware.IsAuthorized(streamDelivery.Stream, "posts:read", "users:read")).Methods("GET")
+	router.Handle("/webhooks", httpMiddleware.IsAuthorized(webhookDelivery.GetWebhooks)).Methods("GET")
+	router.Handle("/webhooks", httpMiddleware.IsAuthorized(webhookDelivery.AddWebhook)).Methods("POST")
+	router.Handle("/webhooks/{id}", httpMiddleware.IsAuthorized(webhookDelivery.DeleteWebhook)).Methods("DELETE")
+	router.Handle("/webhooks/{id}/deliveries", httpMiddleware.IsAuthorized(webhookDelivery.GetDeliveries)).Methods("GET")
+	router.Handle("/users/suggestions", httpMiddleware.IsAuthorized(suggestionDelivery.GetSuggestions, "users:read")).Methods("GET")
+	router.Handle("/users/by-username/{username}", httpMiddleware.IsAuthorized(userDelivery.GetUserByUsername, "users:read")).Methods("GET")
+	router.Handle("/users/{id}", httpMiddleware.IsAuthorized(userDelivery.GetUserByID, "users:read")).Methods("GET")
+	router.Handle("/users/{id}/posts", httpMiddleware.IsAuthorized(postDelivery.GetUserPosts, "posts:read")).Methods("GET")
+	router.Handle("/users/{id}/follow", httpMiddleware.IsAuthorized(followDelivery.Follow, "users:write")).Methods("POST")
+	router.Handle("/users/{id}/follow", httpMiddleware.IsAuthorized(followDelivery.Unfollow, "users:write")).Methods("DELETE")
+	router.Handle("/users/{id}/block", httpMiddleware.IsAuthorized(blockDelivery.Block, "users:write")).Methods("POST")
+	router.Handle("/users/{id}/block", httpMiddleware.IsAuthorized(blockDelivery.Unblock, "users:write")).Methods("DELETE")
+	router.Handle("/users/{id}/mute", httpMiddleware.IsAuthorized(muteDelivery.Mute, "users:write")).Methods("POST")
+	router.Handle("/users/{id}/mute", httpMiddleware.IsAuthorized(muteDelivery.Unmute, "users:write")).Methods("DELETE")
+	router.Handle("/users/update", httpMiddleware.IsAuthorized(userDelivery.UpdateUser, "users:write")).Methods("PATCH")
+
+	var purgeWorker worker.Worker = worker.NewWorker("purge deleted users", time.Hour, usecase.NewUserUsecase().PurgeDeletedUsers)
+
+	var sessionWorker worker.Worker = worker.NewWorker("purge expired sessions", time.Hour, usecase.NewSessionUsecase().PurgeExpiredSessions)
+
+	var variantWorker worker.Worker = worker.NewWorker("generate image variants", 10*time.Second, usecase.NewMediaUsecase().GenerateVariants)
+
+	var webhookWorker worker.Worker = worker.NewWorker("deliver webhooks", 10*time.Second, usecase.NewWebhookUsecase().DeliverWebhooks)
+
+	var trendingWorker worker.Worker = worker.NewWorker("compute trending hashtags", 5*time.Minute, usecase.NewTrendingUsecase().ComputeTrending)
+
+	// walking the follow graph on every request gets slow on big graphs, suggestions can be precomputed instead
+	if usecase.SuggestionsPrecomputed() {
+		var suggestionWorker worker.Worker = worker.NewWorker("precompute suggestions", time.Hour, usecase.NewSuggestionUsecase().PrecomputeSuggestions)
+
+		go suggestionWorker.Run(nil)
+	}
+
+	go purgeWorker.Run(nil)
+	go sessionWorker.Run(nil)
+	go variantWorker.Run(nil)
+	go trendingWorker.Run(nil)
+	go webhookWorker.Run(nil)
 
 	log.Println("Server is listening on port", port)
 	log.Fatalln(http.ListenAndServe(port, router))
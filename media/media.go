@@ -0,0 +1,402 @@
+// Package media checks uploaded images and strips the metadata, such as EXIF location data, that they may carry
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	// register the decoders of the supported formats for image.DecodeConfig
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+// Image is an uploaded image cleaned up and ready to be stored
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+const (
+	// MaxImageSize is the largest upload accepted, in bytes
+	MaxImageSize = 10 << 20
+	// MaxImageDimension is the largest width or height accepted, in pixels
+	MaxImageDimension = 8192
+)
+
+var (
+	// ErrTooLarge is returned for images above MaxImageSize or MaxImageDimension
+	ErrTooLarge = fmt.Errorf("error: too large \"media_size_key\"")
+	// ErrUnsupportedType is returned for uploads that are not JPEG, PNG or GIF images, whatever their file name says
+	ErrUnsupportedType = fmt.Errorf("error: unsupported type \"media_content_type_key\"")
+	// ErrCorrupt is returned for images that look like a supported type but can't be decoded
+	ErrCorrupt = fmt.Errorf("error: invalid \"media_content_type_key\"")
+)
+
+// Extensions maps the supported content types to the file extension blobs are stored with
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Process sniffs the content type of the upload, strips its metadata and checks its dimensions
+func Process(data []byte) (Image, error) {
+	if len(data) > MaxImageSize {
+		return Image{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+
+	var err error
+
+	switch contentType {
+	case "image/jpeg":
+		data, err = stripJPEG(data)
+	case "image/png":
+		data, err = stripPNG(data)
+	case "image/gif":
+		data, err = stripGIF(data)
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	if err != nil {
+		return Image{}, ErrCorrupt
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return Image{}, ErrCorrupt
+	}
+
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+		return Image{}, ErrTooLarge
+	}
+
+	return Image{Data: data, ContentType: contentType, Width: config.Width, Height: config.Height}, nil
+}
+
+const (
+	jpegAPP1  = 0xE1 // EXIF and XMP
+	jpegAPP13 = 0xED // IPTC
+	jpegCOM   = 0xFE
+	jpegSOS   = 0xDA
+)
+
+// stripJPEG drops the APP1, APP13 and comment segments in front of the image data. APP0 (JFIF), APP2 (ICC profile)
+// and APP14 (Adobe) are kept because they affect how the image is rendered, and so is the EXIF orientation, which is
+// written back as an APP1 segment of its own
+func stripJPEG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write([]byte{0xFF, 0xD8})
+	oriented := false
+
+	sos, err := walkJPEG(data, func(marker byte, segment []byte) {
+		switch marker {
+		case jpegAPP1:
+			if orientation := exifOrientation(segment[2:]); !oriented && orientation > 1 {
+				out.Write(orientationSegment(orientation))
+				oriented = true
+			}
+		case jpegAPP13, jpegCOM:
+			// dropped along with the rest of the metadata
+		default:
+			out.Write([]byte{0xFF, marker})
+			out.Write(segment)
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out.Write([]byte{0xFF, jpegSOS})
+	out.Write(data[sos:])
+
+	return out.Bytes(), nil
+}
+
+// jpegOrientation returns the EXIF orientation of the JPEG, 1 when it has none
+func jpegOrientation(data []byte) uint16 {
+	orientation := uint16(1)
+
+	walkJPEG(data, func(marker byte, segment []byte) {
+		if marker == jpegAPP1 && orientation == 1 {
+			if exif := exifOrientation(segment[2:]); exif > 1 {
+				orientation = exif
+			}
+		}
+	})
+
+	return orientation
+}
+
+// walkJPEG calls fn with every segment in front of the image data, starting from its length, and returns the offset of
+// the image data right after the SOS marker
+func walkJPEG(data []byte, fn func(marker byte, segment []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, ErrCorrupt
+	}
+
+	i := 2
+
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return 0, ErrCorrupt
+		}
+
+		// markers may be padded with any number of 0xFF fill bytes
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+
+		if i >= len(data) {
+			return 0, ErrCorrupt
+		}
+
+		marker := data[i]
+		i++
+
+		if marker == jpegSOS {
+			return i, nil
+		}
+
+		if i+2 > len(data) {
+			return 0, ErrCorrupt
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i:]))
+
+		if length < 2 || i+length > len(data) {
+			return 0, ErrCorrupt
+		}
+
+		fn(marker, data[i:i+length])
+
+		i += length
+	}
+
+	return 0, ErrCorrupt
+}
+
+const (
+	exifOrientationTag = 0x0112
+	exifShort          = 3
+)
+
+// exifOrientation returns the orientation in the IFD0 of the EXIF APP1 payload, 0 when there is none
+func exifOrientation(payload []byte) uint16 {
+	if len(payload) < 14 || string(payload[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+
+	tiff := payload[6:]
+	var order binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+
+	for n := 0; n < int(order.Uint16(tiff[ifd:])); n++ {
+		entry := ifd + 2 + n*12
+
+		if entry+12 > len(tiff) {
+			return 0
+		}
+
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+
+		if orientation := order.Uint16(tiff[entry+8:]); order.Uint16(tiff[entry+2:]) == exifShort && orientation <= 8 {
+			return orientation
+		}
+
+		return 0
+	}
+
+	return 0
+}
+
+// orientationSegment returns an APP1 segment whose EXIF holds nothing but the orientation
+func orientationSegment(orientation uint16) []byte {
+	segment := []byte("\xFF\xE1\x00\x22Exif\x00\x00MM\x00\x2A\x00\x00\x00\x08" +
+		"\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(segment[28:], orientation)
+	return segment
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are the ancillary chunks that carry metadata rather than anything needed to render the image
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG drops the metadata chunks, the other chunks are copied as they are
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrCorrupt
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	i := len(pngSignature)
+
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, ErrCorrupt
+		}
+
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length
+
+		if length < 0 || end > len(data) || end < i {
+			return nil, ErrCorrupt
+		}
+
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[i:end])
+		}
+
+		i = end
+
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+
+	return nil, ErrCorrupt
+}
+
+const (
+	gifExtension      = 0x21
+	gifImage          = 0x2C
+	gifTrailer        = 0x3B
+	gifComment        = 0xFE
+	gifApplication    = 0xFF
+	gifColorTableFlag = 0x80
+)
+
+// gifLoopApplications are the application extensions that say how often an animation loops, other applications
+// extensions such as XMP carry metadata
+var gifLoopApplications = map[string]bool{"NETSCAPE2.0": true, "ANIMEXTS1.0": true}
+
+// stripGIF drops the comment extensions and the application extensions other than the loop count, the other blocks
+// are copied as they are
+func stripGIF(data []byte) ([]byte, error) {
+	// header and logical screen descriptor
+	if len(data) < 13 {
+		return nil, ErrCorrupt
+	}
+
+	i := 13 + gifColorTableSize(data[10])
+
+	if i > len(data) {
+		return nil, ErrCorrupt
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:i])
+
+	for i < len(data) {
+		start := i
+
+		switch data[i] {
+		case gifTrailer:
+			out.WriteByte(gifTrailer)
+			return out.Bytes(), nil
+		case gifExtension:
+			if i+2 > len(data) {
+				return nil, ErrCorrupt
+			}
+
+			label := data[i+1]
+			end, err := skipGIFSubBlocks(data, i+2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			i = end
+
+			if label == gifComment || (label == gifApplication && !gifLoopApplications[gifApplicationID(data[start+2:end])]) {
+				continue
+			}
+		case gifImage:
+			// image descriptor, optional local color table and the LZW minimum code size
+			if i+10 > len(data) {
+				return nil, ErrCorrupt
+			}
+
+			i += 10 + gifColorTableSize(data[i+9]) + 1
+
+			if i > len(data) {
+				return nil, ErrCorrupt
+			}
+
+			end, err := skipGIFSubBlocks(data, i)
+
+			if err != nil {
+				return nil, err
+			}
+
+			i = end
+		default:
+			return nil, ErrCorrupt
+		}
+
+		out.Write(data[start:i])
+	}
+
+	return nil, ErrCorrupt
+}
+
+// gifColorTableSize returns the length of the color table the packed field of a descriptor announces
+func gifColorTableSize(packed byte) int {
+	if packed&gifColorTableFlag == 0 {
+		return 0
+	}
+	return 3 << (uint(packed&0x07) + 1)
+}
+
+// skipGIFSubBlocks returns where the data sub-blocks starting at i end, after their zero length terminator
+func skipGIFSubBlocks(data []byte, i int) (int, error) {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+
+		if size == 0 {
+			return i, nil
+		}
+
+		i += size
+	}
+
+	return 0, ErrCorrupt
+}
+
+// gifApplicationID returns the identifier and authentication code of an application extension from its sub-blocks
+func gifApplicationID(blocks []byte) string {
+	if len(blocks) < 12 || blocks[0] != 11 {
+		return ""
+	}
+	return string(blocks[1:12])
+}
@@ -0,0 +1,317 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newImage(width int, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	return img
+}
+
+func TestProcessStripsJPEGExif(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, newImage(4, 3), nil)
+
+	exif := append([]byte("Exif\x00\x00"), []byte("GPS 52.37N 4.89E")...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+
+	data := append([]byte{}, buf.Bytes()[:2]...)
+	data = append(data, segment...)
+	data = append(data, exif...)
+	data = append(data, buf.Bytes()[2:]...)
+
+	img, err := Process(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+	assert.Equal(t, 4, img.Width)
+	assert.Equal(t, 3, img.Height)
+	assert.False(t, bytes.Contains(img.Data, []byte("Exif")))
+	assert.False(t, bytes.Contains(img.Data, []byte("GPS")))
+
+	_, err = jpeg.Decode(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+}
+
+// exifJPEG returns the JPEG with an EXIF APP1 segment that holds the orientation and an artist, little endian as
+// most cameras write it
+func exifJPEG(jpg []byte, orientation uint16) []byte {
+	exif := []byte("Exif\x00\x00II\x2A\x00\x08\x00\x00\x00\x02\x00")
+	entry := make([]byte, 12)
+
+	// Artist, an ASCII string stored at offset 38 of the TIFF header
+	binary.LittleEndian.PutUint16(entry, 0x013B)
+	binary.LittleEndian.PutUint16(entry[2:], 2)
+	binary.LittleEndian.PutUint32(entry[4:], 9)
+	binary.LittleEndian.PutUint32(entry[8:], 38)
+	exif = append(exif, entry...)
+
+	binary.LittleEndian.PutUint16(entry, 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3)
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint32(entry[8:], uint32(orientation))
+	exif = append(exif, entry...)
+	exif = append(exif, "\x00\x00\x00\x00Jane Doe\x00"...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+
+	data := append([]byte{}, jpg[:2]...)
+	data = append(data, segment...)
+	data = append(data, exif...)
+	return append(data, jpg[2:]...)
+}
+
+func TestProcessKeepsJPEGOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, newImage(4, 3), nil)
+
+	img, err := Process(exifJPEG(buf.Bytes(), 6))
+
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(img.Data, []byte("Jane Doe")))
+	assert.Equal(t, uint16(6), jpegOrientation(img.Data))
+
+	_, err = jpeg.Decode(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+
+	// an upright image needs no orientation at all
+	img, err = Process(exifJPEG(buf.Bytes(), 1))
+
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(img.Data, []byte("Exif")))
+}
+
+func TestProcessStripsPNGText(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, newImage(2, 2))
+
+	text := []byte("Comment\x00taken at home")
+	chunk := make([]byte, 8, 12+len(text))
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, text...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	chunk = append(chunk, crc...)
+
+	// right after the IHDR chunk, which is 25 bytes long including its length, type and crc
+	data := append([]byte{}, buf.Bytes()[:33]...)
+	data = append(data, chunk...)
+	data = append(data, buf.Bytes()[33:]...)
+
+	img, err := Process(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.False(t, bytes.Contains(img.Data, []byte("taken at home")))
+
+	_, err = png.Decode(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+}
+
+func TestProcessGIF(t *testing.T) {
+	var buf bytes.Buffer
+	gif.Encode(&buf, newImage(5, 5), nil)
+
+	img, err := Process(buf.Bytes())
+
+	assert.Nil(t, err)
+	assert.Equal(t, "image/gif", img.ContentType)
+}
+
+func TestProcessStripsGIFComments(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 3, 3), palette), image.NewPaletted(image.Rect(0, 0, 3, 3), palette)},
+		Delay: []int{10, 10},
+	}
+
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, animation)
+
+	comment := append([]byte{0x21, 0xFE, 13}, []byte("taken at home\x00")...)
+	xmp := append([]byte{0x21, 0xFF, 11}, []byte("XMP DataXMP\x05<x:a>\x00")...)
+
+	// extensions may come anywhere before the trailer
+	data := append([]byte{}, buf.Bytes()[:buf.Len()-1]...)
+	data = append(data, comment...)
+	data = append(data, xmp...)
+	data = append(data, 0x3B)
+
+	img, err := Process(data)
+
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(img.Data, []byte("taken at home")))
+	assert.False(t, bytes.Contains(img.Data, []byte("XMP")))
+	assert.Equal(t, buf.Bytes(), img.Data)
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(decoded.Image))
+	assert.Equal(t, 0, decoded.LoopCount)
+}
+
+func TestProcessUnsupportedType(t *testing.T) {
+	_, err := Process([]byte("<html><script>alert(1)</script></html>"))
+
+	assert.Equal(t, ErrUnsupportedType, err)
+}
+
+func TestProcessCorruptImage(t *testing.T) {
+	_, err := Process([]byte("\xFF\xD8\xFF\xE0 not really a jpeg"))
+
+	assert.Equal(t, ErrCorrupt, err)
+}
+
+func TestProcessTooLarge(t *testing.T) {
+	_, err := Process(make([]byte, MaxImageSize+1))
+
+	assert.Equal(t, ErrTooLarge, err)
+}
+
+func TestProcessTooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, MaxImageDimension+1, 1)))
+
+	_, err := Process(buf.Bytes())
+
+	assert.Equal(t, ErrTooLarge, err)
+}
+
+func TestFitDimensions(t *testing.T) {
+	width, height := FitDimensions(4000, 3000, 640)
+
+	assert.Equal(t, 640, width)
+	assert.Equal(t, 480, height)
+
+	width, height = FitDimensions(300, 1200, 150)
+
+	assert.Equal(t, 37, width)
+	assert.Equal(t, 150, height)
+
+	width, height = FitDimensions(100, 50, 640)
+
+	assert.Equal(t, 100, width)
+	assert.Equal(t, 50, height)
+}
+
+func TestResizeJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, newImage(400, 200), nil)
+
+	img, err := Resize(buf.Bytes(), Variant{Name: "thumbnail", MaxDimension: 150})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+	assert.Equal(t, 150, img.Width)
+	assert.Equal(t, 75, img.Height)
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 150, config.Width)
+	assert.Equal(t, 75, config.Height)
+}
+
+func TestResizeJPEGKeepsOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, newImage(400, 200), nil)
+
+	img, err := Resize(orientedJPEG(buf.Bytes(), 8), Variant{Name: "thumbnail", MaxDimension: 150})
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(8), jpegOrientation(img.Data))
+
+	_, err = jpeg.Decode(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+}
+
+// orientedJPEG returns the JPEG the way Process stores it, with an APP1 segment that holds nothing but the orientation
+func orientedJPEG(jpg []byte, orientation uint16) []byte {
+	data := append([]byte{}, jpg[:2]...)
+	data = append(data, orientationSegment(orientation)...)
+	return append(data, jpg[2:]...)
+}
+
+func TestResizeGIFAsPNG(t *testing.T) {
+	var buf bytes.Buffer
+	gif.Encode(&buf, newImage(20, 40), nil)
+
+	img, err := Resize(buf.Bytes(), Variant{Name: "thumbnail", MaxDimension: 10})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+
+	config, err := png.DecodeConfig(bytes.NewReader(img.Data))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 5, config.Width)
+	assert.Equal(t, 10, config.Height)
+}
+
+func TestResizeCorrupt(t *testing.T) {
+	_, err := Resize([]byte("\x89PNG\r\n\x1a\nnot really"), Variants[0])
+
+	assert.Equal(t, ErrCorrupt, err)
+}
+
+func TestAvatarsCropCenterSquare(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 100; x < 200; x++ {
+			src.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+
+	images, err := Avatars(buf.Bytes(), []Variant{{Name: "small", MaxDimension: 48}, {Name: "large", MaxDimension: 400}})
+
+	assert.Nil(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, "image/png", images[0].ContentType)
+	assert.Equal(t, 48, images[0].Width)
+	assert.Equal(t, 48, images[0].Height)
+	// smaller squares are not scaled up
+	assert.Equal(t, 100, images[1].Width)
+	assert.Equal(t, 100, images[1].Height)
+
+	decoded, err := png.Decode(bytes.NewReader(images[0].Data))
+
+	assert.Nil(t, err)
+
+	// only the blue center of the wide image is left
+	for _, point := range []image.Point{{0, 0}, {47, 47}, {24, 24}} {
+		_, _, b, _ := decoded.At(point.X, point.Y).RGBA()
+		assert.Equal(t, uint32(0xFFFF), b)
+	}
+}
+
+func TestAvatarsTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, MaxImageDimension+1, 1)))
+
+	_, err := Avatars(buf.Bytes(), AvatarSizes)
+
+	assert.Equal(t, ErrTooLarge, err)
+}
@@ -0,0 +1,189 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// Variant is a resized version of an uploaded image that fits in a MaxDimension square
+type Variant struct {
+	Name         string
+	MaxDimension int
+}
+
+// Variants are generated for every uploaded image, smallest first
+var Variants = []Variant{
+	{Name: "thumbnail", MaxDimension: 150},
+	{Name: "medium", MaxDimension: 640},
+	{Name: "full", MaxDimension: 1280},
+}
+
+// AvatarSizes are the squares generated for every uploaded avatar, smallest first
+var AvatarSizes = []Variant{
+	{Name: "small", MaxDimension: 48},
+	{Name: "medium", MaxDimension: 128},
+	{Name: "large", MaxDimension: 400},
+}
+
+// jpegQuality is what JPEG variants are encoded with
+const jpegQuality = 85
+
+// FitDimensions scales width and height down to fit in a maxDimension square, keeping the aspect ratio. Images that
+// fit already are not scaled up
+func FitDimensions(width int, height int, maxDimension int) (int, int) {
+	if width <= maxDimension && height <= maxDimension {
+		return width, height
+	}
+
+	if width >= height {
+		return maxDimension, max(1, height*maxDimension/width)
+	}
+
+	return max(1, width*maxDimension/height), maxDimension
+}
+
+// Resize decodes the stored image and encodes it again scaled down to fit the variant. JPEG stays JPEG, PNG and GIF
+// variants are encoded as PNG, so animated GIFs are reduced to their first frame
+func Resize(data []byte, variant Variant) (Image, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return Image{}, ErrCorrupt
+	}
+
+	bounds := src.Bounds()
+	width, height := FitDimensions(bounds.Dx(), bounds.Dy(), variant.MaxDimension)
+
+	return encode(scale(src, width, height), format, jpegOrientation(data))
+}
+
+// Avatars decodes the stored image, crops the largest square out of its center and encodes it again scaled down to
+// fit each of the sizes, in the same formats as Resize. Images above MaxImageDimension are rejected before decoding
+func Avatars(data []byte, sizes []Variant) ([]Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+		return nil, ErrTooLarge
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	bounds := src.Bounds()
+	side := bounds.Dx()
+
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+	draw.Draw(square, square.Bounds(), src, offset, draw.Src)
+
+	images := make([]Image, len(sizes))
+	orientation := jpegOrientation(data)
+
+	for i, size := range sizes {
+		width, height := FitDimensions(side, side, size.MaxDimension)
+
+		if images[i], err = encode(scale(square, width, height), format, orientation); err != nil {
+			return nil, err
+		}
+	}
+
+	return images, nil
+}
+
+// encode keeps JPEG images JPEG, with the orientation of the image they were made from, and encodes everything else
+// as PNG
+func encode(img *image.RGBA, format string, orientation uint16) (Image, error) {
+	var buf bytes.Buffer
+	var err error
+	contentType := "image/png"
+
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+
+	if err != nil {
+		return Image{}, err
+	}
+
+	data := buf.Bytes()
+
+	if format == "jpeg" && orientation > 1 {
+		data = append(append(data[:2:2], orientationSegment(orientation)...), data[2:]...)
+	}
+
+	return Image{Data: data, ContentType: contentType, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}, nil
+}
+
+// scale averages every source pixel that falls in a destination pixel, which keeps downscaled images smooth. RGBA
+// images are scaled without being copied first
+func scale(src image.Image, width int, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+
+	if bounds.Dx() == width && bounds.Dy() == height {
+		return rgba
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * bounds.Dy() / height
+		y1 := max(y0+1, (y+1)*bounds.Dy()/height)
+
+		for x := 0; x < width; x++ {
+			x0 := x * bounds.Dx() / width
+			x1 := max(x0+1, (x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, count int
+
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					a += int(row[sx*4+3])
+					count++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = uint8(a / count)
+		}
+	}
+
+	return dst
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// AccessToken schema for AccessToken table, a personal access token a user creates for scripts and integrations
+type AccessToken struct {
+	ID         uint       `gorm:"primary_key" json:"id"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"not null;unique_index"`
+	Scopes     string     `json:"scopes" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
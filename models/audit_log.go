@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog schema for AuditLog table, records security relevant events
+type AuditLog struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Action    string    `json:"action" gorm:"not null"`
+	IP        string    `json:"ip"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
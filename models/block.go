@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Block schema for Block table, blocked users and their blockers can't see or follow each other
+type Block struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	BlockerID uint      `json:"blocker_id" gorm:"not null;unique_index:idx_blocks_blocker_blocked"`
+	BlockedID uint      `json:"blocked_id" gorm:"not null;unique_index:idx_blocks_blocker_blocked;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
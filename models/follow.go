@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Follow statuses, following a private account starts out pending until the account owner approves or rejects it
+const (
+	FollowPending  = "pending"
+	FollowAccepted = "accepted"
+	FollowRejected = "rejected"
+)
+
+// Follow schema for Follow table, one record per follower and followed user
+type Follow struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	FollowerID uint      `json:"follower_id" gorm:"not null;unique_index:idx_follows_follower_followee"`
+	FolloweeID uint      `json:"followee_id" gorm:"not null;unique_index:idx_follows_follower_followee;index"`
+	Status     string    `json:"status" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity schema for Identity table, an account at an external OpenID Connect provider linked to a user
+type Identity struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Provider  string    `json:"provider" gorm:"not null;unique_index:idx_identities_provider_subject"`
+	Subject   string    `json:"-" gorm:"not null;unique_index:idx_identities_provider_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
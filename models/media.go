@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Media statuses, the variants of an uploaded image are generated in the background while it is processing
+const (
+	MediaProcessing = "processing"
+	MediaReady      = "ready"
+	MediaFailed     = "failed"
+)
+
+// Media schema for Media table, an image attached to a post and stored in the blob store under Key
+type Media struct {
+	ID          uint           `gorm:"primary_key" json:"id"`
+	PostID      uint           `json:"post_id" gorm:"not null;index"`
+	UserID      uint           `json:"user_id" gorm:"not null"`
+	Key         string         `json:"-" gorm:"not null;unique"`
+	ContentType string         `json:"content_type" gorm:"not null"`
+	Size        int            `json:"size"`
+	Width       int            `json:"width"`
+	Height      int            `json:"height"`
+	Status      string         `json:"status" gorm:"not null;default:'processing';index"`
+	Attempts    int            `json:"-" gorm:"not null;default:0"`
+	URL         string         `json:"url" gorm:"-"`
+	Variants    []MediaVariant `json:"variants" gorm:"foreignkey:MediaID"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// MediaVariant schema for MediaVariant table, a resized version of the media stored in the blob store under Key.
+// Placeholder variants that are not stored yet have no Key and point at the original image
+type MediaVariant struct {
+	ID          uint   `gorm:"primary_key" json:"-"`
+	MediaID     uint   `json:"-" gorm:"not null;unique_index:idx_media_variants_media_name"`
+	Name        string `json:"name" gorm:"not null;unique_index:idx_media_variants_media_name"`
+	Key         string `json:"-" gorm:"not null;unique"`
+	ContentType string `json:"content_type" gorm:"not null"`
+	Size        int    `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url" gorm:"-"`
+	Placeholder bool   `json:"placeholder" gorm:"-"`
+}
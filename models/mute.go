@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Mute schema for Mute table, posts of muted users are left out of the muter's feeds
+type Mute struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	MuterID   uint      `json:"muter_id" gorm:"not null;unique_index:idx_mutes_muter_muted"`
+	MutedID   uint      `json:"muted_id" gorm:"not null;unique_index:idx_mutes_muter_muted"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification types, there is one per event users are told about
+const (
+	NotificationFollow         = "follow"
+	NotificationFollowRequest  = "follow_request"
+	NotificationFollowAccepted = "follow_accepted"
+	NotificationMention        = "mention"
+)
+
+// Notification schema for Notification table, tells the user that the actor did something involving them. PostID is
+// set for notifications about a post and ReadAt once the user read it
+type Notification struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	ActorID   uint       `json:"actor_id" gorm:"not null"`
+	Type      string     `json:"type" gorm:"not null"`
+	PostID    uint       `json:"post_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	Actor     *User      `json:"-" gorm:"foreignkey:ActorID;save_associations:false"`
+}
+
+// NotificationGroup is the notifications of one type about the same post shown as one, like "budi and 4 others
+// followed you". Actors are the latest actors, ActorCount counts all of them
+type NotificationGroup struct {
+	IDs        []uint
+	Type       string
+	PostID     uint
+	Actors     []User
+	ActorCount int
+	Unread     bool
+	Text       string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset schema for PasswordReset table, only the hash of the emailed token is stored
+type PasswordReset struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
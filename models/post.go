@@ -1,8 +1,19 @@
 package models
 
-// Post schema for Post table
+import (
+	"time"
+)
+
+// Post schema for Post table, Author is only loaded for listings and never serialized as it is. The hashtags and
+// mentions of the caption are stored in Tags and Mentions and shown as Entities
 type Post struct {
-	ID      uint   `gorm:"primary_key" json:"id"`
-	Caption string `json:"caption" gorm:"not null"`
-	UserID  uint   `json:"user_id" gorm:"not null"`
+	ID        uint          `gorm:"primary_key" json:"id"`
+	Caption   string        `json:"caption" gorm:"not null"`
+	UserID    uint          `json:"user_id" gorm:"not null"`
+	Media     []Media       `json:"media" gorm:"foreignkey:PostID"`
+	Tags      []PostTag     `json:"-" gorm:"foreignkey:PostID"`
+	Mentions  []PostMention `json:"-" gorm:"foreignkey:PostID"`
+	Entities  []Entity      `json:"entities" gorm:"-"`
+	Author    *User         `json:"-" gorm:"foreignkey:UserID;save_associations:false"`
+	CreatedAt time.Time     `json:"created_at" gorm:"index"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode schema for RecoveryCode table, single-use codes that replace a TOTP code when the device is lost
+type RecoveryCode struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
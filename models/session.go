@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Session schema for Session table, one record per login that the issued auth token refers to
+type Session struct {
+	ID         uint       `gorm:"primary_key" json:"id"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Suggestion schema for Suggestion table, an account suggested to the user to follow because accounts the user
+// follows follow it. MutualCount is how many of them do and RecentPosts how many posts it wrote lately. The table
+// holds the suggestions precomputed for big follow graphs, they are computed on request otherwise
+type Suggestion struct {
+	ID          uint      `gorm:"primary_key" json:"-"`
+	UserID      uint      `json:"-" gorm:"not null;unique_index:idx_suggestions_user_suggested"`
+	SuggestedID uint      `json:"-" gorm:"not null;unique_index:idx_suggestions_user_suggested"`
+	MutualCount int       `json:"mutual_count"`
+	RecentPosts int       `json:"recent_posts"`
+	Suggested   *User     `json:"-" gorm:"foreignkey:SuggestedID;save_associations:false"`
+	CreatedAt   time.Time `json:"-"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// Entity types, the entities of a caption are parsed from its text
+const (
+	EntityHashtag = "hashtag"
+	EntityMention = "mention"
+)
+
+// PostTag schema for PostTag table, one record per distinct hashtag of a post. Tag is lowercased and without the #
+type PostTag struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	PostID    uint      `json:"post_id" gorm:"not null;unique_index:idx_post_tags_post_tag"`
+	Tag       string    `json:"tag" gorm:"not null;unique_index:idx_post_tags_post_tag;index"`
+	CreatedAt time.Time `json:"-" gorm:"index"`
+}
+
+// PostMention schema for PostMention table, one record per user mentioned in a post. Username is lowercased and
+// without the @, as it was written in the caption
+type PostMention struct {
+	ID       uint   `gorm:"primary_key" json:"-"`
+	PostID   uint   `json:"post_id" gorm:"not null;unique_index:idx_post_mentions_post_user"`
+	UserID   uint   `json:"user_id" gorm:"not null;unique_index:idx_post_mentions_post_user;index"`
+	Username string `json:"username" gorm:"not null"`
+}
+
+// Entity is a hashtag or a mention in a caption. Start and End are offsets in characters (Unicode code points) of the
+// caption, the # or @ included, and Text is what follows the # or @
+type Entity struct {
+	Type   string `json:"type"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Text   string `json:"text"`
+	UserID uint   `json:"user_id,omitempty"`
+}
+
+// TagCount is how many posts of an author used a hashtag in the hour starting at Hour
+type TagCount struct {
+	Tag    string
+	UserID uint
+	Hour   time.Time
+	Count  int
+}
+
+// TrendingTag is a hashtag whose use grows, Score is how much more it is used than usual and Count how many posts
+// used it in the trending window
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
+	Count int     `json:"count"`
+}
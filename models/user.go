@@ -6,14 +6,27 @@ import (
 
 // User schema for User table
 type User struct {
-	ID             uint       `gorm:"primary_key" json:"id"`
-	Username       string     `json:"username" gorm:"unique;not null"`
-	Name           string     `json:"name" gorm:"not null"`
-	Email          string     `json:"email" gorm:"unique;not null"`
-	Password       string     `json:"password,omitempty"`
-	FollowerCount  int        `json:"follower_count"`
-	FollowingCount int        `json:"following_count"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
-	DeletedAt      *time.Time `json:"deleted_at"`
+	ID                uint       `gorm:"primary_key" json:"id"`
+	Username          string     `json:"username" gorm:"unique;not null"`
+	Name              string     `json:"name" gorm:"not null"`
+	Email             string     `json:"email" gorm:"unique;not null"`
+	Password          string     `json:"-"`
+	FollowerCount     int        `json:"follower_count"`
+	FollowingCount    int        `json:"following_count"`
+	Bio               string     `json:"bio"`
+	Website           string     `json:"website"`
+	Location          string     `json:"location"`
+	AvatarURL         string     `json:"avatar_url"`
+	AvatarKey         string     `json:"-"`
+	IsPrivate         bool       `json:"is_private"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at"`
+	SessionsRevokedAt *time.Time `json:"-"`
+	TOTPSecret        string     `json:"-"`
+	TOTPEnabled       bool       `json:"totp_enabled"`
+	TOTPLastStep      int64      `json:"-"`
+	MFANonce          string     `json:"-"`
+	IsAdmin           bool       `json:"is_admin"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `json:"deleted_at"`
 }
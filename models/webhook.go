@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Webhook events partner services can subscribe to
+const (
+	WebhookUserCreated = "user.created"
+	WebhookPostCreated = "post.created"
+	WebhookFollow      = "follow.created"
+)
+
+// Webhook delivery statuses, a delivery is retried while pending and is dead once it ran out of attempts
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// Webhook schema for Webhook table, a URL the events in Events are posted to. Events is comma separated and the
+// payloads are signed with Secret
+type Webhook struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	URL       string    `json:"url" gorm:"not null"`
+	Events    string    `json:"events" gorm:"not null"`
+	Secret    string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery schema for WebhookDelivery table, one event posted to one webhook and the outcome of the last attempt
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primary_key" json:"id"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	Event          string     `json:"event" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"not null;index"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	Error          string     `json:"error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
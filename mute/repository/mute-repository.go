@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// MuteRepository is the repository interface for mute
+type MuteRepository interface {
+	AddMute(mute *models.Mute) error
+	GetMute(muterID uint, mutedID uint, mute *models.Mute) error
+	GetMutes(muterID uint) ([]models.Mute, error)
+	DeleteMute(muterID uint, mutedID uint) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Mute{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewMuteRepository create a new mute repository to fiddle around with database
+func NewMuteRepository(db *gorm.DB) MuteRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddMute returns an error if there is any, otherwise creates a new mute record into database
+func (r *repo) AddMute(mute *models.Mute) error {
+	return r.db.Create(mute).Error
+}
+
+// GetMute returns an error if the muter didn't mute the user, otherwise modifies the mute parameter
+func (r *repo) GetMute(muterID uint, mutedID uint, mute *models.Mute) error {
+	return r.db.Where("muter_id = ? AND muted_id = ?", muterID, mutedID).First(mute).Error
+}
+
+// GetMutes returns the users the muter muted, most recent first
+func (r *repo) GetMutes(muterID uint) ([]models.Mute, error) {
+	var mutes []models.Mute
+
+	err := r.db.Where("muter_id = ?", muterID).Order("created_at desc").Find(&mutes).Error
+
+	return mutes, err
+}
+
+// DeleteMute unmutes the user, returns an error if the user wasn't muted
+func (r *repo) DeleteMute(muterID uint, mutedID uint) error {
+	result := r.db.Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Delete(&models.Mute{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error: not found \"mutes_muted_id_key\"")
+	}
+
+	return nil
+}
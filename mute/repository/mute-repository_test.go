@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	muteRepo MuteRepository
+	mock     sqlmock.Sqlmock
+	db       *sql.DB
+	gdb      *gorm.DB
+	err      error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	muteRepo = NewMuteRepository(gdb)
+}
+
+func TestAddMute(t *testing.T) {
+	setup()
+
+	mute := models.Mute{MuterID: 1, MutedID: 2}
+	const sqlInsert = `INSERT INTO "mutes" ("muter_id","muted_id","created_at") VALUES ($1,$2,$3) RETURNING "mutes"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, 2, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	err := muteRepo.AddMute(&mute)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(5), mute.ID)
+}
+
+func TestGetMutes(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "mutes" WHERE (muter_id = $1) ORDER BY created_at desc`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "muter_id", "muted_id"}).AddRow(5, 1, 2))
+
+	mutes, err := muteRepo.GetMutes(1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mutes))
+	assert.Equal(t, uint(2), mutes[0].MutedID)
+}
+
+func TestDeleteMute(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "mutes"  WHERE (muter_id = $1 AND muted_id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := muteRepo.DeleteMute(1, 2)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
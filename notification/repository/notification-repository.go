@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// NotificationRepository is the repository interface for notification
+type NotificationRepository interface {
+	AddNotification(notification *models.Notification) (bool, error)
+	GetNotifications(userID uint, limit int) ([]models.Notification, error)
+	CountUnread(userID uint) (int, error)
+	MarkRead(userID uint, ids []uint, readAt time.Time) error
+	DeleteNotificationsByUserID(userID uint) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Notification{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository create a new notification repository to fiddle around with database
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// notifications of these actors are left out, because either of them blocked the other or the user muted the actor
+const hiddenActors = "actor_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND " +
+	"actor_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?) AND " +
+	"actor_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = ?)"
+
+// notifications of deactivated actors are left out until the actor comes back or is purged along with them
+const activeActors = "JOIN users ON users.id = notifications.actor_id AND users.deleted_at IS NULL"
+
+// AddNotification creates the notification, an unread notification of the same event replaces the one before so
+// following and unfollowing over and over doesn't pile them up. It returns false without creating the notification
+// when either of them blocked the other or the user muted the actor
+func (r *repo) AddNotification(notification *models.Notification) (bool, error) {
+	added := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var blocks, mutes int
+
+		err := tx.Model(&models.Block{}).Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+			notification.UserID, notification.ActorID, notification.ActorID, notification.UserID).Count(&blocks).Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Mute{}).Where("muter_id = ? AND muted_id = ?", notification.UserID, notification.ActorID).Count(&mutes).Error
+
+		if err != nil || blocks+mutes > 0 {
+			return err
+		}
+
+		err = tx.Where("user_id = ? AND actor_id = ? AND type = ? AND post_id = ? AND read_at IS NULL",
+			notification.UserID, notification.ActorID, notification.Type, notification.PostID).Delete(&models.Notification{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(notification).Error; err != nil {
+			return err
+		}
+
+		added = true
+
+		return nil
+	})
+
+	return added, err
+}
+
+// GetNotifications returns the latest notifications of the user along with their actors, newest first
+func (r *repo) GetNotifications(userID uint, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+
+	err := r.db.Preload("Actor").Select("notifications.*").Joins(activeActors).Where("notifications.user_id = ?", userID).
+		Where(hiddenActors, userID, userID, userID).Order("notifications.id desc").Limit(limit).Find(&notifications).Error
+
+	return notifications, err
+}
+
+// CountUnread returns how many notifications GetNotifications would return that the user didn't read
+func (r *repo) CountUnread(userID uint) (int, error) {
+	var count int
+
+	err := r.db.Model(&models.Notification{}).Joins(activeActors).Where("notifications.user_id = ? AND notifications.read_at IS NULL", userID).
+		Where(hiddenActors, userID, userID, userID).Count(&count).Error
+
+	return count, err
+}
+
+// MarkRead marks the notifications of the user with the ids read, or all of them if there are no ids
+func (r *repo) MarkRead(userID uint, ids []uint, readAt time.Time) error {
+	query := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+
+	if len(ids) > 0 {
+		query = query.Where("id IN (?)", ids)
+	}
+
+	return query.UpdateColumn("read_at", readAt).Error
+}
+
+// DeleteNotificationsByUserID permanently removes the notifications of the user and the ones the user is the actor of
+func (r *repo) DeleteNotificationsByUserID(userID uint) error {
+	return r.db.Where("user_id = ? OR actor_id = ?", userID, userID).Delete(&models.Notification{}).Error
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	notificationRepo NotificationRepository
+	mock             sqlmock.Sqlmock
+	db               *sql.DB
+	gdb              *gorm.DB
+	err              error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	notificationRepo = NewNotificationRepository(gdb)
+}
+
+const sqlHiddenActors = `actor_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $2) AND actor_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $3) AND actor_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = $4)`
+
+const sqlActiveActors = `JOIN users ON users.id = notifications.actor_id AND users.deleted_at IS NULL`
+
+const sqlCountBlocks = `SELECT count(*) FROM "blocks"  WHERE ((blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $3 AND blocked_id = $4))`
+const sqlCountMutes = `SELECT count(*) FROM "mutes"  WHERE (muter_id = $1 AND muted_id = $2)`
+
+func TestAddNotification(t *testing.T) {
+	setup()
+
+	notification := models.Notification{UserID: 2, ActorID: 1, Type: models.NotificationFollow}
+
+	const sqlDelete = `DELETE FROM "notifications"  WHERE (user_id = $1 AND actor_id = $2 AND type = $3 AND post_id = $4 AND read_at IS NULL)`
+	const sqlInsert = `INSERT INTO "notifications" ("user_id","actor_id","type","post_id","read_at","created_at") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "notifications"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCountBlocks)).WithArgs(2, 1, 1, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCountMutes)).WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(2, 1, models.NotificationFollow, 0).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(2, 1, models.NotificationFollow, 0, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	added, err := notificationRepo.AddNotification(&notification)
+
+	assert.Nil(t, err)
+	assert.True(t, added)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(5), notification.ID)
+}
+
+func TestAddNotificationOfMutedActor(t *testing.T) {
+	setup()
+
+	notification := models.Notification{UserID: 2, ActorID: 1, Type: models.NotificationFollow}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCountBlocks)).WithArgs(2, 1, 1, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCountMutes)).WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	added, err := notificationRepo.AddNotification(&notification)
+
+	assert.Nil(t, err)
+	assert.False(t, added)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotifications(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT notifications.* FROM "notifications" ` + sqlActiveActors + ` WHERE (notifications.user_id = $1) AND (` + sqlHiddenActors + `) ORDER BY notifications.id desc LIMIT 50`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(2, 2, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "actor_id", "type"}).AddRow(5, 2, 1, models.NotificationFollow))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users"  WHERE "users"."deleted_at" IS NULL AND (("id" IN ($1)))`)).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "joko"))
+
+	notifications, err := notificationRepo.GetNotifications(2, 50)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "joko", notifications[0].Actor.Username)
+}
+
+func TestCountUnread(t *testing.T) {
+	setup()
+
+	const sqlCount = `SELECT count(*) FROM "notifications" ` + sqlActiveActors + ` WHERE (notifications.user_id = $1 AND notifications.read_at IS NULL) AND (` + sqlHiddenActors + `)`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCount)).WithArgs(2, 2, 2, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := notificationRepo.CountUnread(2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestMarkRead(t *testing.T) {
+	setup()
+
+	readAt := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	const sqlUpdate = `UPDATE "notifications" SET "read_at" = $1 WHERE (user_id = $2 AND read_at IS NULL) AND (id IN ($3,$4))`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(readAt, 2, 5, 6).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := notificationRepo.MarkRead(2, []uint{5, 6}, readAt)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteNotificationsByUserID(t *testing.T) {
+	setup()
+
+	const sqlDelete = `DELETE FROM "notifications"  WHERE (user_id = $1 OR actor_id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	err := notificationRepo.DeleteNotificationsByUserID(2)
+
+	assert.Nil(t, err)
+}
@@ -0,0 +1,324 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ProviderConfig describes an OpenID Connect provider we accept logins from
+type ProviderConfig struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// Claims are the ID token claims used to find or create the local user
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Nonce             string
+}
+
+// Client interface for the authorization code flow with PKCE against one provider
+type Client interface {
+	AuthCodeURL(state string, nonce string, codeChallenge string) (string, error)
+	Exchange(code string, codeVerifier string) (*Claims, error)
+}
+
+// LoadProviders reads the provider configs from the OIDC_PROVIDERS environment variable, a JSON array of ProviderConfig
+func LoadProviders() (map[string]Client, error) {
+	clients := map[string]Client{}
+
+	raw := os.Getenv("OIDC_PROVIDERS")
+
+	if raw == "" {
+		return clients, nil
+	}
+
+	var configs []ProviderConfig
+
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		clients[config.Name] = NewClient(config, http.DefaultClient)
+	}
+
+	return clients, nil
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type client struct {
+	config     ProviderConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// NewClient returns a client that discovers the provider endpoints lazily from its issuer
+func NewClient(config ProviderConfig, httpClient *http.Client) Client {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	withTimeout := *httpClient
+	if withTimeout.Timeout == 0 {
+		withTimeout.Timeout = 10 * time.Second
+	}
+
+	return &client{config: config, httpClient: &withTimeout}
+}
+
+// AuthCodeURL returns the URL of the provider's consent page the user has to be redirected to
+func (c *client) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+	d, err := c.discover()
+
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", c.config.ClientID)
+	values.Set("redirect_uri", c.config.RedirectURL)
+	values.Set("scope", strings.Join(c.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return d.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the verified ID token claims
+func (c *client) Exchange(code string, codeVerifier string) (*Claims, error) {
+	d, err := c.discover()
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", c.config.RedirectURL)
+	values.Set("client_id", c.config.ClientID)
+	values.Set("client_secret", c.config.ClientSecret)
+	values.Set("code_verifier", codeVerifier)
+
+	resp, err := c.httpClient.PostForm(d.TokenEndpoint, values)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint returned %d", resp.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+
+	return c.verify(tokens.IDToken, d.Issuer)
+}
+
+// verify checks the ID token signature against the provider keys and its issuer, audience and expiry
+func (c *client) verify(idToken string, issuer string) (*Claims, error) {
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("oidc: unexpected signing method")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return c.key(kid)
+	})
+
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("oidc: invalid id token")
+	}
+
+	mapClaims := token.Claims.(jwt.MapClaims)
+
+	if !mapClaims.VerifyIssuer(issuer, true) || !hasAudience(mapClaims["aud"], c.config.ClientID) {
+		return nil, fmt.Errorf("oidc: id token was not issued for us")
+	}
+
+	if _, ok := mapClaims["exp"]; !ok {
+		return nil, fmt.Errorf("oidc: id token has no expiry")
+	}
+
+	claims := &Claims{}
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.EmailVerified, _ = mapClaims["email_verified"].(bool)
+	claims.Name, _ = mapClaims["name"].(string)
+	claims.PreferredUsername, _ = mapClaims["preferred_username"].(string)
+	claims.Nonce, _ = mapClaims["nonce"].(string)
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("oidc: id token has no subject")
+	}
+
+	return claims, nil
+}
+
+// hasAudience accepts both forms of the aud claim, a single string or an array of strings
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *client) discover() (*discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	var d discovery
+
+	if err := c.getJSON(strings.TrimRight(c.config.Issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+
+	if d.Issuer != c.config.Issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch %q", d.Issuer)
+	}
+
+	c.discovery = &d
+
+	return c.discovery, nil
+}
+
+// key returns the provider's public key with the id, the key set is fetched again when the id is unknown to follow key rotation
+func (c *client) key(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+
+	if err := c.getJSON(c.discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	c.keys = map[string]*rsa.PublicKey{}
+
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		c.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	key, ok := c.keys[kid]
+
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+func (c *client) getJSON(endpoint string, v interface{}) error {
+	resp, err := c.httpClient.Get(endpoint)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s returned %d", endpoint, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// RandomString returns n random bytes encoded as URL safe base64, suitable for state, nonce and PKCE verifiers
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of the verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
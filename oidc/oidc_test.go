@@ -0,0 +1,79 @@
+package oidc
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"summer-web/oidc/oidctest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(provider *oidctest.Provider) Client {
+	return NewClient(ProviderConfig{
+		Name:         "fake",
+		Issuer:       provider.Issuer(),
+		ClientID:     provider.ClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8000/auth/fake/callback",
+	}, http.DefaultClient)
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	provider := oidctest.NewProvider("summer-web")
+	defer provider.Close()
+
+	client := newTestClient(provider)
+	verifier, _ := RandomString(32)
+
+	authURL, err := client.AuthCodeURL("state123", "nonce123", CodeChallenge(verifier))
+	assert.Nil(t, err)
+
+	parsed, _ := url.Parse(authURL)
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+
+	code, state, err := provider.Authorize(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "state123", state)
+
+	claims, err := client.Exchange(code, verifier)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "fake-subject", claims.Subject)
+	assert.Equal(t, "fake@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "nonce123", claims.Nonce)
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider := oidctest.NewProvider("summer-web")
+	defer provider.Close()
+
+	client := newTestClient(provider)
+	verifier, _ := RandomString(32)
+
+	authURL, _ := client.AuthCodeURL("state123", "nonce123", CodeChallenge(verifier))
+	code, _, _ := provider.Authorize(authURL)
+
+	_, err := client.Exchange(code, "not the verifier")
+
+	assert.NotNil(t, err)
+}
+
+func TestHasAudience(t *testing.T) {
+	assert.True(t, hasAudience("summer-web", "summer-web"))
+	assert.True(t, hasAudience([]interface{}{"other", "summer-web"}, "summer-web"))
+	assert.False(t, hasAudience("other", "summer-web"))
+	assert.False(t, hasAudience(nil, "summer-web"))
+}
+
+func TestLoadProviders(t *testing.T) {
+	os.Setenv("OIDC_PROVIDERS", `[{"name": "google", "issuer": "https://accounts.google.com", "client_id": "id"}]`)
+	defer os.Unsetenv("OIDC_PROVIDERS")
+
+	providers, err := LoadProviders()
+
+	assert.Nil(t, err)
+	assert.Contains(t, providers, "google")
+}
@@ -0,0 +1,212 @@
+// Package oidctest provides a local OpenID Connect provider for tests, in the spirit of net/http/httptest
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// User is the identity the fake provider logs in on its authorization endpoint
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	user          User
+}
+
+// Provider is a fake provider that approves every authorization request for the current user without a consent page
+type Provider struct {
+	Server   *httptest.Server
+	ClientID string
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  User
+	codes map[string]authorization
+}
+
+// NewProvider starts a fake provider that issues ID tokens for clientID
+func NewProvider(clientID string) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		panic(err)
+	}
+
+	p := &Provider{
+		ClientID: clientID,
+		key:      key,
+		codes:    map[string]authorization{},
+		user:     User{Subject: "fake-subject", Email: "fake@example.com", EmailVerified: true, Name: "Fake User", Username: "fake"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// Issuer returns the issuer URL clients have to be configured with
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// SetUser changes who gets logged in by the next authorization request
+func (p *Provider) SetUser(user User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.user = user
+}
+
+// Close shuts the provider down
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Authorize follows the authorization URL like a browser would and returns the code and state the provider redirects back with
+func (p *Provider) Authorize(authURL string) (string, string, error) {
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	defer resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+func (p *Provider) discovery(resp http.ResponseWriter, req *http.Request) {
+	json.NewEncoder(resp).Encode(map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) authorize(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	if query.Get("client_id") != p.ClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(resp, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		clientID:      query.Get("client_id"),
+		redirectURI:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          p.user,
+	}
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(resp, req, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) token(resp http.ResponseWriter, req *http.Request) {
+	code := req.FormValue("code")
+
+	p.mu.Lock()
+	auth, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(req.FormValue("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if !ok || auth.clientID != req.FormValue("client_id") || auth.redirectURI != req.FormValue("redirect_uri") || auth.codeChallenge != challenge {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"error": "invalid_grant"}`))
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.Issuer(),
+		"aud":                p.ClientID,
+		"sub":                auth.user.Subject,
+		"email":              auth.user.Email,
+		"email_verified":     auth.user.EmailVerified,
+		"name":               auth.user.Name,
+		"preferred_username": auth.user.Username,
+		"nonce":              auth.nonce,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	})
+	idToken.Header["kid"] = "fake-key"
+
+	signed, err := idToken.SignedString(p.key)
+
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(resp).Encode(map[string]string{"access_token": randomString(), "token_type": "Bearer", "id_token": signed})
+}
+
+func (p *Provider) jwks(resp http.ResponseWriter, req *http.Request) {
+	json.NewEncoder(resp).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": "fake-key",
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
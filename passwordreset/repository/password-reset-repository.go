@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// PasswordResetRepository is the repository interface for password reset
+type PasswordResetRepository interface {
+	AddPasswordReset(reset *models.PasswordReset) error
+	GetPasswordResetByTokenHash(tokenHash string, reset *models.PasswordReset) error
+	UsePasswordReset(id uint, usedAt time.Time) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.PasswordReset{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository create a new password reset repository to fiddle around with database
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddPasswordReset returns an error if there is any, otherwise creates a new password reset record into database
+func (r *repo) AddPasswordReset(reset *models.PasswordReset) error {
+	return r.db.Create(reset).Error
+}
+
+// GetPasswordResetByTokenHash returns an error if there is any, otherwise modifies the reset parameter with the found record
+func (r *repo) GetPasswordResetByTokenHash(tokenHash string, reset *models.PasswordReset) error {
+	return r.db.Where("token_hash = ?", tokenHash).Find(reset).Error
+}
+
+// UsePasswordReset marks the password reset as used, returns an error if it was already used
+func (r *repo) UsePasswordReset(id uint, usedAt time.Time) error {
+	result := r.db.Model(&models.PasswordReset{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", usedAt)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error: already used \"token\"")
+	}
+
+	return nil
+}
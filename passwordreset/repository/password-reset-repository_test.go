@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	passwordResetRepo PasswordResetRepository
+	mock              sqlmock.Sqlmock
+	db                *sql.DB
+	gdb               *gorm.DB
+	err               error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	passwordResetRepo = NewPasswordResetRepository(gdb)
+}
+
+func TestAddPasswordReset(t *testing.T) {
+	setup()
+
+	reset := models.PasswordReset{UserID: 1, TokenHash: "hash", ExpiresAt: time.Now()}
+	newID := uint(1)
+	const sqlInsert = `INSERT INTO "password_resets" ("user_id","token_hash","expires_at","used_at","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "password_resets"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(reset.UserID, reset.TokenHash, reset.ExpiresAt, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(newID))
+	mock.ExpectCommit()
+
+	err := passwordResetRepo.AddPasswordReset(&reset)
+
+	assert.Nil(t, err)
+	assert.Equal(t, newID, reset.ID)
+}
+
+func TestGetPasswordResetByTokenHash(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "token_hash", "expires_at", "used_at", "created_at"}).
+		AddRow(1, 2, "hash", time.Now(), nil, time.Now())
+
+	const sqlSelect = `SELECT * FROM "password_resets" WHERE (token_hash = $1)`
+
+	reset := models.PasswordReset{}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("hash").WillReturnRows(rows)
+
+	err := passwordResetRepo.GetPasswordResetByTokenHash("hash", &reset)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), reset.UserID)
+}
+
+func TestUsePasswordReset(t *testing.T) {
+	setup()
+
+	usedAt := time.Now()
+	const sqlUpdate = `UPDATE "password_resets" SET "used_at" = $1 WHERE (id = $2 AND used_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(usedAt, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := passwordResetRepo.UsePasswordReset(1, usedAt)
+
+	assert.Nil(t, err)
+}
+
+func TestUsePasswordResetTwice(t *testing.T) {
+	setup()
+
+	usedAt := time.Now()
+	const sqlUpdate = `UPDATE "password_resets" SET "used_at" = $1 WHERE (id = $2 AND used_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(usedAt, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := passwordResetRepo.UsePasswordReset(1, usedAt)
+
+	assert.NotNil(t, err)
+}
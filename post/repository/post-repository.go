@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"summer-web/models"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	// import postgres dialect from gorm lib
@@ -12,8 +13,22 @@ import (
 
 // PostRepository is the repository interface for post
 type PostRepository interface {
-	GetPosts() ([]models.Post, error)
+	GetPosts(viewerID uint) ([]models.Post, error)
+	GetPostsByUserID(userID uint) ([]models.Post, error)
+	GetFeed(viewerID uint) ([]models.Post, error)
+	GetPostsByTag(viewerID uint, tag string, beforeID uint, limit int) ([]models.Post, error)
+	GetPostByID(id uint, post *models.Post) error
 	AddPost(post *models.Post) error
+	UpdatePost(post *models.Post) error
+	GetTagCounts(since time.Time) ([]models.TagCount, error)
+	GetFeedReaderIDs(authorID uint) ([]uint, error)
+	DeletePostsByUserID(userID uint) error
+	GetMediaByKey(key string, media *models.Media) error
+	GetMediaByUserID(userID uint) ([]models.Media, error)
+	GetPendingMedia(limit int) ([]models.Media, error)
+	AddMediaVariants(mediaID uint, variants []models.MediaVariant) error
+	SetMediaStatus(mediaID uint, status string) error
+	CountMediaAttempt(mediaID uint) error
 }
 
 func init() {
@@ -26,7 +41,7 @@ func init() {
 
 	defer db.Close()
 
-	db.AutoMigrate(&models.Post{})
+	db.AutoMigrate(&models.Post{}, &models.Media{}, &models.MediaVariant{}, &models.PostTag{}, &models.PostMention{})
 }
 
 type repo struct {
@@ -47,8 +62,37 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 	return &repo{db: db}
 }
 
-// GetPosts returns all post in database or an error if there is an error
-func (r *repo) GetPosts() ([]models.Post, error) {
+// withAssociations loads what listings show along with the posts
+func (r *repo) withAssociations() *gorm.DB {
+	return r.db.Preload("Author").Preload("Media").Preload("Media.Variants").Preload("Mentions")
+}
+
+// the viewer and the accounts the viewer follows
+const followedAuthorIDs = "user_id = ? OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)"
+
+// posts of deactivated accounts are hidden for the grace period, until the account is restored or purged
+const activeAuthors = "user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)"
+
+// posts of these authors are visible to the viewer besides the public accounts
+const followedAuthors = "(" + followedAuthorIDs + ") AND " + activeAuthors
+
+// posts of these authors are visible to the viewer, that is public accounts and the followed ones
+const visibleAuthors = "(user_id IN (SELECT id FROM users WHERE is_private = false) OR " + followedAuthorIDs + ") AND " + activeAuthors
+
+// posts of these authors are left out of the viewer's feeds, because either of them blocked the other or the viewer
+// muted the author
+const hiddenAuthors = "user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND " +
+	"user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?) AND " +
+	"user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = ?)"
+
+// followers that don't see the posts of the author in their feeds, the other way round of hiddenAuthors
+const hiddenReaders = "follower_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?) AND " +
+	"follower_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND " +
+	"follower_id NOT IN (SELECT muter_id FROM mutes WHERE muted_id = ?)"
+
+// GetPosts returns the posts in database the viewer may see, that is the posts of public accounts, of accounts the
+// viewer was approved to follow and the viewer's own, except those of blocked and muted authors, or an error if there is an error
+func (r *repo) GetPosts(viewerID uint) ([]models.Post, error) {
 	// db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
 	// if err != nil {
 	// 	fmt.Println(err.Error())
@@ -59,7 +103,8 @@ func (r *repo) GetPosts() ([]models.Post, error) {
 
 	var posts []models.Post
 
-	err := r.db.Find(&posts).Error
+	err := r.withAssociations().Where(visibleAuthors, viewerID, viewerID, models.FollowAccepted).
+		Where(hiddenAuthors, viewerID, viewerID, viewerID).Find(&posts).Error
 
 	if err != nil {
 		return nil, err
@@ -68,7 +113,48 @@ func (r *repo) GetPosts() ([]models.Post, error) {
 	return posts, nil
 }
 
-// AddPost adds post into database, returns an error instead if there is an error
+// GetPostsByUserID returns the posts written by the user, newest first
+func (r *repo) GetPostsByUserID(userID uint) ([]models.Post, error) {
+	var posts []models.Post
+
+	err := r.withAssociations().Where("user_id = ?", userID).Order("id desc").Find(&posts).Error
+
+	return posts, err
+}
+
+// GetFeed returns the posts of the viewer and of the accounts the viewer follows but didn't block or mute, newest first
+func (r *repo) GetFeed(viewerID uint) ([]models.Post, error) {
+	var posts []models.Post
+
+	err := r.withAssociations().Where(followedAuthors, viewerID, viewerID, models.FollowAccepted).Where(hiddenAuthors, viewerID, viewerID, viewerID).
+		Order("id desc").Find(&posts).Error
+
+	return posts, err
+}
+
+// GetPostsByTag returns the posts tagged with the tag that the viewer may see, the same posts GetPosts returns. The
+// posts are newest first, up to limit of them and older than the post beforeID unless it is 0
+func (r *repo) GetPostsByTag(viewerID uint, tag string, beforeID uint, limit int) ([]models.Post, error) {
+	var posts []models.Post
+
+	query := r.withAssociations().Where("id IN (SELECT post_id FROM post_tags WHERE tag = ?)", tag).
+		Where(visibleAuthors, viewerID, viewerID, models.FollowAccepted).Where(hiddenAuthors, viewerID, viewerID, viewerID)
+
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	err := query.Order("id desc").Limit(limit).Find(&posts).Error
+
+	return posts, err
+}
+
+// GetPostByID returns an error if there is no post with the id, otherwise modifies the post parameter
+func (r *repo) GetPostByID(id uint, post *models.Post) error {
+	return r.withAssociations().Where("id = ?", id).First(post).Error
+}
+
+// AddPost adds post into database along with its media, returns an error instead if there is an error
 func (r *repo) AddPost(post *models.Post) error {
 	// db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
 	// if err != nil {
@@ -80,3 +166,138 @@ func (r *repo) AddPost(post *models.Post) error {
 
 	return r.db.Create(&post).Error
 }
+
+// UpdatePost saves the caption of the post and replaces its tags and mentions
+func (r *repo) UpdatePost(post *models.Post) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("caption", post.Caption).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.PostTag{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.PostMention{}).Error; err != nil {
+			return err
+		}
+
+		for i := range post.Tags {
+			post.Tags[i].PostID = post.ID
+
+			if err := tx.Create(&post.Tags[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range post.Mentions {
+			post.Mentions[i].PostID = post.ID
+
+			if err := tx.Create(&post.Mentions[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetTagCounts counts the hashtags of the posts made since the time per author and the hour the post was made, editing a
+// post doesn't move its tags. Only the posts of public accounts that are not deleted are counted
+func (r *repo) GetTagCounts(since time.Time) ([]models.TagCount, error) {
+	var counts []models.TagCount
+
+	err := r.db.Table("post_tags").
+		Select("post_tags.tag, posts.user_id, date_trunc('hour', posts.created_at) AS hour, COUNT(*) AS count").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Joins("JOIN users ON users.id = posts.user_id").
+		Where("posts.created_at >= ? AND users.deleted_at IS NULL AND users.is_private = false", since).
+		Group("post_tags.tag, posts.user_id, hour").Scan(&counts).Error
+
+	return counts, err
+}
+
+// GetFeedReaderIDs returns the ids of the followers whose feed shows the posts of the author, the same followers GetFeed
+// doesn't hide the author from
+func (r *repo) GetFeedReaderIDs(authorID uint) ([]uint, error) {
+	var ids []uint
+
+	err := r.db.Model(&models.Follow{}).Where("followee_id = ? AND status = ?", authorID, models.FollowAccepted).
+		Where("followee_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Where(hiddenReaders, authorID, authorID, authorID).Pluck("follower_id", &ids).Error
+
+	return ids, err
+}
+
+// DeletePostsByUserID permanently removes every post written by the user along with the media records, tags and
+// mentions of the posts and the mentions of the user, the blobs are left to the caller
+func (r *repo) DeletePostsByUserID(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id IN (SELECT id FROM posts WHERE user_id = ?)", userID).Delete(&models.PostTag{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("post_id IN (SELECT id FROM posts WHERE user_id = ?) OR user_id = ?", userID, userID).Delete(&models.PostMention{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("media_id IN (SELECT id FROM media WHERE user_id = ?)", userID).Delete(&models.MediaVariant{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Media{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&models.Post{}).Error
+	})
+}
+
+// GetMediaByKey returns an error if neither the media nor one of its variants is stored under the key, otherwise
+// modifies the media parameter
+func (r *repo) GetMediaByKey(key string, media *models.Media) error {
+	return r.db.Preload("Variants").Where("key = ? OR id IN (SELECT media_id FROM media_variants WHERE key = ?)", key, key).First(media).Error
+}
+
+// GetMediaByUserID returns the media of every post written by the user along with their variants
+func (r *repo) GetMediaByUserID(userID uint) ([]models.Media, error) {
+	var media []models.Media
+
+	err := r.db.Preload("Variants").Where("user_id = ?", userID).Find(&media).Error
+
+	return media, err
+}
+
+// GetPendingMedia returns up to limit media whose variants are still to be generated, oldest first
+func (r *repo) GetPendingMedia(limit int) ([]models.Media, error) {
+	var media []models.Media
+
+	err := r.db.Where("status = ?", models.MediaProcessing).Order("id").Limit(limit).Find(&media).Error
+
+	return media, err
+}
+
+// AddMediaVariants adds the generated variants of the media and marks it ready, in a single transaction
+func (r *repo) AddMediaVariants(mediaID uint, variants []models.MediaVariant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range variants {
+			variants[i].MediaID = mediaID
+
+			if err := tx.Create(&variants[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Media{}).Where("id = ?", mediaID).UpdateColumn("status", models.MediaReady).Error
+	})
+}
+
+// SetMediaStatus updates the processing status of the media
+func (r *repo) SetMediaStatus(mediaID uint, status string) error {
+	return r.db.Model(&models.Media{}).Where("id = ?", mediaID).UpdateColumn("status", status).Error
+}
+
+// CountMediaAttempt counts a failed attempt of generating the variants of the media
+func (r *repo) CountMediaAttempt(mediaID uint) error {
+	return r.db.Model(&models.Media{}).Where("id = ?", mediaID).UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+}
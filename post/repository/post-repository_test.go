@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"summer-web/models"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jinzhu/gorm"
@@ -36,18 +37,77 @@ func setup() {
 	postRepo = NewPostRepository(gdb)
 }
 
+const sqlSelectMedia = `SELECT * FROM "media"  WHERE ("post_id" IN `
+const sqlSelectAuthors = `SELECT * FROM "users"  WHERE "users"."deleted_at" IS NULL AND (("id" IN `
+const sqlSelectVariants = `SELECT * FROM "media_variants"  WHERE ("media_id" IN `
+const sqlSelectMentions = `SELECT * FROM "post_mentions"  WHERE ("post_id" IN `
+
 func TestGetPosts(t *testing.T) {
 	setup()
 
 	rows := sqlmock.NewRows([]string{"id", "caption", "user_id"}).AddRow(1, "hello1", 1).AddRow(2, "hello2", 2)
 
-	const sqlSelectAll = `SELECT * FROM "posts"`
+	const sqlSelectAll = `SELECT * FROM "posts" WHERE ((user_id IN (SELECT id FROM users WHERE is_private = false) OR user_id = $1 OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = $2 AND status = $3)) AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)) AND (user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $4) AND user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $5) AND user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = $6))`
 
-	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAll)).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAll)).WithArgs(1, 1, models.FollowAccepted, 1, 1, 1).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAuthors+`($1,$2)))`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "joko").AddRow(2, "budi"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMedia+`($1,$2))`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "key", "content_type"}).AddRow(1, 2, "posts/2/a.jpg", "image/jpeg"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectVariants + `($1))`)).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "media_id", "name", "key"}).AddRow(5, 1, "thumbnail", "posts/2/a_thumbnail.jpg"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMentions+`($1,$2))`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "user_id", "username"}).AddRow(1, 1, 2, "budi"))
 
-	posts, err := postRepo.GetPosts()
+	posts, err := postRepo.GetPosts(1)
 
 	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 2, len(posts))
+	assert.Equal(t, 0, len(posts[0].Media))
+	assert.Equal(t, "posts/2/a.jpg", posts[1].Media[0].Key)
+	assert.Equal(t, "thumbnail", posts[1].Media[0].Variants[0].Name)
+	assert.Equal(t, "budi", posts[1].Author.Username)
+	assert.Equal(t, "budi", posts[0].Mentions[0].Username)
+}
+
+func TestGetPostsByUserID(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "caption", "user_id"}).AddRow(2, "hello2", 2)
+
+	const sqlSelect = `SELECT * FROM "posts" WHERE (user_id = $1) ORDER BY id desc`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(2).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAuthors + `($1)))`)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "budi"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMedia + `($1))`)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMentions + `($1))`)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	posts, err := postRepo.GetPostsByUserID(2)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(posts))
+}
+
+func TestGetFeed(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "caption", "user_id"}).AddRow(2, "hello2", 2).AddRow(1, "hello1", 1)
+
+	const sqlSelect = `SELECT * FROM "posts" WHERE ((user_id = $1 OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = $2 AND status = $3)) AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)) AND (user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $4) AND user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $5) AND user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = $6)) ORDER BY id desc`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1, 1, models.FollowAccepted, 1, 1, 1).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAuthors+`($1,$2)))`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "joko").AddRow(2, "budi"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMedia+`($1,$2))`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMentions+`($1,$2))`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	posts, err := postRepo.GetFeed(1)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "joko", posts[1].Author.Username)
 	assert.Equal(t, 2, len(posts))
 }
 
@@ -55,11 +115,11 @@ func TestAddPost(t *testing.T) {
 	setup()
 
 	post := models.Post{Caption: "123", UserID: 1}
-	const sqlInsert = `INSERT INTO "posts" ("caption","user_id") VALUES ($1,$2) RETURNING "posts"."id"`
+	const sqlInsert = `INSERT INTO "posts" ("caption","user_id","created_at") VALUES ($1,$2,$3) RETURNING "posts"."id"`
 	newID := uint(1)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(post.Caption, post.UserID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(newID))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(post.Caption, post.UserID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(newID))
 	mock.ExpectCommit()
 
 	assert.Equal(t, uint(0), post.ID)
@@ -69,3 +129,240 @@ func TestAddPost(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, newID, post.ID)
 }
+
+func TestDeletePostsByUserID(t *testing.T) {
+	setup()
+
+	const sqlDeleteTags = `DELETE FROM "post_tags"  WHERE (post_id IN (SELECT id FROM posts WHERE user_id = $1))`
+	const sqlDeleteMentions = `DELETE FROM "post_mentions"  WHERE (post_id IN (SELECT id FROM posts WHERE user_id = $1) OR user_id = $2)`
+	const sqlDeleteVariants = `DELETE FROM "media_variants"  WHERE (media_id IN (SELECT id FROM media WHERE user_id = $1))`
+	const sqlDeleteMedia = `DELETE FROM "media"  WHERE (user_id = $1)`
+	const sqlDelete = `DELETE FROM "posts"  WHERE (user_id = $1)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteTags)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteMentions)).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteVariants)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteMedia)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := postRepo.DeletePostsByUserID(1)
+
+	assert.Nil(t, err)
+}
+
+func TestAddPostWithMedia(t *testing.T) {
+	setup()
+
+	post := models.Post{Caption: "123", UserID: 1, Media: []models.Media{{UserID: 1, Key: "posts/1/a.jpg", ContentType: "image/jpeg", Size: 4, Width: 2, Height: 1, Status: models.MediaProcessing}}}
+	const sqlInsert = `INSERT INTO "posts" ("caption","user_id","created_at") VALUES ($1,$2,$3) RETURNING "posts"."id"`
+	const sqlInsertMedia = `INSERT INTO "media" ("post_id","user_id","key","content_type","size","width","height","status","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING "media"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(post.Caption, post.UserID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsertMedia)).WithArgs(7, 1, "posts/1/a.jpg", "image/jpeg", 4, 2, 1, models.MediaProcessing, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	err := postRepo.AddPost(&post)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(7), post.Media[0].PostID)
+}
+
+func TestAddPostWithTags(t *testing.T) {
+	setup()
+
+	post := models.Post{Caption: "#go @budi", UserID: 1, Tags: []models.PostTag{{Tag: "go"}}, Mentions: []models.PostMention{{UserID: 2, Username: "budi"}}}
+	const sqlInsert = `INSERT INTO "posts" ("caption","user_id","created_at") VALUES ($1,$2,$3) RETURNING "posts"."id"`
+	const sqlInsertTag = `INSERT INTO "post_tags" ("post_id","tag","created_at") VALUES ($1,$2,$3) RETURNING "post_tags"."id"`
+	const sqlInsertMention = `INSERT INTO "post_mentions" ("post_id","user_id","username") VALUES ($1,$2,$3) RETURNING "post_mentions"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(post.Caption, post.UserID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsertTag)).WithArgs(7, "go", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsertMention)).WithArgs(7, 2, "budi").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := postRepo.AddPost(&post)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(7), post.Tags[0].PostID)
+	assert.Equal(t, uint(7), post.Mentions[0].PostID)
+}
+
+func TestGetPostsByTag(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "caption", "user_id"}).AddRow(4, "#go", 2)
+
+	const sqlSelect = `SELECT * FROM "posts" WHERE (id IN (SELECT post_id FROM post_tags WHERE tag = $1)) AND ((user_id IN (SELECT id FROM users WHERE is_private = false) OR user_id = $2 OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = $3 AND status = $4)) AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)) AND (user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $5) AND user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $6) AND user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = $7)) AND (id < $8) ORDER BY id desc LIMIT 20`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("go", 1, 1, models.FollowAccepted, 1, 1, 1, 5).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAuthors + `($1)))`)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "budi"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMedia + `($1))`)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMentions + `($1))`)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	posts, err := postRepo.GetPostsByTag(1, "go", 5, 20)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(posts))
+}
+
+func TestGetPostByID(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "posts"  WHERE (id = $1) ORDER BY "posts"."id" ASC LIMIT 1`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id", "caption", "user_id"}).AddRow(4, "#go", 2))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAuthors + `($1)))`)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "budi"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMedia + `($1))`)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectMentions + `($1))`)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var post models.Post
+
+	err := postRepo.GetPostByID(4, &post)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), post.UserID)
+}
+
+func TestUpdatePost(t *testing.T) {
+	setup()
+
+	post := models.Post{ID: 4, Caption: "#rust", Tags: []models.PostTag{{Tag: "rust"}}}
+
+	const sqlUpdate = `UPDATE "posts" SET "caption" = $1 WHERE (id = $2)`
+	const sqlDeleteTags = `DELETE FROM "post_tags"  WHERE (post_id = $1)`
+	const sqlDeleteMentions = `DELETE FROM "post_mentions"  WHERE (post_id = $1)`
+	const sqlInsertTag = `INSERT INTO "post_tags" ("post_id","tag","created_at") VALUES ($1,$2,$3) RETURNING "post_tags"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs("#rust", 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteTags)).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteMentions)).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsertTag)).WithArgs(4, "rust", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	err := postRepo.UpdatePost(&post)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTagCounts(t *testing.T) {
+	setup()
+
+	since := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	hour := since.Add(time.Hour)
+
+	const sqlSelect = `SELECT post_tags.tag, posts.user_id, date_trunc('hour', posts.created_at) AS hour, COUNT(*) AS count FROM "post_tags" JOIN posts ON posts.id = post_tags.post_id JOIN users ON users.id = posts.user_id WHERE (posts.created_at >= $1 AND users.deleted_at IS NULL AND users.is_private = false) GROUP BY post_tags.tag, posts.user_id, hour`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(since).
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "user_id", "hour", "count"}).AddRow("go", 2, hour, 3))
+
+	counts, err := postRepo.GetTagCounts(since)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []models.TagCount{{Tag: "go", UserID: 2, Hour: hour, Count: 3}}, counts)
+}
+
+func TestGetFeedReaderIDs(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT follower_id FROM "follows"  WHERE (followee_id = $1 AND status = $2) AND (followee_id IN (SELECT id FROM users WHERE deleted_at IS NULL)) AND (follower_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $3) AND follower_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $4) AND follower_id NOT IN (SELECT muter_id FROM mutes WHERE muted_id = $5))`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(2, models.FollowAccepted, 2, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id"}).AddRow(3).AddRow(5))
+
+	ids, err := postRepo.GetFeedReaderIDs(2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{3, 5}, ids)
+}
+
+func TestGetMediaByKey(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "media"  WHERE (key = $1 OR id IN (SELECT media_id FROM media_variants WHERE key = $2)) ORDER BY "media"."id" ASC LIMIT 1`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("posts/1/a_medium.jpg", "posts/1/a_medium.jpg").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "key", "content_type"}).AddRow(3, 7, "posts/1/a.jpg", "image/jpeg"))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectVariants + `($1))`)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "media_id", "name", "key"}).AddRow(5, 3, "medium", "posts/1/a_medium.jpg"))
+
+	var media models.Media
+
+	err := postRepo.GetMediaByKey("posts/1/a_medium.jpg", &media)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(7), media.PostID)
+	assert.Equal(t, "medium", media.Variants[0].Name)
+}
+
+func TestGetPendingMedia(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "media"  WHERE (status = $1) ORDER BY "id" LIMIT 10`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(models.MediaProcessing).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "status"}).AddRow(3, "posts/1/a.jpg", models.MediaProcessing))
+
+	media, err := postRepo.GetPendingMedia(10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(media))
+}
+
+func TestAddMediaVariants(t *testing.T) {
+	setup()
+
+	variants := []models.MediaVariant{{Name: "thumbnail", Key: "posts/1/a_thumbnail.jpg", ContentType: "image/jpeg", Size: 4, Width: 2, Height: 1}}
+
+	const sqlInsert = `INSERT INTO "media_variants" ("media_id","name","key","content_type","size","width","height") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "media_variants"."id"`
+	const sqlUpdate = `UPDATE "media" SET "status" = $1 WHERE (id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(3, "thumbnail", "posts/1/a_thumbnail.jpg", "image/jpeg", 4, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(models.MediaReady, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := postRepo.AddMediaVariants(3, variants)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(3), variants[0].MediaID)
+}
+
+func TestSetMediaStatus(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "media" SET "status" = $1 WHERE (id = $2)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(models.MediaFailed, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := postRepo.SetMediaStatus(3, models.MediaFailed)
+
+	assert.Nil(t, err)
+}
+
+func TestCountMediaAttempt(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "media" SET "attempts" = attempts + 1 WHERE (id = $1)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := postRepo.CountMediaAttempt(3)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
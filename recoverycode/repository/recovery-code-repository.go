@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// RecoveryCodeRepository is the repository interface for recovery code
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error
+	UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.RecoveryCode{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository create a new recovery code repository to fiddle around with database
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// ReplaceRecoveryCodes deletes the user's previous recovery codes and stores the new ones in a single transaction
+func (r *repo) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		for i := range codes {
+			codes[i].UserID = userID
+			if err := tx.Create(&codes[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UseRecoveryCode marks the matching unused code as used, returns an error if there is none
+func (r *repo) UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) error {
+	result := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).Update("used_at", usedAt)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error: invalid \"code\"")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	recoveryCodeRepo RecoveryCodeRepository
+	mock             sqlmock.Sqlmock
+	db               *sql.DB
+	gdb              *gorm.DB
+	err              error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	recoveryCodeRepo = NewRecoveryCodeRepository(gdb)
+}
+
+func TestReplaceRecoveryCodes(t *testing.T) {
+	setup()
+
+	codes := []models.RecoveryCode{{CodeHash: "hash1"}, {CodeHash: "hash2"}}
+	const sqlDelete = `DELETE FROM "recovery_codes"  WHERE (user_id = $1)`
+	const sqlInsert = `INSERT INTO "recovery_codes" ("user_id","code_hash","used_at","created_at") VALUES ($1,$2,$3,$4) RETURNING "recovery_codes"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, "hash1", nil, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, "hash2", nil, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	err := recoveryCodeRepo.ReplaceRecoveryCodes(1, codes)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(2), codes[1].ID)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	setup()
+
+	usedAt := time.Now()
+	const sqlUpdate = `UPDATE "recovery_codes" SET "used_at" = $1 WHERE (user_id = $2 AND code_hash = $3 AND used_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(usedAt, 1, "hash").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := recoveryCodeRepo.UseRecoveryCode(1, "hash", usedAt)
+
+	assert.Nil(t, err)
+}
+
+func TestUseRecoveryCodeUnknown(t *testing.T) {
+	setup()
+
+	usedAt := time.Now()
+	const sqlUpdate = `UPDATE "recovery_codes" SET "used_at" = $1 WHERE (user_id = $2 AND code_hash = $3 AND used_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(usedAt, 1, "hash").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := recoveryCodeRepo.UseRecoveryCode(1, "hash", usedAt)
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"summer-web/models"
+)
+
+type memoryRepo struct {
+	posts []models.Post
+	users []models.User
+}
+
+// NewMemorySearchRepository creates a search repository over the posts and users in memory, for tests. It knows
+// nothing of follows, blocks and mutes, so every post of a public account or of the viewer is visible. Words match
+// whole, like Postgres does with the simple configuration, and ranking counts the words that match
+func NewMemorySearchRepository(posts []models.Post, users []models.User) SearchRepository {
+	return &memoryRepo{posts: posts, users: users}
+}
+
+// SearchPosts returns the posts whose caption has every word of the query, ranked like SearchRepository says
+func (r *memoryRepo) SearchPosts(viewerID uint, query string, offset int, limit int) ([]models.Post, error) {
+	private := map[uint]bool{}
+	deleted := map[uint]bool{}
+
+	for _, user := range r.users {
+		private[user.ID] = user.IsPrivate
+		deleted[user.ID] = user.DeletedAt != nil
+	}
+
+	terms := words(query)
+	ranks := map[uint]int{}
+	posts := []models.Post{}
+
+	for _, post := range r.posts {
+		if (private[post.UserID] && post.UserID != viewerID) || deleted[post.UserID] {
+			continue
+		}
+
+		if rank := matches(words(post.Caption), terms); rank > 0 {
+			ranks[post.ID] = rank
+			posts = append(posts, post)
+		}
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		if ranks[posts[i].ID] != ranks[posts[j].ID] {
+			return ranks[posts[i].ID] > ranks[posts[j].ID]
+		}
+		return posts[i].ID > posts[j].ID
+	})
+
+	start, end := bounds(len(posts), offset, limit)
+
+	return posts[start:end], nil
+}
+
+// SearchUsers returns the users matching the query like SearchRepository says
+func (r *memoryRepo) SearchUsers(viewerID uint, query string, offset int, limit int) ([]models.User, error) {
+	username := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	terms := words(query)
+	ranks := map[uint]int{}
+	users := []models.User{}
+
+	for _, user := range r.users {
+		if user.DeletedAt != nil {
+			continue
+		}
+
+		rank := matches(words(user.Username+" "+user.Name+" "+user.Bio), terms)
+
+		switch {
+		case strings.ToLower(user.Username) == username:
+			rank += 2000
+		case username != "" && strings.HasPrefix(strings.ToLower(user.Username), username):
+			rank += 1000
+		}
+
+		if rank > 0 {
+			ranks[user.ID] = rank
+			users = append(users, user)
+		}
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		if ranks[users[i].ID] != ranks[users[j].ID] {
+			return ranks[users[i].ID] > ranks[users[j].ID]
+		}
+		return users[i].ID < users[j].ID
+	})
+
+	start, end := bounds(len(users), offset, limit)
+
+	return users[start:end], nil
+}
+
+// words splits the text into lowercased words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matches returns how many times the terms occur in the words, or 0 if any term doesn't
+func matches(words []string, terms []string) int {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	count := 0
+
+	for _, term := range terms {
+		found := 0
+
+		for _, word := range words {
+			if word == term {
+				found++
+			}
+		}
+
+		if found == 0 {
+			return 0
+		}
+
+		count += found
+	}
+
+	return count
+}
+
+// bounds returns the start and end of the page in a slice of length n
+func bounds(n int, offset int, limit int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+
+	end := offset + limit
+
+	if end > n {
+		end = n
+	}
+
+	return offset, end
+}
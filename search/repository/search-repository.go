@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// SearchRepository is the repository interface for search, results are ranked best match first
+type SearchRepository interface {
+	SearchPosts(viewerID uint, query string, offset int, limit int) ([]models.Post, error)
+	SearchUsers(viewerID uint, query string, offset int, limit int) ([]models.User, error)
+}
+
+// migrations add the search_vector columns that are kept up to date by Postgres and their GIN indexes. The simple
+// configuration doesn't stem words, captions and names are not all written in one language
+var migrations = []string{
+	`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('simple', coalesce(caption, ''))) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (setweight(to_tsvector('simple', coalesce(username, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(bio, '')), 'B')) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_username_prefix ON users (LOWER(username) text_pattern_ops)`,
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Post{}, &models.User{})
+
+	for _, migration := range migrations {
+		if err := db.Exec(migration).Error; err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository create a new search repository to fiddle around with database
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// tsQuery turns what users type in a search box into a tsquery, quoted phrases and -words are understood
+const tsQuery = "websearch_to_tsquery('simple', ?)"
+
+// posts of public accounts, the viewer and the followed accounts that neither blocked the other nor are muted nor
+// deactivated, the same posts the listings of the post repository show
+const visiblePosts = "(user_id IN (SELECT id FROM users WHERE is_private = false) OR user_id = ? OR " +
+	"user_id IN (SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)) AND " +
+	"user_id IN (SELECT id FROM users WHERE deleted_at IS NULL) AND " +
+	"user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND " +
+	"user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?) AND " +
+	"user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = ?)"
+
+// users that neither blocked the viewer nor were blocked by them
+const visibleUsers = "id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND " +
+	"id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?)"
+
+// SearchPosts returns the posts the viewer may see whose caption matches the query, ranked by how well they match
+// and newest first among equals
+func (r *repo) SearchPosts(viewerID uint, query string, offset int, limit int) ([]models.Post, error) {
+	var posts []models.Post
+
+	err := r.db.Preload("Author").Preload("Media").Preload("Media.Variants").Preload("Mentions").
+		Where("search_vector @@ "+tsQuery, query).
+		Where(visiblePosts, viewerID, viewerID, models.FollowAccepted, viewerID, viewerID, viewerID).
+		Order(gorm.Expr("ts_rank(search_vector, "+tsQuery+") DESC", query)).Order("id desc").
+		Offset(offset).Limit(limit).Find(&posts).Error
+
+	return posts, err
+}
+
+// SearchUsers returns the users whose username, name or bio match the query or whose username starts with it. The
+// username written exactly comes first, then usernames starting with the query, then the rest by rank
+func (r *repo) SearchUsers(viewerID uint, query string, offset int, limit int) ([]models.User, error) {
+	var users []models.User
+
+	username := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	prefix := escapeLike(username) + "%"
+
+	err := r.db.Where("search_vector @@ "+tsQuery+" OR LOWER(username) LIKE ?", query, prefix).
+		Where(visibleUsers, viewerID, viewerID).
+		Order(gorm.Expr("LOWER(username) = ? DESC", username)).
+		Order(gorm.Expr("LOWER(username) LIKE ? DESC", prefix)).
+		Order(gorm.Expr("ts_rank(search_vector, "+tsQuery+") DESC", query)).Order("id").
+		Offset(offset).Limit(limit).Find(&users).Error
+
+	return users, err
+}
+
+// escapeLike escapes the wildcards of LIKE patterns so they match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	searchRepo SearchRepository
+	mock       sqlmock.Sqlmock
+	db         *sql.DB
+	gdb        *gorm.DB
+	err        error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	searchRepo = NewSearchRepository(gdb)
+}
+
+func TestSearchPosts(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "posts" WHERE (search_vector @@ websearch_to_tsquery('simple', $1)) AND ((user_id IN (SELECT id FROM users WHERE is_private = false) OR user_id = $2 OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = $3 AND status = $4)) AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL) AND user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $5) AND user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $6) AND user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = $7)) ORDER BY ts_rank(search_vector, websearch_to_tsquery('simple', $8)) DESC,id desc LIMIT 20 OFFSET 40`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("summer go", 1, 1, models.FollowAccepted, 1, 1, 1, "summer go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "caption", "user_id"}).AddRow(4, "summer #go", 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users"`)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "budi"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "media"`)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_mentions"`)).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	posts, err := searchRepo.SearchPosts(1, "summer go", 40, 20)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(posts))
+	assert.Equal(t, "budi", posts[0].Author.Username)
+}
+
+func TestSearchUsers(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "users"  WHERE "users"."deleted_at" IS NULL AND ((search_vector @@ websearch_to_tsquery('simple', $1) OR LOWER(username) LIKE $2) AND (id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $3) AND id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $4))) ORDER BY LOWER(username) = $5 DESC,LOWER(username) LIKE $6 DESC,ts_rank(search_vector, websearch_to_tsquery('simple', $7)) DESC,"id" LIMIT 20 OFFSET 0`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("@Jo_ko", `jo\_ko%`, 1, 1, "jo_ko", `jo\_ko%`, "@Jo_ko").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "jo_ko"))
+
+	users, err := searchRepo.SearchUsers(1, "@Jo_ko", 0, 20)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "jo_ko", users[0].Username)
+}
+
+func TestMemorySearchPosts(t *testing.T) {
+	users := []models.User{{ID: 1, Username: "joko"}, {ID: 2, Username: "budi", IsPrivate: true}}
+	posts := []models.Post{
+		{ID: 1, UserID: 1, Caption: "summer in the city"},
+		{ID: 2, UserID: 1, Caption: "Summer, summer, summer"},
+		{ID: 3, UserID: 2, Caption: "a private summer"},
+		{ID: 4, UserID: 1, Caption: "winter"},
+		{ID: 5, UserID: 1, Caption: "#summer"},
+	}
+
+	repo := NewMemorySearchRepository(posts, users)
+
+	found, err := repo.SearchPosts(1, "SUMMER", 0, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{2, 5, 1}, postIDs(found))
+
+	found, _ = repo.SearchPosts(2, "summer", 1, 2)
+
+	assert.Equal(t, []uint{5, 3}, postIDs(found))
+
+	found, _ = repo.SearchPosts(1, "summer city", 0, 10)
+
+	assert.Equal(t, []uint{1}, postIDs(found))
+
+	found, _ = repo.SearchPosts(1, "summer", 10, 10)
+
+	assert.Equal(t, 0, len(found))
+}
+
+func TestMemorySearchUsers(t *testing.T) {
+	users := []models.User{
+		{ID: 1, Username: "jokowi", Name: "Joko"},
+		{ID: 2, Username: "joko", Name: "Joko Widodo"},
+		{ID: 3, Username: "budi", Name: "Budi", Bio: "friend of joko"},
+		{ID: 4, Username: "andi", Name: "Andi"},
+	}
+
+	repo := NewMemorySearchRepository(nil, users)
+
+	found, err := repo.SearchUsers(1, "@joko", 0, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{2, 1, 3}, userIDs(found))
+
+	found, _ = repo.SearchUsers(1, "jok", 0, 10)
+
+	assert.Equal(t, []uint{1, 2}, userIDs(found))
+}
+
+func postIDs(posts []models.Post) []uint {
+	ids := []uint{}
+
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	return ids
+}
+
+func userIDs(users []models.User) []uint {
+	ids := []uint{}
+
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// SessionRepository is the repository interface for session
+type SessionRepository interface {
+	AddSession(session *models.Session) error
+	GetSessionByID(id uint, session *models.Session) error
+	GetActiveSessionsByUserID(userID uint, now time.Time) ([]models.Session, error)
+	TouchSession(id uint, lastSeenAt time.Time) error
+	RevokeSession(id uint, userID uint, revokedAt time.Time) error
+	RevokeAllSessions(userID uint, revokedAt time.Time) error
+	DeleteSessionsExpiredBefore(deadline time.Time) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Session{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository create a new session repository to fiddle around with database
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddSession returns an error if there is any, otherwise creates a new session record into database
+func (r *repo) AddSession(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// GetSessionByID returns an error if there is any, otherwise modifies the session parameter with the found record
+func (r *repo) GetSessionByID(id uint, session *models.Session) error {
+	return r.db.Where("id = ?", id).First(session).Error
+}
+
+// GetActiveSessionsByUserID returns the sessions that are neither revoked nor expired, most recently seen first
+func (r *repo) GetActiveSessionsByUserID(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).Order("last_seen_at desc").Find(&sessions).Error
+
+	return sessions, err
+}
+
+// TouchSession records when the session was last used
+func (r *repo) TouchSession(id uint, lastSeenAt time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", lastSeenAt).Error
+}
+
+// RevokeSession revokes one of the user's sessions, returns an error if the user has no such active session
+func (r *repo) RevokeSession(id uint, userID uint, revokedAt time.Time) error {
+	result := r.db.Model(&models.Session{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).Update("revoked_at", revokedAt)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error: not found \"sessions_id_key\"")
+	}
+
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere
+func (r *repo) RevokeAllSessions(userID uint, revokedAt time.Time) error {
+	return r.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", revokedAt).Error
+}
+
+// DeleteSessionsExpiredBefore removes the sessions whose tokens can't be used anymore
+func (r *repo) DeleteSessionsExpiredBefore(deadline time.Time) error {
+	return r.db.Where("expires_at < ?", deadline).Delete(&models.Session{}).Error
+}
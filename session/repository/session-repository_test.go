@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	sessionRepo SessionRepository
+	mock        sqlmock.Sqlmock
+	db          *sql.DB
+	gdb         *gorm.DB
+	err         error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	sessionRepo = NewSessionRepository(gdb)
+}
+
+func TestAddSession(t *testing.T) {
+	setup()
+
+	now := time.Now()
+	session := models.Session{UserID: 1, UserAgent: "curl/7.68.0", IP: "127.0.0.1", LastSeenAt: now, ExpiresAt: now.Add(time.Minute)}
+	const sqlInsert = `INSERT INTO "sessions" ("user_id","user_agent","ip","created_at","last_seen_at","expires_at","revoked_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "sessions"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, "curl/7.68.0", "127.0.0.1", sqlmock.AnyArg(), now, session.ExpiresAt, nil).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectCommit()
+
+	err := sessionRepo.AddSession(&session)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(4), session.ID)
+}
+
+func TestGetActiveSessionsByUserID(t *testing.T) {
+	setup()
+
+	now := time.Now()
+	const sqlSelect = `SELECT * FROM "sessions" WHERE (user_id = $1 AND revoked_at IS NULL AND expires_at > $2) ORDER BY last_seen_at desc`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1, now).WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "ip"}).AddRow(2, 1, "127.0.0.1"))
+
+	sessions, err := sessionRepo.GetActiveSessionsByUserID(1, now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, "127.0.0.1", sessions[0].IP)
+}
+
+func TestRevokeSession(t *testing.T) {
+	setup()
+
+	now := time.Now()
+	const sqlUpdate = `UPDATE "sessions" SET "revoked_at" = $1 WHERE (id = $2 AND user_id = $3 AND revoked_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(now, 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := sessionRepo.RevokeSession(2, 1, now)
+
+	assert.Nil(t, err)
+}
+
+func TestRevokeSessionOfOtherUser(t *testing.T) {
+	setup()
+
+	now := time.Now()
+	const sqlUpdate = `UPDATE "sessions" SET "revoked_at" = $1 WHERE (id = $2 AND user_id = $3 AND revoked_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(now, 2, 9).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := sessionRepo.RevokeSession(2, 9, now)
+
+	assert.Equal(t, "error: not found \"sessions_id_key\"", err.Error())
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	setup()
+
+	now := time.Now()
+	const sqlUpdate = `UPDATE "sessions" SET "revoked_at" = $1 WHERE (user_id = $2 AND revoked_at IS NULL)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(now, 1).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err := sessionRepo.RevokeAllSessions(1, now)
+
+	assert.Nil(t, err)
+}
+
+func TestDeleteSessionsExpiredBefore(t *testing.T) {
+	setup()
+
+	now := time.Now()
+	const sqlDelete = `DELETE FROM "sessions"  WHERE (expires_at < $1)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err := sessionRepo.DeleteSessionsExpiredBefore(now)
+
+	assert.Nil(t, err)
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"sync"
+)
+
+// Event types pushed to connected users
+const (
+	EventNotification = "notification"
+	EventPost         = "post"
+)
+
+// DefaultBufferSize is how many events may wait for a slow connection before it is dropped
+const DefaultBufferSize = 32
+
+// Event is something pushed to a connected user, Data is sent as JSON
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Subscription is one connection of a user. Events is closed when the subscription ends, either because it was
+// unsubscribed or because the connection fell too far behind
+type Subscription struct {
+	UserID uint
+	Events <-chan Event
+	events chan Event
+}
+
+// Hub interface fans events out to the connections of the users they are meant for
+type Hub interface {
+	Subscribe(userID uint) *Subscription
+	Unsubscribe(subscription *Subscription)
+	Publish(event Event, userIDs ...uint)
+	Connected() int
+}
+
+type hub struct {
+	mu            sync.Mutex
+	bufferSize    int
+	subscriptions map[uint]map[*Subscription]struct{}
+	count         int
+}
+
+// NewHub returns a hub that buffers up to bufferSize events per connection
+func NewHub(bufferSize int) Hub {
+	return &hub{bufferSize: bufferSize, subscriptions: map[uint]map[*Subscription]struct{}{}}
+}
+
+// Subscribe opens a new connection for the user, a user may have many
+func (h *hub) Subscribe(userID uint) *Subscription {
+	events := make(chan Event, h.bufferSize)
+	subscription := &Subscription{UserID: userID, Events: events, events: events}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscriptions[userID] == nil {
+		h.subscriptions[userID] = map[*Subscription]struct{}{}
+	}
+
+	h.subscriptions[userID][subscription] = struct{}{}
+	h.count++
+
+	return subscription
+}
+
+// Unsubscribe ends the subscription, it is fine to unsubscribe a dropped subscription
+func (h *hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(subscription)
+}
+
+// Publish queues the event on every connection of the users without waiting, connections whose buffer is full are
+// dropped so that one slow client doesn't hold back the others
+func (h *hub) Publish(event Event, userIDs ...uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, userID := range userIDs {
+		for subscription := range h.subscriptions[userID] {
+			select {
+			case subscription.events <- event:
+			default:
+				h.remove(subscription)
+			}
+		}
+	}
+}
+
+// Connected returns how many connections are open
+func (h *hub) Connected() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count
+}
+
+func (h *hub) remove(subscription *Subscription) {
+	subscriptions := h.subscriptions[subscription.UserID]
+
+	if _, ok := subscriptions[subscription]; !ok {
+		return
+	}
+
+	delete(subscriptions, subscription)
+	close(subscription.events)
+	h.count--
+
+	if len(subscriptions) == 0 {
+		delete(h.subscriptions, subscription.UserID)
+	}
+}
@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub(DefaultBufferSize)
+
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+
+	hub.Publish(Event{Type: EventPost, Data: "hello"}, 1, 3)
+
+	assert.Equal(t, Event{Type: EventPost, Data: "hello"}, <-first.Events)
+	assert.Equal(t, Event{Type: EventPost, Data: "hello"}, <-second.Events)
+	assert.Equal(t, 0, len(other.Events))
+	assert.Equal(t, 3, hub.Connected())
+}
+
+func TestUnsubscribe(t *testing.T) {
+	hub := NewHub(DefaultBufferSize)
+
+	subscription := hub.Subscribe(1)
+	hub.Unsubscribe(subscription)
+	hub.Unsubscribe(subscription)
+
+	_, open := <-subscription.Events
+
+	assert.False(t, open)
+	assert.Equal(t, 0, hub.Connected())
+
+	hub.Publish(Event{Type: EventPost}, 1)
+}
+
+func TestPublishDropsSlowConnections(t *testing.T) {
+	hub := NewHub(1)
+
+	slow := hub.Subscribe(1)
+	fast := hub.Subscribe(1)
+
+	hub.Publish(Event{Type: EventNotification, Data: 1}, 1)
+	<-fast.Events
+	hub.Publish(Event{Type: EventNotification, Data: 2}, 1)
+
+	assert.Equal(t, Event{Type: EventNotification, Data: 1}, <-slow.Events)
+
+	_, open := <-slow.Events
+
+	assert.False(t, open)
+	assert.Equal(t, Event{Type: EventNotification, Data: 2}, <-fast.Events)
+	assert.Equal(t, 1, hub.Connected())
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// SuggestionRepository is the repository interface for suggestion
+type SuggestionRepository interface {
+	GetSuggestions(userID uint, activeSince time.Time, limit int) ([]models.Suggestion, error)
+	GetPrecomputedSuggestions(userID uint, limit int) ([]models.Suggestion, error)
+	ReplaceSuggestions(userID uint, suggestions []models.Suggestion) error
+	GetUserIDs(afterID uint, limit int) ([]uint, error)
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Suggestion{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewSuggestionRepository create a new suggestion repository to fiddle around with database
+func NewSuggestionRepository(db *gorm.DB) SuggestionRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// notSuggested leaves out of the suggestions in the column the user, the accounts the user follows or asked to follow,
+// the accounts that blocked the user or that the user blocked or muted, and deleted accounts. It takes the user id
+// five times
+func notSuggested(column string) string {
+	return strings.NewReplacer("{column}", column).Replace("{column} <> ? AND " +
+		"{column} NOT IN (SELECT followee_id FROM follows WHERE follower_id = ?) AND " +
+		"{column} NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND " +
+		"{column} NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?) AND " +
+		"{column} NOT IN (SELECT muted_id FROM mutes WHERE muter_id = ?) AND " +
+		"{column} IN (SELECT id FROM users WHERE deleted_at IS NULL)")
+}
+
+// friendsOfFriends are the accounts followed by the accounts the user follows, with how many of those follow each
+// and how many posts each wrote since a time
+var friendsOfFriends = "SELECT f2.followee_id AS suggested_id, COUNT(DISTINCT f1.followee_id) AS mutual_count, " +
+	"(SELECT COUNT(*) FROM posts WHERE posts.user_id = f2.followee_id AND posts.created_at >= ?) AS recent_posts " +
+	"FROM follows f1 JOIN follows f2 ON f2.follower_id = f1.followee_id AND f2.status = ? " +
+	"WHERE f1.follower_id = ? AND f1.status = ? AND " + notSuggested("f2.followee_id") + " " +
+	"GROUP BY f2.followee_id ORDER BY mutual_count DESC, recent_posts DESC, f2.followee_id LIMIT ?"
+
+// GetSuggestions returns the friends of friends of the user, the accounts followed by most of the accounts the user
+// follows first and the ones that posted most since activeSince among equals. The suggested users are loaded
+func (r *repo) GetSuggestions(userID uint, activeSince time.Time, limit int) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+
+	err := r.db.Raw(friendsOfFriends, activeSince, models.FollowAccepted, userID, models.FollowAccepted,
+		userID, userID, userID, userID, userID, limit).Scan(&suggestions).Error
+
+	if err != nil || len(suggestions) == 0 {
+		return suggestions, err
+	}
+
+	ids := make([]uint, len(suggestions))
+
+	for i := range suggestions {
+		suggestions[i].UserID = userID
+		ids[i] = suggestions[i].SuggestedID
+	}
+
+	var users []models.User
+
+	if err := r.db.Where("id IN (?)", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	byID := map[uint]*models.User{}
+
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	for i := range suggestions {
+		suggestions[i].Suggested = byID[suggestions[i].SuggestedID]
+	}
+
+	return suggestions, nil
+}
+
+// GetPrecomputedSuggestions returns the suggestions stored for the user in the same order as GetSuggestions, leaving
+// out the accounts the user followed or blocked since they were computed
+func (r *repo) GetPrecomputedSuggestions(userID uint, limit int) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+
+	err := r.db.Preload("Suggested").Where("user_id = ?", userID).
+		Where(notSuggested("suggested_id"), userID, userID, userID, userID, userID).
+		Order("mutual_count desc, recent_posts desc, suggested_id").Limit(limit).Find(&suggestions).Error
+
+	return suggestions, err
+}
+
+// ReplaceSuggestions stores the suggestions of the user in place of the ones stored before
+func (r *repo) ReplaceSuggestions(userID uint, suggestions []models.Suggestion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Suggestion{}).Error; err != nil {
+			return err
+		}
+
+		for i := range suggestions {
+			suggestions[i].ID = 0
+			suggestions[i].UserID = userID
+
+			if err := tx.Create(&suggestions[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetUserIDs returns the ids of up to limit users after afterID in order, to go through every user in batches
+func (r *repo) GetUserIDs(afterID uint, limit int) ([]uint, error) {
+	var ids []uint
+
+	err := r.db.Model(&models.User{}).Where("id > ?", afterID).Order("id").Limit(limit).Pluck("id", &ids).Error
+
+	return ids, err
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	suggestionRepo SuggestionRepository
+	mock           sqlmock.Sqlmock
+	db             *sql.DB
+	gdb            *gorm.DB
+	err            error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	suggestionRepo = NewSuggestionRepository(gdb)
+}
+
+func TestGetSuggestions(t *testing.T) {
+	setup()
+
+	since := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	const sqlSelect = `SELECT f2.followee_id AS suggested_id, COUNT(DISTINCT f1.followee_id) AS mutual_count, (SELECT COUNT(*) FROM posts WHERE posts.user_id = f2.followee_id AND posts.created_at >= $1) AS recent_posts FROM follows f1 JOIN follows f2 ON f2.follower_id = f1.followee_id AND f2.status = $2 WHERE f1.follower_id = $3 AND f1.status = $4 AND f2.followee_id <> $5`
+	const sqlSelectUsers = `SELECT * FROM "users"  WHERE "users"."deleted_at" IS NULL AND ((id IN ($1,$2)))`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).
+		WithArgs(since, models.FollowAccepted, 1, models.FollowAccepted, 1, 1, 1, 1, 1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"suggested_id", "mutual_count", "recent_posts"}).AddRow(4, 3, 0).AddRow(5, 1, 7))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectUsers)).WithArgs(4, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(5, "budi").AddRow(4, "andi"))
+
+	suggestions, err := suggestionRepo.GetSuggestions(1, since, 10)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 2, len(suggestions))
+	assert.Equal(t, 3, suggestions[0].MutualCount)
+	assert.Equal(t, "andi", suggestions[0].Suggested.Username)
+	assert.Equal(t, uint(1), suggestions[1].UserID)
+	assert.Equal(t, 7, suggestions[1].RecentPosts)
+}
+
+func TestGetPrecomputedSuggestions(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "suggestions"  WHERE (user_id = $1) AND (suggested_id <> $2 AND suggested_id NOT IN (SELECT followee_id FROM follows WHERE follower_id = $3) AND suggested_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $4) AND suggested_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = $5) AND suggested_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = $6) AND suggested_id IN (SELECT id FROM users WHERE deleted_at IS NULL)) ORDER BY mutual_count desc, recent_posts desc, suggested_id LIMIT 10`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(1, 1, 1, 1, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "suggested_id", "mutual_count"}).AddRow(1, 1, 4, 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users"  WHERE "users"."deleted_at" IS NULL AND (("id" IN ($1)))`)).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(4, "andi"))
+
+	suggestions, err := suggestionRepo.GetPrecomputedSuggestions(1, 10)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "andi", suggestions[0].Suggested.Username)
+}
+
+func TestReplaceSuggestions(t *testing.T) {
+	setup()
+
+	suggestions := []models.Suggestion{{SuggestedID: 4, MutualCount: 3, RecentPosts: 1}}
+
+	const sqlDelete = `DELETE FROM "suggestions"  WHERE (user_id = $1)`
+	const sqlInsert = `INSERT INTO "suggestions" ("user_id","suggested_id","mutual_count","recent_posts","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "suggestions"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, 4, 3, 1, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+
+	err := suggestionRepo.ReplaceSuggestions(1, suggestions)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserIDs(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT id FROM "users" WHERE "users"."deleted_at" IS NULL AND ((id > $1)) ORDER BY "id" LIMIT 100`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8).AddRow(12))
+
+	ids, err := suggestionRepo.GetUserIDs(7, 100)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{8, 12}, ids)
+}
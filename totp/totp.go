@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Period is the number of seconds each code is valid for, as used by common authenticator apps
+const Period = 30
+
+// Digits is the length of the generated codes
+const Digits = 6
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random 160 bit secret encoded as base32
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth URI that authenticator apps read from a QR code
+func URI(issuer string, account string, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(Digits))
+	values.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// Code returns the code of the secret for the time step containing t (RFC 6238)
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, uint64(t.Unix()/Period)), nil
+}
+
+// Validate reports whether code matches the secret at t, allowing one step of clock drift in both directions
+func Validate(secret string, code string, t time.Time) bool {
+	_, ok := Match(secret, code, t)
+	return ok
+}
+
+// Match is Validate that also returns the time step the code belongs to, so callers can refuse to accept a step twice
+func Match(secret string, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	for _, skew := range []time.Duration{0, -Period * time.Second, Period * time.Second} {
+		expected, err := Code(secret, t.Add(skew))
+
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return t.Add(skew).Unix() / Period, true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp computes the HMAC-based one time password of the counter (RFC 4226)
+func hotp(key []byte, counter uint64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
@@ -0,0 +1,63 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 test key of RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFCVectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := Code(rfcSecret, time.Unix(unix, 0))
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+func TestValidateAllowsOneStepOfDrift(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	previous, _ := Code(rfcSecret, now.Add(-Period*time.Second))
+	tooOld, _ := Code(rfcSecret, now.Add(-2*Period*time.Second))
+
+	assert.True(t, Validate(rfcSecret, previous, now))
+	assert.False(t, Validate(rfcSecret, tooOld, now))
+	assert.False(t, Validate(rfcSecret, "12345", now))
+}
+
+func TestMatchReturnsStepOfCode(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	previous, _ := Code(rfcSecret, now.Add(-Period*time.Second))
+
+	step, ok := Match(rfcSecret, previous, now)
+
+	assert.True(t, ok)
+	assert.Equal(t, int64(1234567890/Period-1), step)
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(secret))
+
+	uri := URI("summer-web", "joko", secret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/summer-web:joko?"))
+	assert.True(t, strings.Contains(uri, "secret="+secret))
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"summer-web/accesstoken/repository"
+	"summer-web/models"
+	"time"
+)
+
+// AccessTokenUsecase interface defines the methods that are going to be used in usecase
+type AccessTokenUsecase interface {
+	CreateAccessToken(userID uint, name string, scopes []string, expiresIn time.Duration) (string, models.AccessToken, error)
+	GetAccessTokens(userID uint) ([]models.AccessToken, error)
+	RevokeAccessToken(userID uint, id uint) error
+	Authenticate(rawToken string) (uint, []string, error)
+}
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart from session JWTs
+const AccessTokenPrefix = "swp_"
+
+const (
+	// DefaultAccessTokenLifetime is used when the user does not ask for a specific expiry
+	DefaultAccessTokenLifetime = 30 * 24 * time.Hour
+	maxAccessTokenLifetime     = 365 * 24 * time.Hour
+	// last_used_at is written at most this often per token
+	accessTokenTouchInterval = time.Minute
+)
+
+// AccessTokenScopes are the scopes a personal access token can be granted
+var AccessTokenScopes = []string{"posts:read", "posts:write", "users:read", "users:write"}
+
+var (
+	accessTokenRepo repository.AccessTokenRepository
+)
+
+type accessTokenUsecase struct{}
+
+// NewAccessTokenUsecase creates a new usecase to fiddle around with repository
+func NewAccessTokenUsecase(repo ...repository.AccessTokenRepository) AccessTokenUsecase {
+	if len(repo) > 0 {
+		accessTokenRepo = repo[0]
+	} else {
+		accessTokenRepo = repository.NewAccessTokenRepository(nil)
+	}
+	return &accessTokenUsecase{}
+}
+
+// CreateAccessToken returns the raw token along with its record, only the hash is stored so the raw token can't be shown again
+func (*accessTokenUsecase) CreateAccessToken(userID uint, name string, scopes []string, expiresIn time.Duration) (string, models.AccessToken, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return "", models.AccessToken{}, fmt.Errorf("pg: can't be null \"access_tokens_name_key\"")
+	}
+
+	scopes, err := normalizeScopes(scopes)
+
+	if err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	if expiresIn == 0 {
+		expiresIn = DefaultAccessTokenLifetime
+	}
+
+	if expiresIn < 0 || expiresIn > maxAccessTokenLifetime {
+		return "", models.AccessToken{}, fmt.Errorf("error: out of range \"access_tokens_expires_at_key\"")
+	}
+
+	secret, err := generateSecureToken()
+
+	if err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	rawToken := AccessTokenPrefix + secret
+
+	token := models.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(rawToken),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+
+	if err := accessTokenRepo.AddAccessToken(&token); err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	return rawToken, token, nil
+}
+
+func (*accessTokenUsecase) GetAccessTokens(userID uint) ([]models.AccessToken, error) {
+	return accessTokenRepo.GetAccessTokensByUserID(userID)
+}
+
+func (*accessTokenUsecase) RevokeAccessToken(userID uint, id uint) error {
+	return accessTokenRepo.DeleteAccessToken(id, userID)
+}
+
+// Authenticate returns the owner and the scopes of a valid personal access token. Tokens of deleted users,
+// expired tokens and tokens created before the user's sessions were revoked are rejected
+func (*accessTokenUsecase) Authenticate(rawToken string) (uint, []string, error) {
+	if !strings.HasPrefix(rawToken, AccessTokenPrefix) {
+		return 0, nil, fmt.Errorf("error: invalid \"token\"")
+	}
+
+	var token models.AccessToken
+
+	if err := accessTokenRepo.GetAccessTokenByHash(hashToken(rawToken), &token); err != nil {
+		return 0, nil, fmt.Errorf("error: invalid \"token\"")
+	}
+
+	now := time.Now()
+
+	if !now.Before(token.ExpiresAt) {
+		return 0, nil, fmt.Errorf("error: expired \"token\"")
+	}
+
+	var user models.User
+
+	if err := userRepo.GetUserByID(token.UserID, &user); err != nil {
+		return 0, nil, fmt.Errorf("error: invalid \"token\"")
+	}
+
+	if user.SessionsRevokedAt != nil && token.CreatedAt.Before(*user.SessionsRevokedAt) {
+		return 0, nil, fmt.Errorf("error: revoked \"token\"")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > accessTokenTouchInterval {
+		if err := accessTokenRepo.TouchAccessToken(token.ID, now); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return token.UserID, strings.Fields(token.Scopes), nil
+}
+
+// normalizeScopes rejects unknown scopes and returns the rest sorted without duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := map[string]bool{}
+	for _, scope := range AccessTokenScopes {
+		known[scope] = true
+	}
+
+	seen := map[string]bool{}
+	normalized := []string{}
+
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+
+		if scope == "" || seen[scope] {
+			continue
+		}
+
+		if !known[scope] {
+			return nil, fmt.Errorf("error: unknown scope \"access_tokens_scopes_key\"")
+		}
+
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("pg: can't be null \"access_tokens_scopes_key\"")
+	}
+
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccessTokenMockRepository struct {
+	mock.Mock
+	added models.AccessToken
+	token models.AccessToken
+}
+
+func (mock *AccessTokenMockRepository) AddAccessToken(token *models.AccessToken) error {
+	args := mock.Called()
+
+	mock.added = *token
+
+	return args.Error(0)
+}
+
+func (mock *AccessTokenMockRepository) GetAccessTokensByUserID(userID uint) ([]models.AccessToken, error) {
+	args := mock.Called()
+
+	return args.Get(0).([]models.AccessToken), args.Error(1)
+}
+
+func (mock *AccessTokenMockRepository) GetAccessTokenByHash(tokenHash string, token *models.AccessToken) error {
+	args := mock.Called(tokenHash)
+
+	*token = mock.token
+
+	return args.Error(0)
+}
+
+func (mock *AccessTokenMockRepository) DeleteAccessToken(id uint, userID uint) error {
+	args := mock.Called(id, userID)
+
+	return args.Error(0)
+}
+
+func (mock *AccessTokenMockRepository) TouchAccessToken(id uint, usedAt time.Time) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	mockRepo := new(AccessTokenMockRepository)
+
+	mockRepo.On("AddAccessToken").Return(nil)
+
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	rawToken, token, err := testUsecase.CreateAccessToken(1, "ci", []string{"posts:write", "posts:read", "posts:read"}, 0)
+
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(rawToken, AccessTokenPrefix))
+	assert.Equal(t, hashToken(rawToken), mockRepo.added.TokenHash)
+	assert.Equal(t, "posts:read posts:write", token.Scopes)
+	assert.WithinDuration(t, time.Now().Add(DefaultAccessTokenLifetime), token.ExpiresAt, time.Minute)
+}
+
+func TestCreateAccessTokenUnknownScope(t *testing.T) {
+	mockRepo := new(AccessTokenMockRepository)
+
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	_, _, err := testUsecase.CreateAccessToken(1, "ci", []string{"admin"}, 0)
+
+	mockRepo.AssertNotCalled(t, "AddAccessToken")
+	assert.Equal(t, "error: unknown scope \"access_tokens_scopes_key\"", err.Error())
+}
+
+func TestCreateAccessTokenTooLong(t *testing.T) {
+	testUsecase := NewAccessTokenUsecase(new(AccessTokenMockRepository))
+
+	_, _, err := testUsecase.CreateAccessToken(1, "ci", []string{"posts:read"}, 2*maxAccessTokenLifetime)
+
+	assert.NotNil(t, err)
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	mockUserRepo := new(UserMockRepository)
+	mockRepo := &AccessTokenMockRepository{token: models.AccessToken{ID: 3, UserID: 1, Scopes: "posts:read users:read", ExpiresAt: time.Now().Add(time.Hour)}}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("GetAccessTokenByHash", hashToken("swp_abc")).Return(nil)
+	mockRepo.On("TouchAccessToken").Return(nil)
+
+	NewUserUsecase(mockUserRepo)
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	userID, scopes, err := testUsecase.Authenticate("swp_abc")
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), userID)
+	assert.Equal(t, []string{"posts:read", "users:read"}, scopes)
+}
+
+func TestAuthenticateRecentlyUsedAccessTokenIsNotTouched(t *testing.T) {
+	lastUsedAt := time.Now().Add(-time.Second)
+	mockUserRepo := new(UserMockRepository)
+	mockRepo := &AccessTokenMockRepository{token: models.AccessToken{ID: 3, UserID: 1, Scopes: "posts:read", ExpiresAt: time.Now().Add(time.Hour), LastUsedAt: &lastUsedAt}}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("GetAccessTokenByHash", hashToken("swp_abc")).Return(nil)
+
+	NewUserUsecase(mockUserRepo)
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	_, _, err := testUsecase.Authenticate("swp_abc")
+
+	mockRepo.AssertNotCalled(t, "TouchAccessToken")
+	assert.Nil(t, err)
+}
+
+func TestAuthenticateExpiredAccessToken(t *testing.T) {
+	mockRepo := &AccessTokenMockRepository{token: models.AccessToken{ID: 3, UserID: 1, Scopes: "posts:read", ExpiresAt: time.Now().Add(-time.Hour)}}
+
+	mockRepo.On("GetAccessTokenByHash", hashToken("swp_abc")).Return(nil)
+
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	_, _, err := testUsecase.Authenticate("swp_abc")
+
+	assert.Equal(t, "error: expired \"token\"", err.Error())
+}
+
+func TestAuthenticateUnknownAccessToken(t *testing.T) {
+	mockRepo := new(AccessTokenMockRepository)
+
+	mockRepo.On("GetAccessTokenByHash", hashToken("swp_abc")).Return(fmt.Errorf("record not found"))
+
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	_, _, err := testUsecase.Authenticate("swp_abc")
+
+	assert.Equal(t, "error: invalid \"token\"", err.Error())
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	mockRepo := new(AccessTokenMockRepository)
+
+	mockRepo.On("DeleteAccessToken", uint(3), uint(1)).Return(nil)
+
+	testUsecase := NewAccessTokenUsecase(mockRepo)
+
+	err := testUsecase.RevokeAccessToken(1, 3)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"fmt"
+	"summer-web/block/repository"
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// BlockUsecase interface defines the methods that are going to be used in usecase
+type BlockUsecase interface {
+	Block(blockerID uint, blockedID uint) error
+	Unblock(blockerID uint, blockedID uint) error
+	GetBlocks(blockerID uint) ([]models.Block, error)
+}
+
+var (
+	blockRepo repository.BlockRepository
+)
+
+type blockUsecase struct{}
+
+// NewBlockUsecase creates a new usecase to fiddle around with repository
+func NewBlockUsecase(repo ...repository.BlockRepository) BlockUsecase {
+	if len(repo) > 0 {
+		blockRepo = repo[0]
+	} else {
+		blockRepo = repository.NewBlockRepository(nil)
+	}
+	return &blockUsecase{}
+}
+
+// Block hides the users from each other and removes the follows and follow requests between them
+func (*blockUsecase) Block(blockerID uint, blockedID uint) error {
+	if blockerID == blockedID {
+		return fmt.Errorf("error: invalid \"blocks_blocked_id_key\"")
+	}
+
+	var blocked models.User
+
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByID(blockedID, &blocked)); err != nil {
+		return err
+	}
+
+	var block models.Block
+
+	err := blockRepo.GetBlock(blockerID, blockedID, &block)
+
+	if err == nil {
+		return nil
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	block = models.Block{BlockerID: blockerID, BlockedID: blockedID}
+
+	if err := blockRepo.AddBlock(&block); err != nil {
+		return err
+	}
+
+	if err := removeFollow(blockerID, blockedID); err != nil {
+		return err
+	}
+
+	return removeFollow(blockedID, blockerID)
+}
+
+// Unblock lets the users see each other again, follows removed by the block are not restored
+func (*blockUsecase) Unblock(blockerID uint, blockedID uint) error {
+	return blockRepo.DeleteBlock(blockerID, blockedID)
+}
+
+func (*blockUsecase) GetBlocks(blockerID uint) ([]models.Block, error) {
+	return blockRepo.GetBlocks(blockerID)
+}
+
+// errIfBlocked returns ErrUserNotFound if either user blocked the other, blocked users are not told about the block
+func errIfBlocked(userID uint, otherID uint) error {
+	if userID == otherID {
+		return nil
+	}
+
+	blocked, err := blockRepo.IsBlocked(userID, otherID)
+
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
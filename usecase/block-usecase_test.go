@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"summer-web/models"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type BlockMockRepository struct {
+	mock.Mock
+	blocked bool
+}
+
+func (mock *BlockMockRepository) AddBlock(block *models.Block) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *BlockMockRepository) GetBlock(blockerID uint, blockedID uint, block *models.Block) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *BlockMockRepository) GetBlocks(blockerID uint) ([]models.Block, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Block), args.Error(1)
+}
+
+func (mock *BlockMockRepository) DeleteBlock(blockerID uint, blockedID uint) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *BlockMockRepository) IsBlocked(userID uint, otherID uint) (bool, error) {
+	return mock.blocked, nil
+}
+
+func (mock *BlockMockRepository) GetBlocksByUserID(userID uint) ([]models.Block, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Block), args.Error(1)
+}
+
+func TestBlockRemovesFollows(t *testing.T) {
+	mockRepo := new(BlockMockRepository)
+	mockFollowRepo := &FollowMockRepository{status: models.FollowAccepted}
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("AdjustFollowCounts", -1).Return(nil)
+	mockRepo.On("GetBlock").Return(gorm.ErrRecordNotFound)
+	mockRepo.On("AddBlock").Return(nil)
+	mockFollowRepo.On("GetFollow").Return(nil)
+	mockFollowRepo.On("DeleteFollow").Return(true, nil)
+
+	testUsecase := NewBlockUsecase(mockRepo)
+	NewFollowUsecase(mockFollowRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.Block(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	mockFollowRepo.AssertNumberOfCalls(t, "DeleteFollow", 2)
+	mockUserRepo.AssertNumberOfCalls(t, "AdjustFollowCounts", 2)
+	assert.Nil(t, err)
+}
+
+func TestBlockAlreadyBlocked(t *testing.T) {
+	mockRepo := new(BlockMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("GetBlock").Return(nil)
+
+	testUsecase := NewBlockUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.Block(1, 2)
+
+	mockRepo.AssertNotCalled(t, "AddBlock")
+	assert.Nil(t, err)
+}
+
+func TestBlockSelf(t *testing.T) {
+	testUsecase := NewBlockUsecase(new(BlockMockRepository))
+
+	err := testUsecase.Block(1, 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: invalid \"blocks_blocked_id_key\"", err.Error())
+}
+
+func TestFollowBlockedUser(t *testing.T) {
+	mockFollowRepo := new(FollowMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewFollowUsecase(mockFollowRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(&BlockMockRepository{blocked: true})
+
+	_, err := testUsecase.Follow(1, 2)
+
+	mockFollowRepo.AssertNotCalled(t, "AddFollow", models.FollowAccepted)
+	assert.Equal(t, ErrUserNotFound, err)
+}
+
+func TestGetUserProfileBlocked(t *testing.T) {
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(&BlockMockRepository{blocked: true})
+
+	var user models.User
+
+	err := testUsecase.GetUserProfile(1, 2, &user)
+
+	assert.Equal(t, ErrUserNotFound, err)
+}
+
+func TestGetPostsByUserIDBlocked(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(&BlockMockRepository{blocked: true})
+
+	_, err := testUsecase.GetPostsByUserID(1, 2)
+
+	mockRepo.AssertNotCalled(t, "GetPostsByUserID")
+	assert.Equal(t, ErrUserNotFound, err)
+}
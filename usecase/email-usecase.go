@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"summer-web/mailer"
+	"summer-web/models"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// EmailUsecase interface defines the methods that are going to be used in usecase
+type EmailUsecase interface {
+	SendVerificationEmail(userID uint) error
+	VerifyEmail(token string) error
+}
+
+// ErrEmailNotVerified is returned when an unverified user tries to do something that requires a verified email
+var ErrEmailNotVerified = fmt.Errorf("error: unverified \"users_email_key\"")
+
+const verifyEmailPurpose = "verify_email"
+
+var (
+	userMailer mailer.Mailer
+)
+
+type emailUsecase struct{}
+
+// NewEmailUsecase creates a new usecase to send and check email verifications
+func NewEmailUsecase(m ...mailer.Mailer) EmailUsecase {
+	if len(m) > 0 {
+		userMailer = m[0]
+	} else {
+		userMailer = mailer.NewMailer()
+	}
+	return &emailUsecase{}
+}
+
+// SendVerificationEmail mails a new verification link to a user whose email is not verified yet
+func (*emailUsecase) SendVerificationEmail(userID uint) error {
+	var user models.User
+
+	if err := userRepo.GetUserByID(userID, &user); err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return fmt.Errorf("error: already verified \"users_email_key\"")
+	}
+
+	return sendVerificationEmail(user)
+}
+
+// VerifyEmail checks the token and marks the email it was issued for as verified, each token works only once
+func (*emailUsecase) VerifyEmail(tokenString string) error {
+	claims, err := parsePurposeToken(tokenString, verifyEmailPurpose)
+
+	if err != nil {
+		return err
+	}
+
+	userID, ok := claims["user_id"].(float64)
+
+	if !ok {
+		return fmt.Errorf("error: invalid \"token\"")
+	}
+
+	var user models.User
+
+	if err := userRepo.GetUserByID(uint(userID), &user); err != nil {
+		return fmt.Errorf("error: invalid \"token\"")
+	}
+
+	if user.EmailVerifiedAt != nil || user.Email != claims["email"] {
+		return fmt.Errorf("error: already used \"token\"")
+	}
+
+	now := time.Now()
+
+	return userRepo.SetEmailVerifiedAt(user.ID, &now)
+}
+
+func sendVerificationEmail(user models.User) error {
+	token, err := createPurposeToken(verifyEmailPurpose, jwt.MapClaims{"user_id": user.ID, "email": user.Email}, 24*time.Hour)
+
+	if err != nil {
+		return err
+	}
+
+	body := "Hi " + user.Name + ",\n\nPlease verify your email by opening the link below within 24 hours:\n\n" +
+		appBaseURL() + "/verify_email?token=" + token + "\n"
+
+	return userMailer.Send(user.Email, "Verify your summer-web email", body)
+}
+
+// trySendVerificationEmail sends the verification email without failing the caller, the user can always ask to resend it
+func trySendVerificationEmail(user models.User) {
+	if err := sendVerificationEmail(user); err != nil {
+		log.Println("could not send verification email to user", user.ID, err)
+	}
+}
+
+// createPurposeToken signs a JWT that is only accepted by parsePurposeToken with the same purpose,
+// the key is derived from the purpose so these tokens are never accepted as auth tokens
+func createPurposeToken(purpose string, claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	at := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return at.SignedString(purposeKey(purpose))
+}
+
+func parsePurposeToken(tokenString string, purpose string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return purposeKey(purpose), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("error: invalid \"token\"")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	if claims["purpose"] != purpose {
+		return nil, fmt.Errorf("error: invalid \"token\"")
+	}
+
+	return claims, nil
+}
+
+func purposeKey(purpose string) []byte {
+	return []byte(os.Getenv("SECRET_JWT_KEY") + ":" + purpose)
+}
+
+func appBaseURL() string {
+	if url := os.Getenv("APP_BASE_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8000"
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+	to   string
+	body string
+}
+
+func (mock *MockMailer) Send(to string, subject string, body string) error {
+	args := mock.Called()
+
+	mock.to = to
+	mock.body = body
+
+	return args.Error(0)
+}
+
+func TestSendVerificationEmail(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockMailer := new(MockMailer)
+
+	mockRepo.On("GetUserByID").Return(nil, false)
+	mockMailer.On("Send").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewEmailUsecase(mockMailer)
+
+	err := testUsecase.SendVerificationEmail(1)
+
+	mockMailer.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, "joko@joko.com", mockMailer.to)
+	assert.True(t, strings.Contains(mockMailer.body, "/verify_email?token="))
+}
+
+func TestSendVerificationEmailAlreadyVerified(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockMailer := new(MockMailer)
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewEmailUsecase(mockMailer)
+
+	err := testUsecase.SendVerificationEmail(1)
+
+	mockMailer.AssertNotCalled(t, "Send")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil, false)
+	mockRepo.On("SetEmailVerifiedAt", false).Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewEmailUsecase(new(MockMailer))
+
+	token, err := createPurposeToken(verifyEmailPurpose, jwt.MapClaims{"user_id": 1, "email": "joko@joko.com"}, time.Hour)
+	assert.Nil(t, err)
+
+	err = testUsecase.VerifyEmail(token)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestVerifyEmailTokenUsedTwice(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewEmailUsecase(new(MockMailer))
+
+	token, err := createPurposeToken(verifyEmailPurpose, jwt.MapClaims{"user_id": 1, "email": "joko@joko.com"}, time.Hour)
+	assert.Nil(t, err)
+
+	err = testUsecase.VerifyEmail(token)
+
+	mockRepo.AssertNotCalled(t, "SetEmailVerifiedAt", false)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyEmailRejectsAuthToken(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewEmailUsecase(new(MockMailer))
+
+	token, err := createToken(1, 9, time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+
+	err = testUsecase.VerifyEmail(token)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: invalid \"token\"", err.Error())
+}
+
+func TestVerificationTokenIsNotAnAuthToken(t *testing.T) {
+	token, err := createPurposeToken(verifyEmailPurpose, jwt.MapClaims{"user_id": 1, "email": "joko@joko.com"}, time.Hour)
+	assert.Nil(t, err)
+
+	_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
+	})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"fmt"
+	blockRepository "summer-web/block/repository"
+	"summer-web/follow/repository"
+	"summer-web/models"
+	notificationRepository "summer-web/notification/repository"
+	webhookRepository "summer-web/webhook/repository"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FollowUsecase interface defines the methods that are going to be used in usecase
+type FollowUsecase interface {
+	Follow(followerID uint, followeeID uint) (models.Follow, error)
+	Unfollow(followerID uint, followeeID uint) error
+	GetFollowRequests(userID uint) ([]models.Follow, error)
+	ApproveFollowRequest(userID uint, id uint) error
+	RejectFollowRequest(userID uint, id uint) error
+}
+
+// ErrFollowRequestNotFound is returned when the user has no pending follow request with the id
+var ErrFollowRequestNotFound = fmt.Errorf("error: not found \"follows_id_key\"")
+
+var (
+	followRepo repository.FollowRepository
+)
+
+type followUsecase struct{}
+
+// NewFollowUsecase creates a new usecase to fiddle around with repository
+func NewFollowUsecase(repo ...repository.FollowRepository) FollowUsecase {
+	if len(repo) > 0 {
+		followRepo = repo[0]
+	} else {
+		followRepo = repository.NewFollowRepository(nil)
+		blockRepo = blockRepository.NewBlockRepository(nil)
+		notificationRepo = notificationRepository.NewNotificationRepository(nil)
+		webhookRepo = webhookRepository.NewWebhookRepository(nil)
+	}
+	return &followUsecase{}
+}
+
+// Follow follows public accounts right away, following a private account sends a request its owner has to approve.
+// Following again after being rejected sends a new request. Users blocked either way can't follow each other
+func (*followUsecase) Follow(followerID uint, followeeID uint) (models.Follow, error) {
+	if followerID == followeeID {
+		return models.Follow{}, fmt.Errorf("error: invalid \"follows_followee_id_key\"")
+	}
+
+	var followee models.User
+
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByID(followeeID, &followee)); err != nil {
+		return models.Follow{}, err
+	}
+
+	if err := errIfBlocked(followerID, followeeID); err != nil {
+		return models.Follow{}, err
+	}
+
+	status := models.FollowAccepted
+	if followee.IsPrivate {
+		status = models.FollowPending
+	}
+
+	var follow models.Follow
+
+	err := followRepo.GetFollow(followerID, followeeID, &follow)
+
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return models.Follow{}, err
+	}
+
+	if err == nil {
+		if follow.Status != models.FollowRejected {
+			return follow, nil
+		}
+
+		updated, err := followRepo.SetFollowStatus(follow.ID, models.FollowRejected, status)
+
+		if err != nil {
+			return models.Follow{}, err
+		}
+
+		// a request sent at the same time got there first and did the counting
+		if !updated {
+			err := followRepo.GetFollowByID(follow.ID, &follow)
+			return follow, err
+		}
+
+		follow.Status = status
+	} else {
+		follow = models.Follow{FollowerID: followerID, FolloweeID: followeeID, Status: status}
+
+		if err := followRepo.AddFollow(&follow); err != nil {
+			return models.Follow{}, err
+		}
+	}
+
+	if status == models.FollowAccepted {
+		if err := userRepo.AdjustFollowCounts(followerID, followeeID, 1); err != nil {
+			return models.Follow{}, err
+		}
+
+		notify(followeeID, followerID, models.NotificationFollow, 0)
+		publishWebhook(models.WebhookFollow, followWebhookData(follow))
+	} else {
+		notify(followeeID, followerID, models.NotificationFollowRequest, 0)
+	}
+
+	return follow, nil
+}
+
+// Unfollow stops following the user, or cancels the request to follow them
+func (*followUsecase) Unfollow(followerID uint, followeeID uint) error {
+	var follow models.Follow
+
+	if err := followRepo.GetFollow(followerID, followeeID, &follow); err != nil {
+		return fmt.Errorf("error: not found \"follows_followee_id_key\"")
+	}
+
+	return deleteFollow(follow)
+}
+
+func (*followUsecase) GetFollowRequests(userID uint) ([]models.Follow, error) {
+	return followRepo.GetFollowRequests(userID)
+}
+
+// ApproveFollowRequest lets the requester see the user's posts, the requester is told about it
+func (*followUsecase) ApproveFollowRequest(userID uint, id uint) error {
+	follow, err := getFollowRequest(userID, id)
+
+	if err != nil {
+		return err
+	}
+
+	updated, err := followRepo.SetFollowStatus(follow.ID, models.FollowPending, models.FollowAccepted)
+
+	if err != nil {
+		return err
+	}
+
+	// an approval running at the same time already counted the follow and told the requester
+	if !updated {
+		return ErrFollowRequestNotFound
+	}
+
+	if err := userRepo.AdjustFollowCounts(follow.FollowerID, follow.FolloweeID, 1); err != nil {
+		return err
+	}
+
+	notify(follow.FollowerID, follow.FolloweeID, models.NotificationFollowAccepted, 0)
+	publishWebhook(models.WebhookFollow, followWebhookData(follow))
+
+	return nil
+}
+
+func (*followUsecase) RejectFollowRequest(userID uint, id uint) error {
+	follow, err := getFollowRequest(userID, id)
+
+	if err != nil {
+		return err
+	}
+
+	updated, err := followRepo.SetFollowStatus(follow.ID, models.FollowPending, models.FollowRejected)
+
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return ErrFollowRequestNotFound
+	}
+
+	return nil
+}
+
+// removeFollow deletes the follow or the request to follow, if there is any
+func removeFollow(followerID uint, followeeID uint) error {
+	var follow models.Follow
+
+	if err := followRepo.GetFollow(followerID, followeeID, &follow); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+
+	return deleteFollow(follow)
+}
+
+func deleteFollow(follow models.Follow) error {
+	deleted, err := followRepo.DeleteFollow(follow.ID)
+
+	if err != nil {
+		return err
+	}
+
+	// an unfollow running at the same time already took the follow off the counts
+	if deleted && follow.Status == models.FollowAccepted {
+		return userRepo.AdjustFollowCounts(follow.FollowerID, follow.FolloweeID, -1)
+	}
+
+	return nil
+}
+
+// getFollowRequest returns the pending request to follow the user with the id
+func getFollowRequest(userID uint, id uint) (models.Follow, error) {
+	var follow models.Follow
+
+	if err := followRepo.GetFollowByID(id, &follow); err != nil {
+		return models.Follow{}, ErrFollowRequestNotFound
+	}
+
+	if follow.FolloweeID != userID || follow.Status != models.FollowPending {
+		return models.Follow{}, ErrFollowRequestNotFound
+	}
+
+	return follow, nil
+}
+
+// canViewPosts tells whether the viewer may see the author's posts, those of private accounts are only shown to the
+// author and to approved followers
+func canViewPosts(viewerID uint, author models.User) (bool, error) {
+	if !author.IsPrivate || viewerID == author.ID {
+		return true, nil
+	}
+
+	var follow models.Follow
+
+	if err := followRepo.GetFollow(viewerID, author.ID, &follow); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return follow.Status == models.FollowAccepted, nil
+}
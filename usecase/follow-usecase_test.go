@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"summer-web/models"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type FollowMockRepository struct {
+	mock.Mock
+	status string
+}
+
+func (mock *FollowMockRepository) AddFollow(follow *models.Follow) error {
+	args := mock.Called(follow.Status)
+
+	follow.ID = 3
+
+	return args.Error(0)
+}
+
+func (mock *FollowMockRepository) GetFollow(followerID uint, followeeID uint, follow *models.Follow) error {
+	args := mock.Called()
+
+	follow.ID = 3
+	follow.FollowerID = followerID
+	follow.FolloweeID = followeeID
+	follow.Status = mock.status
+
+	return args.Error(0)
+}
+
+// GetFollowByID returns a follow of user 2 following user 1
+func (mock *FollowMockRepository) GetFollowByID(id uint, follow *models.Follow) error {
+	args := mock.Called()
+
+	follow.ID = id
+	follow.FollowerID = 2
+	follow.FolloweeID = 1
+	follow.Status = mock.status
+
+	return args.Error(0)
+}
+
+func (mock *FollowMockRepository) GetFollowRequests(followeeID uint) ([]models.Follow, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Follow), args.Error(1)
+}
+
+func (mock *FollowMockRepository) SetFollowStatus(id uint, from string, to string) (bool, error) {
+	args := mock.Called(to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (mock *FollowMockRepository) DeleteFollow(id uint) (bool, error) {
+	args := mock.Called()
+	return args.Bool(0), args.Error(1)
+}
+
+func TestFollowPublicAccount(t *testing.T) {
+	mockRepo := new(FollowMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("AdjustFollowCounts", 1).Return(nil)
+	mockRepo.On("GetFollow").Return(gorm.ErrRecordNotFound)
+	mockRepo.On("AddFollow", models.FollowAccepted).Return(nil)
+
+	mockNotificationRepo := new(NotificationMockRepository)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+	NewNotificationUsecase(mockNotificationRepo)
+
+	follow, err := testUsecase.Follow(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, models.FollowAccepted, follow.Status)
+	assert.Equal(t, []models.Notification{{UserID: 2, ActorID: 1, Type: models.NotificationFollow}}, mockNotificationRepo.added)
+}
+
+func TestFollowPrivateAccount(t *testing.T) {
+	mockRepo := new(FollowMockRepository)
+	mockUserRepo := &UserMockRepository{isPrivate: true}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("GetFollow").Return(gorm.ErrRecordNotFound)
+	mockRepo.On("AddFollow", models.FollowPending).Return(nil)
+
+	mockNotificationRepo := new(NotificationMockRepository)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+	NewNotificationUsecase(mockNotificationRepo)
+
+	follow, err := testUsecase.Follow(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	mockUserRepo.AssertNotCalled(t, "AdjustFollowCounts", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.FollowPending, follow.Status)
+	assert.Equal(t, models.NotificationFollowRequest, mockNotificationRepo.added[0].Type)
+}
+
+func TestFollowAgainAfterRejection(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowRejected}
+	mockUserRepo := &UserMockRepository{isPrivate: true}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("GetFollow").Return(nil)
+	mockRepo.On("SetFollowStatus", models.FollowPending).Return(true, nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+
+	follow, err := testUsecase.Follow(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, models.FollowPending, follow.Status)
+}
+
+func TestFollowSelf(t *testing.T) {
+	testUsecase := NewFollowUsecase(new(FollowMockRepository))
+
+	_, err := testUsecase.Follow(1, 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: invalid \"follows_followee_id_key\"", err.Error())
+}
+
+func TestUnfollow(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowAccepted}
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("GetFollow").Return(nil)
+	mockRepo.On("DeleteFollow").Return(true, nil)
+	mockUserRepo.On("AdjustFollowCounts", -1).Return(nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.Unfollow(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestUnfollowDeletedMeanwhile(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowAccepted}
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("GetFollow").Return(nil)
+	mockRepo.On("DeleteFollow").Return(false, nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.Unfollow(1, 2)
+
+	mockUserRepo.AssertNotCalled(t, "AdjustFollowCounts", -1)
+	assert.Nil(t, err)
+}
+
+func TestApproveFollowRequest(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowPending}
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("GetFollowByID").Return(nil)
+	mockRepo.On("SetFollowStatus", models.FollowAccepted).Return(true, nil)
+	mockUserRepo.On("AdjustFollowCounts", 1).Return(nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.ApproveFollowRequest(1, 3)
+
+	mockRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestApproveFollowRequestTwiceAtOnce(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowPending}
+	mockUserRepo := new(UserMockRepository)
+	mockNotificationRepo := new(NotificationMockRepository)
+
+	mockRepo.On("GetFollowByID").Return(nil)
+	mockRepo.On("SetFollowStatus", models.FollowAccepted).Return(false, nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewNotificationUsecase(mockNotificationRepo)
+
+	err := testUsecase.ApproveFollowRequest(1, 3)
+
+	assert.Equal(t, ErrFollowRequestNotFound, err)
+	mockUserRepo.AssertNotCalled(t, "AdjustFollowCounts", 1)
+	assert.Empty(t, mockNotificationRepo.added)
+}
+
+func TestRejectFollowRequest(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowPending}
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("GetFollowByID").Return(nil)
+	mockRepo.On("SetFollowStatus", models.FollowRejected).Return(true, nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.RejectFollowRequest(1, 3)
+
+	mockRepo.AssertExpectations(t)
+	mockUserRepo.AssertNotCalled(t, "AdjustFollowCounts", 1)
+	assert.Nil(t, err)
+}
+
+func TestApproveSomeoneElsesFollowRequest(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowPending}
+
+	mockRepo.On("GetFollowByID").Return(nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+
+	err := testUsecase.ApproveFollowRequest(5, 3)
+
+	mockRepo.AssertNotCalled(t, "SetFollowStatus", models.FollowAccepted)
+	assert.Equal(t, ErrFollowRequestNotFound, err)
+}
+
+func TestApproveAnsweredFollowRequest(t *testing.T) {
+	mockRepo := &FollowMockRepository{status: models.FollowRejected}
+
+	mockRepo.On("GetFollowByID").Return(nil)
+
+	testUsecase := NewFollowUsecase(mockRepo)
+
+	err := testUsecase.ApproveFollowRequest(1, 3)
+
+	assert.Equal(t, ErrFollowRequestNotFound, err)
+}
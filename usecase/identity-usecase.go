@@ -0,0 +1,280 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"summer-web/identity/repository"
+	"summer-web/models"
+	"summer-web/oidc"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// IdentityUsecase interface defines the methods that are going to be used in usecase
+type IdentityUsecase interface {
+	StartLogin(provider string, linkUserID uint) (string, string, error)
+	FinishLogin(provider string, code string, state string, stateToken string, ip string, userAgent string) (string, bool, error)
+}
+
+var (
+	// ErrUnknownProvider is returned for providers that are not configured in OIDC_PROVIDERS
+	ErrUnknownProvider = fmt.Errorf("error: unknown \"provider\"")
+	// ErrProviderUnavailable is returned when the provider's discovery document can't be fetched
+	ErrProviderUnavailable = fmt.Errorf("error: unavailable \"provider\"")
+)
+
+const (
+	oidcStatePurpose = "oidc_state"
+	oidcStateTTL     = 10 * time.Minute
+)
+
+var (
+	identityRepo  repository.IdentityRepository
+	oidcProviders map[string]oidc.Client
+)
+
+type identityUsecase struct{}
+
+// NewIdentityUsecase creates a new usecase to log in with external OpenID Connect providers
+func NewIdentityUsecase(repo ...repository.IdentityRepository) IdentityUsecase {
+	if len(repo) > 0 {
+		identityRepo = repo[0]
+	} else {
+		identityRepo = repository.NewIdentityRepository(nil)
+	}
+	if oidcProviders == nil {
+		providers, err := oidc.LoadProviders()
+		if err != nil {
+			log.Println("could not load OIDC_PROVIDERS", err)
+		}
+		oidcProviders = providers
+	}
+	return &identityUsecase{}
+}
+
+// StartLogin returns the provider URL to redirect to and a signed state token the caller has to keep until the callback,
+// a non zero linkUserID links the external identity to that user instead of logging in
+func (*identityUsecase) StartLogin(provider string, linkUserID uint) (string, string, error) {
+	client, ok := oidcProviders[provider]
+
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := client.AuthCodeURL(state, nonce, oidc.CodeChallenge(verifier))
+
+	if err != nil {
+		log.Println("oidc discovery of", provider, "failed", err)
+		return "", "", ErrProviderUnavailable
+	}
+
+	stateToken, err := createPurposeToken(oidcStatePurpose, jwt.MapClaims{
+		"provider":      provider,
+		"state":         state,
+		"nonce":         nonce,
+		"code_verifier": verifier,
+		"link_user_id":  linkUserID,
+	}, oidcStateTTL)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, stateToken, nil
+}
+
+// FinishLogin checks the callback against the state token from StartLogin, exchanges the code and logs in the linked user,
+// it returns an mfa pending token and true when the user has two factor login enabled
+func (*identityUsecase) FinishLogin(provider string, code string, state string, stateToken string, ip string, userAgent string) (string, bool, error) {
+	client, ok := oidcProviders[provider]
+
+	if !ok {
+		return "", false, ErrUnknownProvider
+	}
+
+	stateClaims, err := parsePurposeToken(stateToken, oidcStatePurpose)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	expectedState, _ := stateClaims["state"].(string)
+
+	if stateClaims["provider"] != provider || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		return "", false, fmt.Errorf("error: invalid \"state\"")
+	}
+
+	verifier, _ := stateClaims["code_verifier"].(string)
+
+	claims, err := client.Exchange(code, verifier)
+
+	if err != nil {
+		log.Println("oidc exchange with", provider, "failed", err)
+		return "", false, fmt.Errorf("error: invalid \"code\"")
+	}
+
+	if claims.Nonce != stateClaims["nonce"] {
+		return "", false, fmt.Errorf("error: invalid \"nonce\"")
+	}
+
+	linkUserID, _ := stateClaims["link_user_id"].(float64)
+
+	userID, err := findOrLinkIdentity(provider, claims, uint(linkUserID))
+
+	if err != nil {
+		return "", false, err
+	}
+
+	var user models.User
+
+	if err := userRepo.GetUserByID(userID, &user); err != nil {
+		return "", false, err
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := createMFAPendingToken(user.ID)
+		return mfaToken, true, err
+	}
+
+	token, err := createSession(user.ID, ip, userAgent)
+
+	return token, false, err
+}
+
+// findOrLinkIdentity returns the user the external identity belongs to. An unknown identity is linked to linkUserID when given,
+// otherwise to the account with the same email when both sides verified it, otherwise to a new account
+func findOrLinkIdentity(provider string, claims *oidc.Claims, linkUserID uint) (uint, error) {
+	var identity models.Identity
+
+	if err := identityRepo.GetIdentity(provider, claims.Subject, &identity); err == nil {
+		if linkUserID != 0 && identity.UserID != linkUserID {
+			return 0, fmt.Errorf("error: already linked \"identities_subject_key\"")
+		}
+		return identity.UserID, nil
+	}
+
+	userID := linkUserID
+
+	if userID == 0 {
+		var existing models.User
+
+		if claims.Email != "" && userRepo.GetUserByEmail(claims.Email, &existing) == nil {
+			// linking an account by an email nobody proved to own would hand it over to whoever registered the email first
+			if !claims.EmailVerified || existing.EmailVerifiedAt == nil {
+				return 0, fmt.Errorf("error: already exists \"users_email_key\"")
+			}
+			userID = existing.ID
+		} else {
+			user, err := addUserFromClaims(claims)
+			if err != nil {
+				return 0, err
+			}
+			userID = user.ID
+		}
+	}
+
+	identity = models.Identity{UserID: userID, Provider: provider, Subject: claims.Subject, Email: claims.Email}
+
+	if err := identityRepo.AddIdentity(&identity); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// addUserFromClaims signs up a user without a password, they can set one later with the password reset flow
+func addUserFromClaims(claims *oidc.Claims) (*models.User, error) {
+	username, err := availableUsername(claims)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Username: username, Name: claims.Name, Email: claims.Email}
+
+	if user.Name == "" {
+		user.Name = username
+	}
+
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := userRepo.AddUser(user); err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		trySendVerificationEmail(*user)
+	}
+
+	publishWebhook(models.WebhookUserCreated, userWebhookData(*user))
+
+	return user, nil
+}
+
+var usernameDisallowed = regexp.MustCompile("[^a-zA-Z0-9_.]")
+
+// availableUsername derives a username from the preferred username or the email, with a numeric suffix when it is taken
+func availableUsername(claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+
+	if base == "" {
+		base = strings.Split(claims.Email, "@")[0]
+	}
+
+	base = usernameDisallowed.ReplaceAllString(base, "")
+
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+
+	for i := 0; i < 10; i++ {
+		taken, err := userRepo.IsUsernameTaken(username, 0)
+
+		if err != nil {
+			return "", err
+		}
+
+		if !taken {
+			return username, nil
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+
+		username = base + suffix.String()
+	}
+
+	return "", fmt.Errorf("error: already exists \"users_username_key\"")
+}
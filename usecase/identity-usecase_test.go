@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"fmt"
+	"net/http"
+	"summer-web/models"
+	"summer-web/oidc"
+	"summer-web/oidc/oidctest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type IdentityMockRepository struct {
+	mock.Mock
+	added []models.Identity
+}
+
+func (mock *IdentityMockRepository) AddIdentity(identity *models.Identity) error {
+	args := mock.Called()
+
+	mock.added = append(mock.added, *identity)
+
+	return args.Error(0)
+}
+
+func (mock *IdentityMockRepository) GetIdentity(provider string, subject string, identity *models.Identity) error {
+	args := mock.Called()
+
+	identity.Provider = provider
+	identity.Subject = subject
+	identity.UserID = 1
+
+	return args.Error(0)
+}
+
+func setupIdentityUsecase(identityRepo *IdentityMockRepository) (IdentityUsecase, *oidctest.Provider) {
+	provider := oidctest.NewProvider("summer-web")
+
+	oidcProviders = map[string]oidc.Client{
+		"fake": oidc.NewClient(oidc.ProviderConfig{
+			Name:        "fake",
+			Issuer:      provider.Issuer(),
+			ClientID:    "summer-web",
+			RedirectURL: "http://localhost:8000/auth/fake/callback",
+		}, http.DefaultClient),
+	}
+
+	NewSessionUsecase(newSessionMockRepository())
+
+	return NewIdentityUsecase(identityRepo), provider
+}
+
+func loginWithProvider(t *testing.T, testUsecase IdentityUsecase, provider *oidctest.Provider, linkUserID uint) (string, bool, error) {
+	authURL, stateToken, err := testUsecase.StartLogin("fake", linkUserID)
+	assert.Nil(t, err)
+
+	code, state, err := provider.Authorize(authURL)
+	assert.Nil(t, err)
+
+	return testUsecase.FinishLogin("fake", code, state, stateToken, "127.0.0.1", "curl/7.68.0")
+}
+
+func TestOIDCLoginLinkedIdentity(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockIdentityRepo.On("GetIdentity").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	token, mfaRequired, err := loginWithProvider(t, testUsecase, provider, 0)
+
+	assert.Nil(t, err)
+	assert.False(t, mfaRequired)
+	assert.NotEmpty(t, token)
+	mockIdentityRepo.AssertNotCalled(t, "AddIdentity")
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	mockRepo.On("GetUserByEmail").Return(fmt.Errorf("record not found"))
+	mockRepo.On("AddUser").Return(nil)
+	mockRepo.On("GetUserByID").Return(nil)
+	mockIdentityRepo.On("GetIdentity").Return(fmt.Errorf("record not found"))
+	mockIdentityRepo.On("AddIdentity").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	_, _, err := loginWithProvider(t, testUsecase, provider, 0)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, "fake-subject", mockIdentityRepo.added[0].Subject)
+}
+
+func TestOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	mockRepo.On("GetUserByEmail").Return(nil, true)
+	mockRepo.On("GetUserByID").Return(nil)
+	mockIdentityRepo.On("GetIdentity").Return(fmt.Errorf("record not found"))
+	mockIdentityRepo.On("AddIdentity").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	_, _, err := loginWithProvider(t, testUsecase, provider, 0)
+
+	mockRepo.AssertNotCalled(t, "AddUser")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), mockIdentityRepo.added[0].UserID)
+}
+
+func TestOIDCLoginRefusesUnverifiedEmail(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	mockRepo.On("GetUserByEmail").Return(nil, false)
+	mockIdentityRepo.On("GetIdentity").Return(fmt.Errorf("record not found"))
+
+	NewUserUsecase(mockRepo)
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	_, _, err := loginWithProvider(t, testUsecase, provider, 0)
+
+	mockIdentityRepo.AssertNotCalled(t, "AddIdentity")
+	assert.Equal(t, "error: already exists \"users_email_key\"", err.Error())
+}
+
+func TestOIDCLinkToLoggedInUser(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockIdentityRepo.On("GetIdentity").Return(fmt.Errorf("record not found"))
+	mockIdentityRepo.On("AddIdentity").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	_, _, err := loginWithProvider(t, testUsecase, provider, 5)
+
+	mockRepo.AssertNotCalled(t, "GetUserByEmail")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(5), mockIdentityRepo.added[0].UserID)
+}
+
+func TestOIDCLoginWithTOTPEnabled(t *testing.T) {
+	mockRepo := &UserMockRepository{totpSecret: "SECRET", totpEnabled: true}
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockIdentityRepo.On("GetIdentity").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	mfaToken, mfaRequired, err := loginWithProvider(t, testUsecase, provider, 0)
+
+	assert.Nil(t, err)
+	assert.True(t, mfaRequired)
+
+	_, err = parsePurposeToken(mfaToken, mfaPendingPurpose)
+	assert.Nil(t, err)
+}
+
+func TestOIDCLoginWrongState(t *testing.T) {
+	mockIdentityRepo := new(IdentityMockRepository)
+
+	NewUserUsecase(new(UserMockRepository))
+	testUsecase, provider := setupIdentityUsecase(mockIdentityRepo)
+	defer provider.Close()
+
+	authURL, stateToken, _ := testUsecase.StartLogin("fake", 0)
+	code, _, _ := provider.Authorize(authURL)
+
+	_, _, err := testUsecase.FinishLogin("fake", code, "forged", stateToken, "127.0.0.1", "curl/7.68.0")
+
+	assert.Equal(t, "error: invalid \"state\"", err.Error())
+}
+
+func TestOIDCUnknownProvider(t *testing.T) {
+	NewUserUsecase(new(UserMockRepository))
+	testUsecase, provider := setupIdentityUsecase(new(IdentityMockRepository))
+	defer provider.Close()
+
+	_, _, err := testUsecase.StartLogin("nope", 0)
+
+	assert.Equal(t, ErrUnknownProvider, err)
+}
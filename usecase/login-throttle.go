@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TooManyAttemptsError is returned by Login while the account or the client IP is locked out
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return "too many failed login attempts, please try again later"
+}
+
+const (
+	accountAttemptLimit = 5
+	ipAttemptLimit      = 20
+	baseLockout         = time.Minute
+	maxLockout          = time.Hour
+	// failures older than this are forgotten
+	attemptWindow = 24 * time.Hour
+)
+
+type failedAttempts struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginThrottle tracks failed logins per key and locks a key out with exponential backoff once it passes its limit
+type loginThrottle struct {
+	mu       sync.Mutex
+	attempts map[string]*failedAttempts
+}
+
+func newLoginThrottle() *loginThrottle {
+	return &loginThrottle{attempts: map[string]*failedAttempts{}}
+}
+
+var loginAttempts = newLoginThrottle()
+
+// userThrottleKey is the key failed attempts against an existing account are counted under
+func userThrottleKey(id uint) string {
+	return "user:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// retryAfter returns how long the key is still locked out, zero when it is not
+func (l *loginThrottle) retryAfter(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts, ok := l.attempts[key]
+
+	if !ok || !now.Before(attempts.lockedUntil) {
+		return 0
+	}
+
+	return attempts.lockedUntil.Sub(now)
+}
+
+// fail records a failed attempt and returns the new lockout duration, zero when the key did not get locked
+func (l *loginThrottle) fail(key string, limit int, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.attempts) > 10000 {
+		l.prune(now)
+	}
+
+	attempts, ok := l.attempts[key]
+
+	if !ok || now.Sub(attempts.lastFailure) > attemptWindow {
+		attempts = &failedAttempts{}
+		l.attempts[key] = attempts
+	}
+
+	attempts.count++
+	attempts.lastFailure = now
+
+	if attempts.count < limit {
+		return 0
+	}
+
+	lockout := baseLockout << uint(attempts.count-limit)
+
+	if lockout > maxLockout || lockout <= 0 {
+		lockout = maxLockout
+	}
+
+	attempts.lockedUntil = now.Add(lockout)
+
+	return lockout
+}
+
+// prune forgets the keys that are not locked and have no recent failures, the caller must hold the lock
+func (l *loginThrottle) prune(now time.Time) {
+	for key, attempts := range l.attempts {
+		if now.Sub(attempts.lastFailure) > attemptWindow && !now.Before(attempts.lockedUntil) {
+			delete(l.attempts, key)
+		}
+	}
+}
+
+func (l *loginThrottle) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleBacksOffExponentially(t *testing.T) {
+	throttle := newLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, time.Duration(0), throttle.fail("user:1", 5, now))
+	}
+
+	assert.Equal(t, baseLockout, throttle.fail("user:1", 5, now))
+	assert.Equal(t, baseLockout, throttle.retryAfter("user:1", now))
+
+	now = now.Add(baseLockout)
+	assert.Equal(t, time.Duration(0), throttle.retryAfter("user:1", now))
+
+	assert.Equal(t, 2*baseLockout, throttle.fail("user:1", 5, now))
+	assert.Equal(t, 4*baseLockout, throttle.fail("user:1", 5, now))
+
+	for i := 0; i < 20; i++ {
+		throttle.fail("user:1", 5, now)
+	}
+
+	assert.Equal(t, maxLockout, throttle.retryAfter("user:1", now))
+}
+
+func TestThrottleReset(t *testing.T) {
+	throttle := newLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		throttle.fail("user:1", 5, now)
+	}
+
+	throttle.reset("user:1")
+
+	assert.Equal(t, time.Duration(0), throttle.retryAfter("user:1", now))
+	assert.Equal(t, time.Duration(0), throttle.fail("user:1", 5, now))
+}
+
+func TestThrottleForgetsOldFailures(t *testing.T) {
+	throttle := newLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < 4; i++ {
+		throttle.fail("user:1", 5, now)
+	}
+
+	assert.Equal(t, time.Duration(0), throttle.fail("user:1", 5, now.Add(attemptWindow+time.Minute)))
+}
@@ -0,0 +1,498 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"summer-web/blobstore"
+	"summer-web/media"
+	"summer-web/models"
+	postRepository "summer-web/post/repository"
+	userRepository "summer-web/user/repository"
+	"time"
+)
+
+// MediaUsecase interface defines the methods that are going to be used in usecase
+type MediaUsecase interface {
+	GetMedia(key string, expires string, signature string) (blobstore.Object, models.Media, error)
+	GenerateVariants() error
+}
+
+// MaxPostImages is how many images can be attached to a post
+const MaxPostImages = 4
+
+// variantBatchSize is how many images GenerateVariants resizes per run
+const variantBatchSize = 10
+
+// maxVariantAttempts is how many times generating the variants of an image is attempted before it is given up as failed
+const maxVariantAttempts = 5
+
+// MediaURLTTL is how long media URLs stay valid at least, they are valid for up to twice as long so that the URLs
+// handed out within the same MediaURLTTL window are the same and stay cached by the clients
+const MediaURLTTL = time.Hour
+
+var (
+	// ErrMediaNotFound is returned when there is no media with the key
+	ErrMediaNotFound = fmt.Errorf("error: not found \"media_key_key\"")
+	// ErrMediaURLInvalid is returned when the signature of the media URL doesn't match its key and expiry
+	ErrMediaURLInvalid = fmt.Errorf("error: invalid \"media_signature_key\"")
+	// ErrMediaURLExpired is returned when the media URL is past its expiry
+	ErrMediaURLExpired = fmt.Errorf("error: expired \"media_expires_key\"")
+	// ErrTooManyImages is returned when more than MaxPostImages images are attached to a post
+	ErrTooManyImages = fmt.Errorf("error: too many \"media_post_id_key\"")
+)
+
+var (
+	blobStore blobstore.BlobStore
+)
+
+type mediaUsecase struct{}
+
+// NewMediaUsecase creates a new usecase to fiddle around with the blob store
+func NewMediaUsecase(store ...blobstore.BlobStore) MediaUsecase {
+	if len(store) > 0 {
+		blobStore = store[0]
+	} else {
+		blobStore = blobstore.NewBlobStore()
+		postRepo = postRepository.NewPostRepository(nil)
+		userRepo = userRepository.NewUserRepository(nil)
+	}
+	return &mediaUsecase{}
+}
+
+// GetMedia opens the blob of the media or of one of its variants or avatar sizes. Media URLs are only handed out to
+// viewers that may see the media, so checking the signature is what authorizes the request. The returned media
+// describes the blob that was opened
+func (*mediaUsecase) GetMedia(key string, expires string, signature string) (blobstore.Object, models.Media, error) {
+	if err := verifyMediaSignature(key, expires, signature, time.Now()); err != nil {
+		return nil, models.Media{}, err
+	}
+
+	var item models.Media
+
+	if strings.HasPrefix(key, "avatars/") {
+		avatar, err := getAvatar(key)
+
+		if err != nil {
+			return nil, models.Media{}, err
+		}
+
+		item = avatar
+	} else if err := postRepo.GetMediaByKey(key, &item); err != nil {
+		return nil, models.Media{}, ErrMediaNotFound
+	}
+
+	for _, variant := range item.Variants {
+		if variant.Key == key {
+			item.Key = variant.Key
+			item.ContentType = variant.ContentType
+			item.Size = variant.Size
+			item.Width = variant.Width
+			item.Height = variant.Height
+		}
+	}
+
+	object, err := blobStore.Open(item.Key)
+
+	if err == blobstore.ErrNotFound {
+		return nil, models.Media{}, ErrMediaNotFound
+	}
+
+	if err != nil {
+		return nil, models.Media{}, err
+	}
+
+	return object, item, nil
+}
+
+// GenerateVariants resizes the images that are still processing into every media.Variants size. Images that can't be
+// decoded are marked failed and keep being served as uploaded, blob store errors leave them processing for the next run
+func (*mediaUsecase) GenerateVariants() error {
+	pending, err := postRepo.GetPendingMedia(variantBatchSize)
+
+	if err != nil {
+		return err
+	}
+
+	// one image the blob store can't serve right now doesn't hold up the others, it is retried in the next run
+	for _, item := range pending {
+		if err := generateVariants(item); err != nil {
+			log.Println("generating variants of", item.Key, "failed:", err)
+			countVariantAttempt(item)
+		}
+	}
+
+	return nil
+}
+
+// countVariantAttempt counts the failed attempt, the image is marked failed once it runs out of attempts
+func countVariantAttempt(item models.Media) {
+	err := postRepo.CountMediaAttempt(item.ID)
+
+	if err == nil && item.Attempts+1 >= maxVariantAttempts {
+		err = postRepo.SetMediaStatus(item.ID, models.MediaFailed)
+	}
+
+	if err != nil {
+		log.Println("could not count attempt of", item.Key, err)
+	}
+}
+
+func generateVariants(item models.Media) error {
+	body, err := blobStore.Get(item.Key)
+
+	if err == blobstore.ErrNotFound {
+		return postRepo.SetMediaStatus(item.ID, models.MediaFailed)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	variants := []models.MediaVariant{}
+
+	for _, spec := range media.Variants {
+		img, err := media.Resize(data, spec)
+
+		if err == media.ErrCorrupt {
+			deleteBlobs([]models.Media{{Variants: variants}})
+			log.Println("generating variants of", item.Key, "failed:", err)
+			return postRepo.SetMediaStatus(item.ID, models.MediaFailed)
+		}
+
+		if err != nil {
+			deleteBlobs([]models.Media{{Variants: variants}})
+			return err
+		}
+
+		key := variantKey(item.Key, spec.Name, img.ContentType)
+
+		if err := blobStore.Put(key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+			deleteBlobs([]models.Media{{Variants: variants}})
+			return err
+		}
+
+		variants = append(variants, models.MediaVariant{
+			Name:        spec.Name,
+			Key:         key,
+			ContentType: img.ContentType,
+			Size:        len(img.Data),
+			Width:       img.Width,
+			Height:      img.Height,
+		})
+	}
+
+	if err := postRepo.AddMediaVariants(item.ID, variants); err != nil {
+		deleteBlobs([]models.Media{{Variants: variants}})
+		return err
+	}
+
+	return nil
+}
+
+// variantKey stores the variant next to the original, posts/1/abc.png gets posts/1/abc_thumbnail.png
+func variantKey(key string, name string, contentType string) string {
+	if dot := strings.LastIndex(key, "."); dot > strings.LastIndex(key, "/") {
+		key = key[:dot]
+	}
+
+	return key + "_" + name + media.Extensions[contentType]
+}
+
+// getAvatar describes one of the sizes of the current avatar of the user in the key, replaced avatars are not found
+func getAvatar(key string) (models.Media, error) {
+	segments := strings.Split(key, "/")
+
+	if len(segments) != 3 {
+		return models.Media{}, ErrMediaNotFound
+	}
+
+	userID, err := strconv.Atoi(segments[1])
+
+	if err != nil {
+		return models.Media{}, ErrMediaNotFound
+	}
+
+	var user models.User
+
+	if err := userRepo.GetUserByID(uint(userID), &user); err != nil || !isAvatarSizeKey(user.AvatarKey, key) {
+		return models.Media{}, ErrMediaNotFound
+	}
+
+	return models.Media{UserID: user.ID, Key: key, ContentType: contentTypeOf(key)}, nil
+}
+
+// storeAvatar crops the image to every media.AvatarSizes size and stores them, the returned key is what the size keys
+// are derived from. Either all the sizes are stored or none
+func storeAvatar(userID uint, data []byte) (string, error) {
+	img, err := media.Process(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	sizes, err := media.Avatars(img.Data, media.AvatarSizes)
+
+	if err != nil {
+		return "", err
+	}
+
+	name, err := generateSecureToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	key := "avatars/" + strconv.Itoa(int(userID)) + "/" + name[:32] + media.Extensions[sizes[0].ContentType]
+
+	for i, size := range media.AvatarSizes {
+		sizeKey := avatarSizeKey(key, size.Name)
+
+		if err := blobStore.Put(sizeKey, bytes.NewReader(sizes[i].Data), int64(len(sizes[i].Data)), sizes[i].ContentType); err != nil {
+			deleteAvatar(key)
+			return "", err
+		}
+	}
+
+	return key, nil
+}
+
+// deleteAvatar removes every size of the avatar, failures are only logged
+func deleteAvatar(key string) {
+	if key == "" {
+		return
+	}
+
+	for _, size := range media.AvatarSizes {
+		sizeKey := avatarSizeKey(key, size.Name)
+
+		if err := blobStore.Delete(sizeKey); err != nil && err != blobstore.ErrNotFound {
+			log.Println("deleting blob", sizeKey, "failed:", err)
+		}
+	}
+}
+
+// avatarSizeKey stores the sizes next to each other, avatars/1/abc.png gets avatars/1/abc_small.png
+func avatarSizeKey(key string, name string) string {
+	ext := path.Ext(key)
+
+	return strings.TrimSuffix(key, ext) + "_" + name + ext
+}
+
+func isAvatarSizeKey(avatarKey string, key string) bool {
+	if avatarKey == "" {
+		return false
+	}
+
+	for _, size := range media.AvatarSizes {
+		if avatarSizeKey(avatarKey, size.Name) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contentTypeOf returns the content type of the blob from the extension it was stored with
+func contentTypeOf(key string) string {
+	for contentType, ext := range media.Extensions {
+		if path.Ext(key) == ext {
+			return contentType
+		}
+	}
+
+	return "application/octet-stream"
+}
+
+// AvatarURLs returns where the clients can download every size of the uploaded avatar of the user, keyed by the size
+// name, or nil if the user didn't upload one
+func AvatarURLs(user models.User) map[string]string {
+	if user.AvatarKey == "" {
+		return nil
+	}
+
+	urls := map[string]string{}
+
+	for _, size := range media.AvatarSizes {
+		urls[size.Name] = mediaURL(avatarSizeKey(user.AvatarKey, size.Name))
+	}
+
+	return urls
+}
+
+// AvatarURL returns the largest size of the uploaded avatar of the user, or else the avatar URL set on the profile
+func AvatarURL(user models.User) string {
+	if user.AvatarKey == "" {
+		return user.AvatarURL
+	}
+
+	return mediaURL(avatarSizeKey(user.AvatarKey, media.AvatarSizes[len(media.AvatarSizes)-1].Name))
+}
+
+// storeImages checks every image before storing any of them, so a rejected upload never leaves blobs behind
+func storeImages(userID uint, images [][]byte) ([]models.Media, error) {
+	if len(images) > MaxPostImages {
+		return nil, ErrTooManyImages
+	}
+
+	processed := make([]media.Image, len(images))
+
+	for i, data := range images {
+		img, err := media.Process(data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		processed[i] = img
+	}
+
+	stored := []models.Media{}
+
+	for _, img := range processed {
+		name, err := generateSecureToken()
+
+		if err != nil {
+			deleteBlobs(stored)
+			return nil, err
+		}
+
+		key := "posts/" + strconv.Itoa(int(userID)) + "/" + name[:32] + media.Extensions[img.ContentType]
+
+		if err := blobStore.Put(key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+			deleteBlobs(stored)
+			return nil, err
+		}
+
+		stored = append(stored, models.Media{
+			UserID:      userID,
+			Key:         key,
+			ContentType: img.ContentType,
+			Size:        len(img.Data),
+			Width:       img.Width,
+			Height:      img.Height,
+			Status:      models.MediaProcessing,
+		})
+	}
+
+	return stored, nil
+}
+
+// deleteBlobs removes the blobs of the media and their variants, failures are only logged since the records are gone
+// already
+func deleteBlobs(items []models.Media) {
+	for _, item := range items {
+		keys := []string{}
+
+		if item.Key != "" {
+			keys = append(keys, item.Key)
+		}
+
+		for _, variant := range item.Variants {
+			keys = append(keys, variant.Key)
+		}
+
+		for _, key := range keys {
+			if err := blobStore.Delete(key); err != nil {
+				log.Println("deleting blob", key, "failed:", err)
+			}
+		}
+	}
+}
+
+// setMediaURLs fills in where the clients can download the media of the posts. Until the variants of an image are
+// generated it gets placeholders with the final dimensions that point at the image as uploaded
+func setMediaURLs(posts []models.Post) {
+	for i := range posts {
+		for j := range posts[i].Media {
+			item := &posts[i].Media[j]
+			item.URL = mediaURL(item.Key)
+
+			if item.Status != models.MediaReady {
+				item.Variants = placeholderVariants(*item)
+			}
+
+			for k := range item.Variants {
+				if item.Variants[k].Key != "" {
+					item.Variants[k].URL = mediaURL(item.Variants[k].Key)
+				}
+			}
+		}
+	}
+}
+
+func placeholderVariants(item models.Media) []models.MediaVariant {
+	variants := []models.MediaVariant{}
+
+	for _, spec := range media.Variants {
+		width, height := media.FitDimensions(item.Width, item.Height, spec.MaxDimension)
+
+		variants = append(variants, models.MediaVariant{
+			Name:        spec.Name,
+			ContentType: item.ContentType,
+			Width:       width,
+			Height:      height,
+			URL:         item.URL,
+			Placeholder: true,
+		})
+	}
+
+	return variants
+}
+
+// mediaURL signs the key with the time it expires at, anyone holding the URL can download the media until then
+func mediaURL(key string) string {
+	return signMediaURL(key, time.Now())
+}
+
+func signMediaURL(key string, now time.Time) string {
+	expires := strconv.FormatInt(now.Truncate(MediaURLTTL).Add(2*MediaURLTTL).Unix(), 10)
+
+	return "/media/" + key + "?expires=" + expires + "&signature=" + mediaSignature(key, expires)
+}
+
+func verifyMediaSignature(key string, expires string, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(mediaSignature(key, expires))) {
+		return ErrMediaURLInvalid
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+
+	if err != nil {
+		return ErrMediaURLInvalid
+	}
+
+	if now.Unix() > expiresAt {
+		return ErrMediaURLExpired
+	}
+
+	return nil
+}
+
+// mediaSignature is the HMAC-SHA256 of the key and the expiry with MEDIA_URL_SECRET, or SECRET_JWT_KEY if unset
+func mediaSignature(key string, expires string) string {
+	secret := os.Getenv("MEDIA_URL_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SECRET_JWT_KEY")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + "\n" + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
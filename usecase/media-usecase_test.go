@@ -0,0 +1,440 @@
+package usecase
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"summer-web/blobstore"
+	"summer-web/media"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestBlobStore stores blobs in a temporary directory that is removed by the returned func
+func newTestBlobStore() (string, func()) {
+	dir, err := ioutil.TempDir("", "media")
+
+	if err != nil {
+		panic(err)
+	}
+
+	NewMediaUsecase(blobstore.NewLocalStore(dir))
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func pngImage() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 3, 2)))
+	return buf.Bytes()
+}
+
+func countFiles(dir string) int {
+	count := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestCreateWithImage(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("AddPost").Return(nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	post := models.Post{UserID: 1}
+
+	err := testUsecase.AddPost(&post, pngImage())
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(post.Media))
+	assert.Equal(t, "image/png", post.Media[0].ContentType)
+	assert.Equal(t, 3, post.Media[0].Width)
+	assert.True(t, strings.HasPrefix(post.Media[0].Key, "posts/1/"))
+	assert.True(t, strings.HasSuffix(post.Media[0].Key, ".png"))
+	assert.Equal(t, post.Media[0].Key, mediaKeyOf(post.Media[0].URL))
+	assert.Equal(t, 1, countFiles(dir))
+}
+
+func TestCreateWithUnsupportedImage(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.AddPost(&models.Post{Caption: "hi", UserID: 1}, pngImage(), []byte("%PDF-1.4"))
+
+	mockRepo.AssertNotCalled(t, "AddPost")
+	assert.Equal(t, media.ErrUnsupportedType, err)
+	assert.Equal(t, 0, countFiles(dir))
+}
+
+func TestCreateWithTooManyImages(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	images := [][]byte{pngImage(), pngImage(), pngImage(), pngImage(), pngImage()}
+
+	err := testUsecase.AddPost(&models.Post{UserID: 1}, images...)
+
+	assert.Equal(t, ErrTooManyImages, err)
+}
+
+func TestCreateRemovesImagesWhenPostFails(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("AddPost").Return(fmt.Errorf("pg: can't be null \"posts_user_id_key\""))
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.AddPost(&models.Post{UserID: 1}, pngImage())
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, countFiles(dir))
+}
+
+// signedQuery returns the expires and signature parameters of the URL
+func signedQuery(mediaURL string) (string, string) {
+	parsed, err := url.Parse(mediaURL)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed.Query().Get("expires"), parsed.Query().Get("signature")
+}
+
+// mediaKeyOf returns the key the media URL points at
+func mediaKeyOf(mediaURL string) string {
+	return strings.TrimPrefix(strings.SplitN(mediaURL, "?", 2)[0], "/media/")
+}
+
+func TestGetMedia(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("posts/2/a.png", bytes.NewReader([]byte("png")), 3, "image/png")
+
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetMediaByKey").Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewPostUsecase(mockRepo)
+
+	expires, signature := signedQuery(mediaURL("posts/2/a.png"))
+
+	object, item, err := testUsecase.GetMedia("posts/2/a.png", expires, signature)
+
+	assert.Nil(t, err)
+
+	data, _ := ioutil.ReadAll(object)
+	object.Close()
+
+	assert.Equal(t, "png", string(data))
+	assert.Equal(t, "image/png", item.ContentType)
+}
+
+func TestGetMediaWithWrongSignature(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	mockRepo := new(PostMockRepository)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewPostUsecase(mockRepo)
+
+	expires, signature := signedQuery(mediaURL("posts/2/a.png"))
+
+	_, _, err := testUsecase.GetMedia("posts/2/b.png", expires, signature)
+
+	assert.Equal(t, ErrMediaURLInvalid, err)
+
+	_, _, err = testUsecase.GetMedia("posts/2/a.png", expires+"0", signature)
+
+	assert.Equal(t, ErrMediaURLInvalid, err)
+
+	_, _, err = testUsecase.GetMedia("posts/2/a.png", "", "")
+
+	assert.Equal(t, ErrMediaURLInvalid, err)
+	mockRepo.AssertNotCalled(t, "GetMediaByKey")
+}
+
+func TestGetMediaWithExpiredURL(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	testUsecase := NewMediaUsecase(blobStore)
+
+	expires, signature := signedQuery(signMediaURL("posts/2/a.png", time.Now().Add(-3*MediaURLTTL)))
+
+	_, _, err := testUsecase.GetMedia("posts/2/a.png", expires, signature)
+
+	assert.Equal(t, ErrMediaURLExpired, err)
+}
+
+func TestMediaURLsAreStableWithinTTL(t *testing.T) {
+	now := time.Now().Truncate(MediaURLTTL)
+
+	assert.Equal(t, signMediaURL("posts/2/a.png", now), signMediaURL("posts/2/a.png", now.Add(MediaURLTTL-time.Second)))
+	assert.NotEqual(t, signMediaURL("posts/2/a.png", now), signMediaURL("posts/2/a.png", now.Add(MediaURLTTL)))
+
+	expires, signature := signedQuery(signMediaURL("posts/2/a.png", now.Add(MediaURLTTL-time.Second)))
+
+	assert.Nil(t, verifyMediaSignature("posts/2/a.png", expires, signature, now.Add(2*MediaURLTTL)))
+}
+
+func TestGenerateVariants(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("posts/1/a.png", bytes.NewReader(pngImage()), int64(len(pngImage())), "image/png")
+
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPendingMedia").Return([]models.Media{{ID: 3, Key: "posts/1/a.png", Status: models.MediaProcessing}}, nil)
+	mockRepo.On("AddMediaVariants", uint(3), mock.Anything).Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewPostUsecase(mockRepo)
+
+	err := testUsecase.GenerateVariants()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, countFiles(dir))
+
+	variants := mockRepo.Calls[1].Arguments.Get(1).([]models.MediaVariant)
+
+	assert.Equal(t, len(media.Variants), len(variants))
+	assert.Equal(t, "thumbnail", variants[0].Name)
+	assert.Equal(t, "posts/1/a_thumbnail.png", variants[0].Key)
+	assert.Equal(t, 3, variants[0].Width)
+}
+
+func TestGenerateVariantsOfCorruptImage(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("posts/1/a.png", bytes.NewReader([]byte("\x89PNG\r\n\x1a\n")), 8, "image/png")
+
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPendingMedia").Return([]models.Media{{ID: 3, Key: "posts/1/a.png", Status: models.MediaProcessing}}, nil)
+	mockRepo.On("SetMediaStatus", uint(3), models.MediaFailed).Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewPostUsecase(mockRepo)
+
+	err := testUsecase.GenerateVariants()
+
+	assert.Nil(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "AddMediaVariants", mock.Anything, mock.Anything)
+	assert.Equal(t, 1, countFiles(dir))
+}
+
+func TestGenerateVariantsContinuesAfterFailure(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	// reading a directory fails like an unreachable blob store would
+	blobStore.Put("posts/1/broken/a.png", bytes.NewReader(pngImage()), int64(len(pngImage())), "image/png")
+	blobStore.Put("posts/1/b.png", bytes.NewReader(pngImage()), int64(len(pngImage())), "image/png")
+
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPendingMedia").Return([]models.Media{
+		{ID: 3, Key: "posts/1/broken", Status: models.MediaProcessing, Attempts: maxVariantAttempts - 2},
+		{ID: 4, Key: "posts/1/b.png", Status: models.MediaProcessing},
+	}, nil)
+	mockRepo.On("CountMediaAttempt", uint(3)).Return(nil)
+	mockRepo.On("AddMediaVariants", uint(4), mock.Anything).Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewPostUsecase(mockRepo)
+
+	err := testUsecase.GenerateVariants()
+
+	assert.Nil(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetMediaStatus", uint(3), models.MediaFailed)
+	assert.Equal(t, 2+len(media.Variants), countFiles(dir))
+}
+
+func TestGenerateVariantsGivesUp(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("posts/1/broken/a.png", bytes.NewReader(pngImage()), int64(len(pngImage())), "image/png")
+
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPendingMedia").Return([]models.Media{{ID: 3, Key: "posts/1/broken", Status: models.MediaProcessing, Attempts: maxVariantAttempts - 1}}, nil)
+	mockRepo.On("CountMediaAttempt", uint(3)).Return(nil)
+	mockRepo.On("SetMediaStatus", uint(3), models.MediaFailed).Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewPostUsecase(mockRepo)
+
+	err := testUsecase.GenerateVariants()
+
+	assert.Nil(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMediaPlaceholdersWhileProcessing(t *testing.T) {
+	posts := []models.Post{{Media: []models.Media{{Key: "posts/1/a.jpg", ContentType: "image/jpeg", Width: 4000, Height: 3000, Status: models.MediaProcessing}}}}
+
+	setMediaURLs(posts)
+
+	variants := posts[0].Media[0].Variants
+
+	assert.Equal(t, len(media.Variants), len(variants))
+	assert.True(t, variants[0].Placeholder)
+	assert.Equal(t, "posts/1/a.jpg", mediaKeyOf(variants[0].URL))
+	assert.Equal(t, 150, variants[0].Width)
+	assert.Equal(t, 112, variants[0].Height)
+}
+
+func TestMediaVariantURLs(t *testing.T) {
+	posts := []models.Post{{Media: []models.Media{{Key: "posts/1/a.jpg", Status: models.MediaReady, Variants: []models.MediaVariant{{Name: "thumbnail", Key: "posts/1/a_thumbnail.jpg"}}}}}}
+
+	setMediaURLs(posts)
+
+	variants := posts[0].Media[0].Variants
+
+	assert.Equal(t, 1, len(variants))
+	assert.False(t, variants[0].Placeholder)
+	assert.Equal(t, "posts/1/a_thumbnail.jpg", mediaKeyOf(variants[0].URL))
+}
+
+func TestSetAvatar(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("avatars/1/old_small.png", bytes.NewReader([]byte("png")), 3, "image/png")
+
+	mockUserRepo := &UserMockRepository{avatarKey: "avatars/1/old.png"}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("SetAvatar", uint(1), mock.Anything).Return(nil)
+
+	testUsecase := NewUserUsecase(mockUserRepo)
+
+	var user models.User
+
+	err := testUsecase.SetAvatar(1, pngImage(), &user)
+
+	assert.Nil(t, err)
+	mockUserRepo.AssertExpectations(t)
+	assert.True(t, strings.HasPrefix(user.AvatarKey, "avatars/1/"))
+	assert.Equal(t, mockUserRepo.Calls[1].Arguments.Get(1), user.AvatarKey)
+	assert.Equal(t, len(media.AvatarSizes), countFiles(dir))
+
+	urls := AvatarURLs(user)
+
+	assert.Equal(t, len(media.AvatarSizes), len(urls))
+	assert.Equal(t, avatarSizeKey(user.AvatarKey, "small"), mediaKeyOf(urls["small"]))
+	assert.Equal(t, avatarSizeKey(user.AvatarKey, "large"), mediaKeyOf(AvatarURL(user)))
+}
+
+func TestSetAvatarUnsupportedImage(t *testing.T) {
+	dir, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.SetAvatar(1, []byte("%PDF-1.4"), &models.User{})
+
+	assert.Equal(t, media.ErrUnsupportedType, err)
+	mockUserRepo.AssertNotCalled(t, "SetAvatar", mock.Anything, mock.Anything)
+	assert.Equal(t, 0, countFiles(dir))
+}
+
+func TestGetAvatar(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("avatars/2/abc_medium.png", bytes.NewReader([]byte("png")), 3, "image/png")
+
+	mockUserRepo := &UserMockRepository{avatarKey: "avatars/2/abc.png"}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewUserUsecase(mockUserRepo)
+
+	expires, signature := signedQuery(AvatarURLs(models.User{AvatarKey: "avatars/2/abc.png"})["medium"])
+
+	object, item, err := testUsecase.GetMedia("avatars/2/abc_medium.png", expires, signature)
+
+	assert.Nil(t, err)
+	object.Close()
+	assert.Equal(t, "image/png", item.ContentType)
+}
+
+func TestGetReplacedAvatar(t *testing.T) {
+	_, cleanup := newTestBlobStore()
+	defer cleanup()
+
+	blobStore.Put("avatars/2/old_medium.png", bytes.NewReader([]byte("png")), 3, "image/png")
+
+	mockUserRepo := &UserMockRepository{avatarKey: "avatars/2/abc.png"}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewMediaUsecase(blobStore)
+	NewUserUsecase(mockUserRepo)
+
+	expires, signature := signedQuery(mediaURL("avatars/2/old_medium.png"))
+
+	_, _, err := testUsecase.GetMedia("avatars/2/old_medium.png", expires, signature)
+
+	assert.Equal(t, ErrMediaNotFound, err)
+}
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"summer-web/models"
+	"summer-web/recoverycode/repository"
+	"summer-web/totp"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// MFAUsecase interface defines the methods that are going to be used in usecase
+type MFAUsecase interface {
+	EnrollTOTP(userID uint) (string, string, error)
+	ConfirmTOTP(userID uint, code string) ([]string, error)
+	VerifyLogin(mfaToken string, code string, ip string, userAgent string) (string, error)
+}
+
+const (
+	mfaPendingPurpose = "mfa_pending"
+	mfaPendingTTL     = 5 * time.Minute
+	recoveryCodeCount = 10
+	totpIssuer        = "summer-web"
+)
+
+var (
+	recoveryCodeRepo repository.RecoveryCodeRepository
+
+	errInvalidMFACode = fmt.Errorf("error: invalid \"code\"")
+)
+
+type mfaUsecase struct{}
+
+// NewMFAUsecase creates a new usecase to fiddle around with repository
+func NewMFAUsecase(repo ...repository.RecoveryCodeRepository) MFAUsecase {
+	if len(repo) > 0 {
+		recoveryCodeRepo = repo[0]
+	} else {
+		recoveryCodeRepo = repository.NewRecoveryCodeRepository(nil)
+	}
+	return &mfaUsecase{}
+}
+
+// EnrollTOTP stores a new pending secret for the user and returns it with its otpauth URI, it is enforced only after ConfirmTOTP
+func (*mfaUsecase) EnrollTOTP(userID uint) (string, string, error) {
+	var user models.User
+
+	if err := userRepo.GetUserByID(userID, &user); err != nil {
+		return "", "", err
+	}
+
+	if user.TOTPEnabled {
+		return "", "", fmt.Errorf("error: already enabled \"users_totp_key\"")
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := userRepo.SetTOTP(userID, secret, false); err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(totpIssuer, user.Username, secret), nil
+}
+
+// ConfirmTOTP enables two factor login once the user proves the authenticator works, and returns fresh recovery codes
+func (*mfaUsecase) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	var user models.User
+
+	if err := userRepo.GetUserByID(userID, &user); err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("error: already enabled \"users_totp_key\"")
+	}
+
+	if user.TOTPSecret == "" || !totp.Validate(user.TOTPSecret, code, time.Now()) {
+		return nil, fmt.Errorf("error: invalid \"code\"")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+
+	for i := range codes {
+		recoveryCode, err := generateRecoveryCode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = recoveryCode
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(recoveryCode))}
+	}
+
+	if err := recoveryCodeRepo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, err
+	}
+
+	if err := userRepo.SetTOTP(userID, user.TOTPSecret, true); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// VerifyLogin exchanges the mfa pending token from Login and a TOTP or recovery code for an auth token
+func (*mfaUsecase) VerifyLogin(mfaToken string, code string, ip string, userAgent string) (string, error) {
+	claims, err := parsePurposeToken(mfaToken, mfaPendingPurpose)
+
+	if err != nil {
+		return "", err
+	}
+
+	userID, hasUserID := claims["user_id"].(float64)
+	nonce, hasNonce := claims["nonce"].(string)
+
+	if !hasUserID || !hasNonce {
+		return "", fmt.Errorf("error: invalid \"token\"")
+	}
+
+	var user models.User
+
+	deleted := false
+	err = userRepo.GetUserByID(uint(userID), &user)
+
+	// Login only checked the password of a deleted account, it is reactivated once the second factor is right too
+	if err != nil {
+		err = userRepo.GetDeletedUserByID(uint(userID), &user)
+		deleted = err == nil && user.DeletedAt != nil && time.Since(*user.DeletedAt) <= AccountDeletionGracePeriod
+
+		if !deleted {
+			err = fmt.Errorf("error: invalid \"token\"")
+		}
+	}
+
+	// a used token, or one replaced by a newer login, is rejected before any code is checked
+	if err != nil || !user.TOTPEnabled || user.MFANonce == "" || user.MFANonce != nonce {
+		return "", fmt.Errorf("error: invalid \"token\"")
+	}
+
+	accountKey := userThrottleKey(user.ID)
+	ipKey := "ip:" + ip
+	now := time.Now()
+
+	wait := loginAttempts.retryAfter(accountKey, now)
+
+	if ipWait := loginAttempts.retryAfter(ipKey, now); ipWait > wait {
+		wait = ipWait
+	}
+
+	if wait > 0 {
+		return "", &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	if err := useMFACode(user, code, now); err != nil {
+		if err == errInvalidMFACode {
+			recordFailedLogin(user.ID, accountKey, ipKey, ip, now)
+		}
+		return "", err
+	}
+
+	loginAttempts.reset(accountKey)
+
+	used, err := userRepo.UseMFANonce(user.ID, nonce)
+
+	if err != nil {
+		return "", err
+	}
+
+	// another request got in with the same token in the meantime
+	if !used {
+		return "", fmt.Errorf("error: invalid \"token\"")
+	}
+
+	if deleted {
+		if err := userRepo.RestoreUser(user.ID); err != nil {
+			return "", err
+		}
+	}
+
+	return createSession(user.ID, ip, userAgent)
+}
+
+// useMFACode accepts a TOTP code of a later time step than the last accepted one, or an unused recovery code
+func useMFACode(user models.User, code string, now time.Time) error {
+	if step, ok := totp.Match(user.TOTPSecret, code, now); ok {
+		accepted, err := userRepo.UseTOTPStep(user.ID, step)
+
+		if err != nil {
+			return err
+		}
+
+		if !accepted {
+			return errInvalidMFACode
+		}
+
+		return nil
+	}
+
+	if err := recoveryCodeRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)), now); err != nil {
+		return errInvalidMFACode
+	}
+
+	return nil
+}
+
+// createMFAPendingToken returns a short-lived token that only VerifyLogin accepts, and only once. Logging in again
+// replaces the token
+func createMFAPendingToken(userID uint) (string, error) {
+	nonce, err := generateSecureToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := userRepo.SetMFANonce(userID, nonce); err != nil {
+		return "", err
+	}
+
+	return createPurposeToken(mfaPendingPurpose, jwt.MapClaims{"user_id": userID, "nonce": nonce}, mfaPendingTTL)
+}
+
+// generateRecoveryCode returns a random code formatted like abcde-fghij
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+}
@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"summer-web/models"
+	"summer-web/totp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type RecoveryCodeMockRepository struct {
+	mock.Mock
+	codes []models.RecoveryCode
+}
+
+func (mock *RecoveryCodeMockRepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	args := mock.Called()
+
+	mock.codes = codes
+
+	return args.Error(0)
+}
+
+func (mock *RecoveryCodeMockRepository) UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) error {
+	args := mock.Called(codeHash)
+
+	return args.Error(0)
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("SetTOTP", false).Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewMFAUsecase(new(RecoveryCodeMockRepository))
+
+	secret, uri, err := testUsecase.EnrollTOTP(1)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, secret, mockRepo.totpSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/summer-web:joko123?"))
+}
+
+func TestEnrollTOTPAlreadyEnabled(t *testing.T) {
+	mockRepo := &UserMockRepository{totpSecret: "SECRET", totpEnabled: true}
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewMFAUsecase(new(RecoveryCodeMockRepository))
+
+	_, _, err := testUsecase.EnrollTOTP(1)
+
+	mockRepo.AssertNotCalled(t, "SetTOTP", false)
+	assert.NotNil(t, err)
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	mockRepo := &UserMockRepository{totpSecret: secret}
+	mockCodeRepo := new(RecoveryCodeMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("SetTOTP", true).Return(nil)
+	mockCodeRepo.On("ReplaceRecoveryCodes").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewMFAUsecase(mockCodeRepo)
+
+	code, _ := totp.Code(secret, time.Now())
+
+	recoveryCodes, err := testUsecase.ConfirmTOTP(1, code)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.True(t, mockRepo.totpEnabled)
+	assert.Equal(t, recoveryCodeCount, len(recoveryCodes))
+	assert.Equal(t, hashToken(normalizeRecoveryCode(recoveryCodes[0])), mockCodeRepo.codes[0].CodeHash)
+}
+
+func TestConfirmTOTPInvalidCode(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	mockRepo := &UserMockRepository{totpSecret: secret}
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewMFAUsecase(new(RecoveryCodeMockRepository))
+
+	_, err := testUsecase.ConfirmTOTP(1, "000000x")
+
+	mockRepo.AssertNotCalled(t, "SetTOTP", true)
+	assert.Equal(t, "error: invalid \"code\"", err.Error())
+}
+
+func TestLoginWithTOTPEnabled(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	secret, _ := totp.GenerateSecret()
+	mockRepo := &UserMockRepository{totpSecret: secret, totpEnabled: true}
+
+	mockRepo.On("GetUserByLogin").Return(nil)
+	mockRepo.On("GetUserByID").Return(nil)
+
+	userUsecase := NewUserUsecase(mockRepo)
+	testUsecase := NewMFAUsecase(new(RecoveryCodeMockRepository))
+	NewSessionUsecase(newSessionMockRepository())
+
+	mfaToken, mfaRequired, err := userUsecase.Login(models.User{Username: "joko", Password: "123"}, "127.0.0.1", "curl/7.68.0")
+
+	assert.Nil(t, err)
+	assert.True(t, mfaRequired)
+
+	_, err = parsePurposeToken(mfaToken, mfaPendingPurpose)
+	assert.Nil(t, err)
+
+	code, _ := totp.Code(secret, time.Now())
+
+	token, err := testUsecase.VerifyLogin(mfaToken, code, "127.0.0.1", "curl/7.68.0")
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+}
+
+func TestVerifyLoginWithRecoveryCode(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := &UserMockRepository{totpSecret: "JBSWY3DPEHPK3PXP", totpEnabled: true}
+	mockCodeRepo := new(RecoveryCodeMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockCodeRepo.On("UseRecoveryCode", hashToken("abcdefghij")).Return(nil)
+
+	NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+	testUsecase := NewMFAUsecase(mockCodeRepo)
+
+	mfaToken, _ := createMFAPendingToken(1)
+
+	token, err := testUsecase.VerifyLogin(mfaToken, "ABCDE-fghij", "127.0.0.1", "curl/7.68.0")
+
+	mockCodeRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+}
+
+func TestVerifyLoginInvalidCode(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := &UserMockRepository{totpSecret: "JBSWY3DPEHPK3PXP", totpEnabled: true}
+	mockCodeRepo := new(RecoveryCodeMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockCodeRepo.On("UseRecoveryCode", mock.Anything).Return(fmt.Errorf("error: invalid \"code\""))
+
+	NewUserUsecase(mockRepo)
+	testUsecase := NewMFAUsecase(mockCodeRepo)
+
+	mfaToken, _ := createMFAPendingToken(1)
+
+	_, err := testUsecase.VerifyLogin(mfaToken, "12345", "127.0.0.1", "curl/7.68.0")
+
+	assert.Equal(t, "error: invalid \"code\"", err.Error())
+}
+
+func TestVerifyLoginTokenOnlyOnce(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := &UserMockRepository{totpSecret: "JBSWY3DPEHPK3PXP", totpEnabled: true}
+	mockCodeRepo := new(RecoveryCodeMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockCodeRepo.On("UseRecoveryCode", mock.Anything).Return(nil)
+
+	NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+	testUsecase := NewMFAUsecase(mockCodeRepo)
+
+	mfaToken, _ := createMFAPendingToken(1)
+
+	_, err := testUsecase.VerifyLogin(mfaToken, "abcde-fghij", "127.0.0.1", "curl/7.68.0")
+	assert.Nil(t, err)
+
+	_, err = testUsecase.VerifyLogin(mfaToken, "klmno-pqrst", "127.0.0.1", "curl/7.68.0")
+	assert.Equal(t, "error: invalid \"token\"", err.Error())
+}
+
+func TestVerifyLoginRejectsReusedTOTPCode(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := &UserMockRepository{totpSecret: "JBSWY3DPEHPK3PXP", totpEnabled: true}
+	mockCodeRepo := new(RecoveryCodeMockRepository)
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockCodeRepo.On("UseRecoveryCode", mock.Anything).Return(fmt.Errorf("error: invalid \"code\""))
+
+	NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+	testUsecase := NewMFAUsecase(mockCodeRepo)
+
+	code, _ := totp.Code("JBSWY3DPEHPK3PXP", time.Now())
+
+	mfaToken, _ := createMFAPendingToken(1)
+	_, err := testUsecase.VerifyLogin(mfaToken, code, "127.0.0.1", "curl/7.68.0")
+	assert.Nil(t, err)
+
+	// a code seen over someone's shoulder doesn't work for a second login in the same time step
+	mfaToken, _ = createMFAPendingToken(1)
+	_, err = testUsecase.VerifyLogin(mfaToken, code, "127.0.0.1", "curl/7.68.0")
+	assert.Equal(t, "error: invalid \"code\"", err.Error())
+}
+
+func TestVerifyLoginRejectsAuthToken(t *testing.T) {
+	NewUserUsecase(new(UserMockRepository))
+	testUsecase := NewMFAUsecase(new(RecoveryCodeMockRepository))
+
+	authToken, _ := createToken(1, 9, time.Now().Add(time.Minute))
+
+	_, err := testUsecase.VerifyLogin(authToken, "123456", "127.0.0.1", "curl/7.68.0")
+
+	assert.Equal(t, "error: invalid \"token\"", err.Error())
+}
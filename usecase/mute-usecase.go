@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"fmt"
+	"summer-web/models"
+	"summer-web/mute/repository"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MuteUsecase interface defines the methods that are going to be used in usecase
+type MuteUsecase interface {
+	Mute(muterID uint, mutedID uint) error
+	Unmute(muterID uint, mutedID uint) error
+	GetMutes(muterID uint) ([]models.Mute, error)
+}
+
+var (
+	muteRepo repository.MuteRepository
+)
+
+type muteUsecase struct{}
+
+// NewMuteUsecase creates a new usecase to fiddle around with repository
+func NewMuteUsecase(repo ...repository.MuteRepository) MuteUsecase {
+	if len(repo) > 0 {
+		muteRepo = repo[0]
+	} else {
+		muteRepo = repository.NewMuteRepository(nil)
+	}
+	return &muteUsecase{}
+}
+
+// Mute leaves the user's posts out of the muter's feeds, unlike blocking the muted user doesn't notice anything
+func (*muteUsecase) Mute(muterID uint, mutedID uint) error {
+	if muterID == mutedID {
+		return fmt.Errorf("error: invalid \"mutes_muted_id_key\"")
+	}
+
+	var muted models.User
+
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByID(mutedID, &muted)); err != nil {
+		return err
+	}
+
+	var mute models.Mute
+
+	err := muteRepo.GetMute(muterID, mutedID, &mute)
+
+	if err == nil {
+		return nil
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	return muteRepo.AddMute(&models.Mute{MuterID: muterID, MutedID: mutedID})
+}
+
+func (*muteUsecase) Unmute(muterID uint, mutedID uint) error {
+	return muteRepo.DeleteMute(muterID, mutedID)
+}
+
+func (*muteUsecase) GetMutes(muterID uint) ([]models.Mute, error) {
+	return muteRepo.GetMutes(muterID)
+}
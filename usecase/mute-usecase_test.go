@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"summer-web/models"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MuteMockRepository struct {
+	mock.Mock
+}
+
+func (mock *MuteMockRepository) AddMute(mute *models.Mute) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *MuteMockRepository) GetMute(muterID uint, mutedID uint, mute *models.Mute) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *MuteMockRepository) GetMutes(muterID uint) ([]models.Mute, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Mute), args.Error(1)
+}
+
+func (mock *MuteMockRepository) DeleteMute(muterID uint, mutedID uint) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func TestMute(t *testing.T) {
+	mockRepo := new(MuteMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("GetMute").Return(gorm.ErrRecordNotFound)
+	mockRepo.On("AddMute").Return(nil)
+
+	testUsecase := NewMuteUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.Mute(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestMuteUnknownUser(t *testing.T) {
+	mockRepo := new(MuteMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(gorm.ErrRecordNotFound)
+
+	testUsecase := NewMuteUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.Mute(1, 9)
+
+	mockRepo.AssertNotCalled(t, "AddMute")
+	assert.Equal(t, ErrUserNotFound, err)
+}
+
+func TestMuteSelf(t *testing.T) {
+	testUsecase := NewMuteUsecase(new(MuteMockRepository))
+
+	err := testUsecase.Mute(1, 1)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: invalid \"mutes_muted_id_key\"", err.Error())
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"summer-web/models"
+	"summer-web/notification/repository"
+	"summer-web/stream"
+	"time"
+)
+
+// NotificationUsecase interface defines the methods that are going to be used in usecase
+type NotificationUsecase interface {
+	GetNotifications(userID uint, limit int) ([]models.NotificationGroup, int, error)
+	MarkRead(userID uint, ids []uint) (int, error)
+}
+
+const (
+	// only the latest notifications are aggregated into groups
+	maxAggregatedNotifications = 500
+	// groups name up to this many actors, the rest are counted as others
+	maxGroupActors = 3
+)
+
+// what every type of notification says the actors did
+var notificationPhrases = map[string]string{
+	models.NotificationFollow:         "followed you",
+	models.NotificationFollowRequest:  "asked to follow you",
+	models.NotificationFollowAccepted: "accepted your follow request",
+	models.NotificationMention:        "mentioned you in a post",
+}
+
+var (
+	notificationRepo repository.NotificationRepository
+)
+
+type notificationUsecase struct{}
+
+// NewNotificationUsecase creates a new usecase to fiddle around with repository
+func NewNotificationUsecase(repo ...repository.NotificationRepository) NotificationUsecase {
+	if len(repo) > 0 {
+		notificationRepo = repo[0]
+	} else {
+		notificationRepo = repository.NewNotificationRepository(nil)
+	}
+	return &notificationUsecase{}
+}
+
+// GetNotifications returns up to limit groups of the latest notifications of the user, newest first, and how many
+// notifications the user didn't read
+func (*notificationUsecase) GetNotifications(userID uint, limit int) ([]models.NotificationGroup, int, error) {
+	notifications, err := notificationRepo.GetNotifications(userID, maxAggregatedNotifications)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := notificationRepo.CountUnread(userID)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups := groupNotifications(notifications)
+
+	if limit = PageSize(limit); len(groups) > limit {
+		groups = groups[:limit]
+	}
+
+	return groups, unread, nil
+}
+
+// MarkRead marks the notifications with the ids read, or all of them without ids, and returns how many are left unread
+func (*notificationUsecase) MarkRead(userID uint, ids []uint) (int, error) {
+	if err := notificationRepo.MarkRead(userID, ids, time.Now()); err != nil {
+		return 0, err
+	}
+
+	return notificationRepo.CountUnread(userID)
+}
+
+// notify tells the user about what the actor did, users are not told about their own doings nor about actors they
+// blocked, muted or are blocked by. Failing to notify doesn't fail what the actor did, it is only logged
+func notify(userID uint, actorID uint, notificationType string, postID uint) {
+	if userID == actorID {
+		return
+	}
+
+	notification := models.Notification{UserID: userID, ActorID: actorID, Type: notificationType, PostID: postID}
+
+	added, err := notificationRepo.AddNotification(&notification)
+
+	if err != nil {
+		log.Println("could not notify user", userID, "of", notificationType, err)
+		return
+	}
+
+	if !added {
+		return
+	}
+
+	streamHub.Publish(stream.Event{Type: stream.EventNotification, Data: notification}, userID)
+}
+
+// groupNotifications groups the notifications of the same type about the same post, read and unread ones apart.
+// Notifications of deleted actors are left out
+func groupNotifications(notifications []models.Notification) []models.NotificationGroup {
+	groups := []models.NotificationGroup{}
+	index := map[string]int{}
+	actors := map[string]map[uint]bool{}
+
+	for _, notification := range notifications {
+		if notification.Actor == nil {
+			continue
+		}
+
+		unread := notification.ReadAt == nil
+		key := notification.Type + ":" + strconv.FormatUint(uint64(notification.PostID), 10) + ":" + strconv.FormatBool(unread)
+
+		i, ok := index[key]
+
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			actors[key] = map[uint]bool{}
+			groups = append(groups, models.NotificationGroup{Type: notification.Type, PostID: notification.PostID, Unread: unread})
+		}
+
+		group := &groups[i]
+		group.IDs = append(group.IDs, notification.ID)
+
+		if notification.CreatedAt.After(group.CreatedAt) {
+			group.CreatedAt = notification.CreatedAt
+		}
+
+		if actors[key][notification.ActorID] {
+			continue
+		}
+
+		actors[key][notification.ActorID] = true
+		group.ActorCount++
+
+		if len(group.Actors) < maxGroupActors {
+			group.Actors = append(group.Actors, *notification.Actor)
+		}
+	}
+
+	for i := range groups {
+		groups[i].Text = notificationText(groups[i])
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].CreatedAt.After(groups[j].CreatedAt)
+	})
+
+	return groups
+}
+
+// notificationText says what happened like "budi and 4 others followed you"
+func notificationText(group models.NotificationGroup) string {
+	names := group.Actors[0].Username
+
+	switch {
+	case group.ActorCount == 2:
+		names += " and " + group.Actors[1].Username
+	case group.ActorCount == 3:
+		names += ", " + group.Actors[1].Username + " and " + group.Actors[2].Username
+	case group.ActorCount > 3:
+		names += " and " + strconv.Itoa(group.ActorCount-1) + " others"
+	}
+
+	return names + " " + notificationPhrases[group.Type]
+}
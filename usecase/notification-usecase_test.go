@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"summer-web/models"
+	"summer-web/stream"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// NotificationMockRepository keeps the notifications added in memory
+type NotificationMockRepository struct {
+	added         []models.Notification
+	hidden        bool
+	notifications []models.Notification
+	unread        int
+	readIDs       []uint
+	deletedUserID uint
+}
+
+func (mock *NotificationMockRepository) AddNotification(notification *models.Notification) (bool, error) {
+	if mock.hidden {
+		return false, nil
+	}
+
+	mock.added = append(mock.added, *notification)
+	return true, nil
+}
+
+func (mock *NotificationMockRepository) GetNotifications(userID uint, limit int) ([]models.Notification, error) {
+	return mock.notifications, nil
+}
+
+func (mock *NotificationMockRepository) CountUnread(userID uint) (int, error) {
+	return mock.unread, nil
+}
+
+func (mock *NotificationMockRepository) MarkRead(userID uint, ids []uint, readAt time.Time) error {
+	mock.readIDs = ids
+	mock.unread = 0
+	return nil
+}
+
+func (mock *NotificationMockRepository) DeleteNotificationsByUserID(userID uint) error {
+	mock.deletedUserID = userID
+	return nil
+}
+
+// every usecase may notify, tests that don't look at the notifications drop them here
+func init() {
+	NewNotificationUsecase(new(NotificationMockRepository))
+}
+
+func TestGetNotificationsGrouped(t *testing.T) {
+	start := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	readAt := start.Add(time.Hour)
+	actors := []models.User{{ID: 2, Username: "budi"}, {ID: 3, Username: "andi"}, {ID: 4, Username: "sari"}, {ID: 5, Username: "tono"}, {ID: 6, Username: "dewi"}}
+
+	mockRepo := &NotificationMockRepository{unread: 6}
+
+	for i, actor := range actors {
+		actor := actor
+		mockRepo.notifications = append(mockRepo.notifications, models.Notification{ID: uint(10 - i), ActorID: actor.ID, Type: models.NotificationFollow, CreatedAt: start.Add(time.Duration(10-i) * time.Minute), Actor: &actor})
+	}
+
+	mockRepo.notifications = append(mockRepo.notifications,
+		models.Notification{ID: 4, ActorID: 2, Type: models.NotificationMention, PostID: 7, CreatedAt: start.Add(4 * time.Minute), Actor: &actors[0]},
+		models.Notification{ID: 3, ActorID: 2, Type: models.NotificationMention, PostID: 8, CreatedAt: start.Add(3 * time.Minute), Actor: &actors[0], ReadAt: &readAt},
+		models.Notification{ID: 2, ActorID: 3, Type: models.NotificationMention, PostID: 8, CreatedAt: start.Add(2 * time.Minute), Actor: &actors[1], ReadAt: &readAt},
+		models.Notification{ID: 1, ActorID: 9, Type: models.NotificationFollow, CreatedAt: start.Add(time.Minute)},
+	)
+
+	testUsecase := NewNotificationUsecase(mockRepo)
+
+	groups, unread, err := testUsecase.GetNotifications(1, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 6, unread)
+	assert.Equal(t, 3, len(groups))
+	assert.Equal(t, "budi and 4 others followed you", groups[0].Text)
+	assert.Equal(t, []uint{10, 9, 8, 7, 6}, groups[0].IDs)
+	assert.Equal(t, 3, len(groups[0].Actors))
+	assert.Equal(t, 5, groups[0].ActorCount)
+	assert.True(t, groups[0].Unread)
+	assert.Equal(t, "budi mentioned you in a post", groups[1].Text)
+	assert.Equal(t, "budi and andi mentioned you in a post", groups[2].Text)
+	assert.False(t, groups[2].Unread)
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	mockRepo := &NotificationMockRepository{unread: 2}
+
+	testUsecase := NewNotificationUsecase(mockRepo)
+
+	unread, err := testUsecase.MarkRead(1, []uint{5})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, unread)
+	assert.Equal(t, []uint{5}, mockRepo.readIDs)
+}
+
+func TestNotifyMentions(t *testing.T) {
+	mockRepo := new(NotificationMockRepository)
+
+	NewNotificationUsecase(mockRepo)
+	streamUsecase := NewStreamUsecase(stream.NewHub(stream.DefaultBufferSize))
+
+	mentioned := streamUsecase.Subscribe(2)
+	defer streamUsecase.Unsubscribe(mentioned)
+
+	post := models.Post{ID: 7, UserID: 1, Mentions: []models.PostMention{{UserID: 1}, {UserID: 2}, {UserID: 3}}}
+
+	notifyMentions(post, []models.PostMention{{UserID: 3}})
+
+	assert.Equal(t, []models.Notification{{UserID: 2, ActorID: 1, Type: models.NotificationMention, PostID: 7}}, mockRepo.added)
+	assert.Equal(t, stream.Event{Type: stream.EventNotification, Data: mockRepo.added[0]}, <-mentioned.Events)
+}
+
+func TestNotifyHiddenActor(t *testing.T) {
+	mockRepo := &NotificationMockRepository{hidden: true}
+
+	NewNotificationUsecase(mockRepo)
+	streamUsecase := NewStreamUsecase(stream.NewHub(stream.DefaultBufferSize))
+
+	subscription := streamUsecase.Subscribe(2)
+	defer streamUsecase.Unsubscribe(subscription)
+
+	notify(2, 1, models.NotificationFollow, 0)
+
+	assert.Empty(t, mockRepo.added)
+	assert.Empty(t, subscription.Events)
+}
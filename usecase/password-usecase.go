@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"summer-web/mailer"
+	"summer-web/models"
+	"summer-web/passwordreset/repository"
+	"sync"
+	"time"
+)
+
+// PasswordUsecase interface defines the methods that are going to be used in usecase
+type PasswordUsecase interface {
+	ForgotPassword(email string) error
+	ResetPassword(token string, newPassword string) error
+}
+
+const passwordResetTTL = time.Hour
+
+var (
+	passwordResetRepo repository.PasswordResetRepository
+
+	// the reset links being sent in the background
+	passwordResetMails sync.WaitGroup
+)
+
+type passwordUsecase struct{}
+
+// NewPasswordUsecase creates a new usecase to fiddle around with repository
+func NewPasswordUsecase(repo ...repository.PasswordResetRepository) PasswordUsecase {
+	if len(repo) > 0 {
+		passwordResetRepo = repo[0]
+	} else {
+		passwordResetRepo = repository.NewPasswordResetRepository(nil)
+	}
+	if userMailer == nil {
+		userMailer = mailer.NewMailer()
+	}
+	return &passwordUsecase{}
+}
+
+// ForgotPassword mails a reset link when the email belongs to a user, unknown emails are silently ignored. The link is
+// sent in the background so known and unknown emails take the same time to answer and can't be told apart
+func (*passwordUsecase) ForgotPassword(email string) error {
+	var user models.User
+
+	if err := userRepo.GetUserByEmail(email, &user); err != nil {
+		log.Println("password reset requested for unknown email")
+		return nil
+	}
+
+	passwordResetMails.Add(1)
+
+	go func() {
+		defer passwordResetMails.Done()
+
+		if err := sendPasswordReset(user); err != nil {
+			log.Println("could not send password reset to user", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// sendPasswordReset stores a new reset token of the user and mails the link to it
+func sendPasswordReset(user models.User) error {
+	token, err := generateSecureToken()
+
+	if err != nil {
+		return err
+	}
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+
+	if err := passwordResetRepo.AddPasswordReset(&reset); err != nil {
+		return err
+	}
+
+	body := "Hi " + user.Name + ",\n\nSomeone asked to reset your password. Open the link below within an hour to choose a new one:\n\n" +
+		appBaseURL() + "/password/reset?token=" + token + "\n\nIf it was not you, you can ignore this email.\n"
+
+	return userMailer.Send(user.Email, "Reset your summer-web password", body)
+}
+
+// ResetPassword sets a new password using an unexpired reset token once, and logs out every session of the user
+func (*passwordUsecase) ResetPassword(token string, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	var reset models.PasswordReset
+
+	err := passwordResetRepo.GetPasswordResetByTokenHash(hashToken(token), &reset)
+
+	if err != nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return fmt.Errorf("error: invalid \"token\"")
+	}
+
+	now := time.Now()
+
+	if err := passwordResetRepo.UsePasswordReset(reset.ID, now); err != nil {
+		return err
+	}
+
+	if err := userRepo.UpdateUser(models.User{ID: reset.UserID, Password: newPassword}); err != nil {
+		return err
+	}
+
+	return revokeAllSessions(reset.UserID, now)
+}
+
+// generateSecureToken returns 32 random bytes encoded as hex
+func generateSecureToken() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
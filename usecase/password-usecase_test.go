@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordResetMockRepository struct {
+	mock.Mock
+	added models.PasswordReset
+}
+
+func (mock *PasswordResetMockRepository) AddPasswordReset(reset *models.PasswordReset) error {
+	args := mock.Called()
+
+	mock.added = *reset
+
+	return args.Error(0)
+}
+
+func (mock *PasswordResetMockRepository) GetPasswordResetByTokenHash(tokenHash string, reset *models.PasswordReset) error {
+	args := mock.Called(tokenHash)
+
+	result := args.Get(0).(models.PasswordReset)
+	*reset = result
+
+	return args.Error(1)
+}
+
+func (mock *PasswordResetMockRepository) UsePasswordReset(id uint, usedAt time.Time) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func TestForgotPassword(t *testing.T) {
+	mockRepo := new(PasswordResetMockRepository)
+	mockUserRepo := new(UserMockRepository)
+	mockMailer := new(MockMailer)
+
+	mockUserRepo.On("GetUserByEmail").Return(nil)
+	mockRepo.On("AddPasswordReset").Return(nil)
+	mockMailer.On("Send").Return(nil)
+
+	NewUserUsecase(mockUserRepo)
+	NewEmailUsecase(mockMailer)
+	testUsecase := NewPasswordUsecase(mockRepo)
+
+	err := testUsecase.ForgotPassword("joko@joko.com")
+
+	passwordResetMails.Wait()
+
+	mockRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, "joko@joko.com", mockMailer.to)
+
+	token := regexp.MustCompile("token=([0-9a-f]+)").FindStringSubmatch(mockMailer.body)[1]
+
+	assert.Equal(t, hashToken(token), mockRepo.added.TokenHash)
+	assert.NotEqual(t, token, mockRepo.added.TokenHash)
+	assert.True(t, mockRepo.added.ExpiresAt.After(time.Now()))
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	mockRepo := new(PasswordResetMockRepository)
+	mockUserRepo := new(UserMockRepository)
+	mockMailer := new(MockMailer)
+
+	mockUserRepo.On("GetUserByEmail").Return(fmt.Errorf("record not found"))
+
+	NewUserUsecase(mockUserRepo)
+	NewEmailUsecase(mockMailer)
+	testUsecase := NewPasswordUsecase(mockRepo)
+
+	err := testUsecase.ForgotPassword("nobody@joko.com")
+
+	mockMailer.AssertNotCalled(t, "Send")
+	mockRepo.AssertNotCalled(t, "AddPasswordReset")
+	assert.Nil(t, err)
+}
+
+func TestForgotPasswordMailerError(t *testing.T) {
+	mockRepo := new(PasswordResetMockRepository)
+	mockUserRepo := new(UserMockRepository)
+	mockMailer := new(MockMailer)
+
+	mockUserRepo.On("GetUserByEmail").Return(nil)
+	mockRepo.On("AddPasswordReset").Return(nil)
+	mockMailer.On("Send").Return(fmt.Errorf("dial tcp: connection refused"))
+
+	NewUserUsecase(mockUserRepo)
+	NewEmailUsecase(mockMailer)
+	testUsecase := NewPasswordUsecase(mockRepo)
+
+	// the answer is the same as for an unknown email, the mailer failing is only logged
+	err := testUsecase.ForgotPassword("joko@joko.com")
+
+	passwordResetMails.Wait()
+
+	mockMailer.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestResetPassword(t *testing.T) {
+	mockRepo := new(PasswordResetMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	reset := models.PasswordReset{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(time.Minute)}
+
+	mockRepo.On("GetPasswordResetByTokenHash", hashToken("abc")).Return(reset, nil)
+	mockRepo.On("UsePasswordReset").Return(nil)
+	mockUserRepo.On("UpdateUser").Return(nil)
+	mockUserRepo.On("RevokeSessions").Return(nil)
+
+	mockSessionRepo := newSessionMockRepository()
+
+	NewUserUsecase(mockUserRepo)
+	NewSessionUsecase(mockSessionRepo)
+	testUsecase := NewPasswordUsecase(mockRepo)
+
+	err := testUsecase.ResetPassword("abc", "newpassword1")
+
+	mockRepo.AssertExpectations(t)
+	mockSessionRepo.AssertCalled(t, "RevokeAllSessions")
+	mockUserRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	mockRepo := new(PasswordResetMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	reset := models.PasswordReset{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}
+
+	mockRepo.On("GetPasswordResetByTokenHash", hashToken("abc")).Return(reset, nil)
+
+	NewUserUsecase(mockUserRepo)
+	testUsecase := NewPasswordUsecase(mockRepo)
+
+	err := testUsecase.ResetPassword("abc", "newpassword1")
+
+	mockUserRepo.AssertNotCalled(t, "UpdateUser")
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: invalid \"token\"", err.Error())
+}
+
+func TestResetPasswordWeakPassword(t *testing.T) {
+	mockRepo := new(PasswordResetMockRepository)
+
+	testUsecase := NewPasswordUsecase(mockRepo)
+
+	err := testUsecase.ResetPassword("abc", "short")
+
+	mockRepo.AssertNotCalled(t, "GetPasswordResetByTokenHash", hashToken("abc"))
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: too weak \"users_password_key\"", err.Error())
+}
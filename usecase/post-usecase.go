@@ -2,16 +2,48 @@ package usecase
 
 import (
 	"fmt"
+	"strings"
+	"summer-web/blobstore"
+	blockRepository "summer-web/block/repository"
+	"summer-web/caption"
+	followRepository "summer-web/follow/repository"
 	"summer-web/models"
+	notificationRepository "summer-web/notification/repository"
 	"summer-web/post/repository"
+	webhookRepository "summer-web/webhook/repository"
+
+	"github.com/jinzhu/gorm"
 )
 
 // PostUsecase interface defines the methods that are going to be used in usecase
 type PostUsecase interface {
-	GetPosts() ([]models.Post, error)
-	AddPost(post *models.Post) error
+	GetPosts(viewerID uint) ([]models.Post, error)
+	GetPostsByUserID(viewerID uint, userID uint) ([]models.Post, error)
+	GetFeed(viewerID uint) ([]models.Post, error)
+	GetPostsByTag(viewerID uint, tag string, beforeID uint, limit int) ([]models.Post, error)
+	AddPost(post *models.Post, images ...[]byte) error
+	UpdatePost(userID uint, id uint, caption string) (models.Post, error)
 }
 
+var (
+	// ErrPrivateAccount is returned when the viewer is not an approved follower of the private account
+	ErrPrivateAccount = fmt.Errorf("error: private \"users_is_private_key\"")
+	// ErrPostNotFound is returned when there is no post with the id
+	ErrPostNotFound = fmt.Errorf("error: not found \"posts_id_key\"")
+	// ErrNotPostAuthor is returned when someone other than the author edits a post
+	ErrNotPostAuthor = fmt.Errorf("error: forbidden \"posts_user_id_key\"")
+	// ErrInvalidTag is returned when the tag is not a hashtag captions could contain
+	ErrInvalidTag = fmt.Errorf("error: invalid \"post_tags_tag_key\"")
+	// ErrEmptyPost is returned for posts with neither a caption nor images
+	ErrEmptyPost = fmt.Errorf("pg: can't be null \"posts_caption_key\"")
+)
+
+// Pages of tags and search results hold DefaultPageSize items unless the caller asks for up to MaxPageSize
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
 var (
 	postRepo repository.PostRepository
 )
@@ -24,26 +56,274 @@ func NewPostUsecase(repo ...repository.PostRepository) PostUsecase {
 		postRepo = repo[0]
 	} else {
 		postRepo = repository.NewPostRepository(nil)
+		followRepo = followRepository.NewFollowRepository(nil)
+		blockRepo = blockRepository.NewBlockRepository(nil)
+		blobStore = blobstore.NewBlobStore()
+		notificationRepo = notificationRepository.NewNotificationRepository(nil)
+		webhookRepo = webhookRepository.NewWebhookRepository(nil)
 	}
 	return &postUsecase{}
 }
 
-// GetPosts accesses repo to get all the post records in database
-func (*postUsecase) GetPosts() ([]models.Post, error) {
-	return postRepo.GetPosts()
+// GetPosts accesses repo to get the post records in database the viewer may see
+func (*postUsecase) GetPosts(viewerID uint) ([]models.Post, error) {
+	posts, err := postRepo.GetPosts(viewerID)
+
+	setMediaURLs(posts)
+	setEntities(posts)
+
+	return posts, err
+}
+
+// GetPostsByUserID returns the user's posts, or ErrPrivateAccount if the account is private and the viewer is not
+// an approved follower. Users that blocked each other are not found
+func (*postUsecase) GetPostsByUserID(viewerID uint, userID uint) ([]models.Post, error) {
+	var author models.User
+
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByID(userID, &author)); err != nil {
+		return nil, err
+	}
+
+	if err := errIfBlocked(viewerID, userID); err != nil {
+		return nil, err
+	}
+
+	visible, err := canViewPosts(viewerID, author)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !visible {
+		return nil, ErrPrivateAccount
+	}
+
+	posts, err := postRepo.GetPostsByUserID(userID)
+
+	setMediaURLs(posts)
+	setEntities(posts)
+
+	return posts, err
+}
+
+// GetFeed returns the posts of the viewer and of the accounts the viewer follows
+func (*postUsecase) GetFeed(viewerID uint) ([]models.Post, error) {
+	posts, err := postRepo.GetFeed(viewerID)
+
+	setMediaURLs(posts)
+	setEntities(posts)
+
+	return posts, err
+}
+
+// GetPostsByTag returns a page of the posts tagged with the tag that the viewer may see, newest first. Pages after the
+// first one start below the ID of the last post of the previous page
+func (*postUsecase) GetPostsByTag(viewerID uint, tag string, beforeID uint, limit int) ([]models.Post, error) {
+	tag, ok := caption.NormalizeHashtag(tag)
+
+	if !ok {
+		return nil, ErrInvalidTag
+	}
+
+	posts, err := postRepo.GetPostsByTag(viewerID, tag, beforeID, PageSize(limit))
+
+	setMediaURLs(posts)
+	setEntities(posts)
+
+	return posts, err
+}
+
+// PageSize returns how many items a page holds when the caller asks for limit of them
+func PageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+
+	return limit
 }
 
-// AddPost accesses repo to add a post record to database
-func (*postUsecase) AddPost(post *models.Post) error {
-	if err := validatePost(post); err != nil {
+// AddPost accesses repo to add a post record to database, the images are checked, stripped of their metadata and
+// stored in the blob store. A post with images doesn't need a caption. The hashtags and mentions of the caption are
+// stored along with the post
+func (*postUsecase) AddPost(post *models.Post, images ...[]byte) error {
+	if err := validatePost(post, len(images)); err != nil {
+		return err
+	}
+
+	var author models.User
+
+	if err := userRepo.GetUserByID(post.UserID, &author); err != nil {
+		return err
+	}
+
+	if author.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+
+	if err := tagPost(post); err != nil {
+		return err
+	}
+
+	stored, err := storeImages(post.UserID, images)
+
+	if err != nil {
+		return err
+	}
+
+	post.Media = stored
+
+	if err := postRepo.AddPost(post); err != nil {
+		deleteBlobs(stored)
 		return err
 	}
-	return postRepo.AddPost(post)
+
+	setMediaURLs([]models.Post{*post})
+	post.Entities = entities(*post)
+
+	notifyMentions(*post, nil)
+	publishWebhook(models.WebhookPostCreated, postWebhookData(*post))
+
+	published := *post
+	published.Author = &author
+	publishPost(published)
+
+	return nil
+}
+
+// UpdatePost lets the author change the caption of the post, its hashtags and mentions are parsed again
+func (*postUsecase) UpdatePost(userID uint, id uint, text string) (models.Post, error) {
+	var post models.Post
+
+	if err := postRepo.GetPostByID(id, &post); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return post, ErrPostNotFound
+		}
+		return post, err
+	}
+
+	if post.UserID != userID {
+		return post, ErrNotPostAuthor
+	}
+
+	post.Caption = text
+	mentioned := post.Mentions
+
+	if err := validatePost(&post, len(post.Media)); err != nil {
+		return post, err
+	}
+
+	if err := tagPost(&post); err != nil {
+		return post, err
+	}
+
+	if err := postRepo.UpdatePost(&post); err != nil {
+		return post, err
+	}
+
+	setMediaURLs([]models.Post{post})
+	post.Entities = entities(post)
+
+	notifyMentions(post, mentioned)
+
+	return post, nil
+}
+
+// notifyMentions tells the users mentioned in the post about it, except the ones that were mentioned already
+func notifyMentions(post models.Post, mentionedBefore []models.PostMention) {
+	told := map[uint]bool{}
+
+	for _, mention := range mentionedBefore {
+		told[mention.UserID] = true
+	}
+
+	for _, mention := range post.Mentions {
+		if !told[mention.UserID] {
+			notify(mention.UserID, post.UserID, models.NotificationMention, post.ID)
+		}
+	}
+}
+
+// tagPost sets the hashtags and mentions of the post from its caption. Mentions of users that don't exist or that
+// blocked the author, or were blocked by them, are left out
+func tagPost(post *models.Post) error {
+	post.Tags = []models.PostTag{}
+	post.Mentions = []models.PostMention{}
+
+	for _, tag := range caption.Hashtags(post.Caption) {
+		post.Tags = append(post.Tags, models.PostTag{PostID: post.ID, Tag: tag})
+	}
+
+	for _, username := range caption.Mentions(post.Caption) {
+		var user models.User
+
+		err := userRepo.GetUserByUsername(username, &user)
+
+		if gorm.IsRecordNotFoundError(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = errIfBlocked(post.UserID, user.ID)
+
+		if err == ErrUserNotFound {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		post.Mentions = append(post.Mentions, models.PostMention{PostID: post.ID, UserID: user.ID, Username: username})
+	}
+
+	return nil
+}
+
+// setEntities parses the entities of every post, see entities
+func setEntities(posts []models.Post) {
+	for i := range posts {
+		posts[i].Entities = entities(posts[i])
+	}
+}
+
+// entities returns the hashtags of the caption and the mentions that were stored with the post, so mentions of
+// unknown users are not shown as entities
+func entities(post models.Post) []models.Entity {
+	mentioned := map[string]uint{}
+
+	for _, mention := range post.Mentions {
+		mentioned[mention.Username] = mention.UserID
+	}
+
+	result := []models.Entity{}
+
+	for _, entity := range caption.Parse(post.Caption) {
+		if entity.Type == models.EntityMention {
+			userID, ok := mentioned[strings.ToLower(entity.Text)]
+
+			if !ok {
+				continue
+			}
+
+			entity.UserID = userID
+		}
+
+		result = append(result, entity)
+	}
+
+	return result
 }
 
-func validatePost(post *models.Post) error {
-	if post.Caption == "" {
-		return fmt.Errorf("pg: can't be null \"posts_caption_key\"")
+func validatePost(post *models.Post, imageCount int) error {
+	if post.Caption == "" && imageCount == 0 {
+		return ErrEmptyPost
 	}
 	if post.UserID == 0 {
 		return fmt.Errorf("pg: can't be null \"posts_user_id_key\"")
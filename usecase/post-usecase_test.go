@@ -2,8 +2,11 @@ package usecase
 
 import (
 	"summer-web/models"
+	"summer-web/stream"
 	"testing"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -12,7 +15,7 @@ type PostMockRepository struct {
 	mock.Mock
 }
 
-func (mock *PostMockRepository) GetPosts() ([]models.Post, error) {
+func (mock *PostMockRepository) GetPosts(viewerID uint) ([]models.Post, error) {
 	args := mock.Called()
 
 	result := args.Get(0)
@@ -20,11 +23,100 @@ func (mock *PostMockRepository) GetPosts() ([]models.Post, error) {
 	return result.([]models.Post), args.Error(1)
 }
 
+func (mock *PostMockRepository) GetPostsByUserID(userID uint) ([]models.Post, error) {
+	args := mock.Called()
+
+	result := args.Get(0)
+
+	return result.([]models.Post), args.Error(1)
+}
+
+func (mock *PostMockRepository) GetFeed(viewerID uint) ([]models.Post, error) {
+	args := mock.Called()
+
+	result := args.Get(0)
+
+	return result.([]models.Post), args.Error(1)
+}
+
+func (mock *PostMockRepository) GetPostsByTag(viewerID uint, tag string, beforeID uint, limit int) ([]models.Post, error) {
+	args := mock.Called(tag, beforeID, limit)
+
+	return args.Get(0).([]models.Post), args.Error(1)
+}
+
+func (mock *PostMockRepository) GetPostByID(id uint, post *models.Post) error {
+	args := mock.Called()
+
+	post.ID = id
+	post.UserID = 2
+	post.Caption = "old"
+
+	return args.Error(0)
+}
+
 func (mock *PostMockRepository) AddPost(post *models.Post) error {
 	args := mock.Called()
 	return args.Error(0)
 }
 
+func (mock *PostMockRepository) UpdatePost(post *models.Post) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *PostMockRepository) GetTagCounts(since time.Time) ([]models.TagCount, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.TagCount), args.Error(1)
+}
+
+func (mock *PostMockRepository) GetFeedReaderIDs(authorID uint) ([]uint, error) {
+	args := mock.Called(authorID)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (mock *PostMockRepository) DeletePostsByUserID(userID uint) error {
+	args := mock.Called()
+	return args.Error(0)
+}
+
+func (mock *PostMockRepository) GetMediaByKey(key string, media *models.Media) error {
+	args := mock.Called()
+
+	media.ID = 3
+	media.PostID = 7
+	media.UserID = 2
+	media.Key = key
+	media.ContentType = "image/png"
+
+	return args.Error(0)
+}
+
+func (mock *PostMockRepository) GetMediaByUserID(userID uint) ([]models.Media, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Media), args.Error(1)
+}
+
+func (mock *PostMockRepository) GetPendingMedia(limit int) ([]models.Media, error) {
+	args := mock.Called()
+	return args.Get(0).([]models.Media), args.Error(1)
+}
+
+func (mock *PostMockRepository) AddMediaVariants(mediaID uint, variants []models.MediaVariant) error {
+	args := mock.Called(mediaID, variants)
+	return args.Error(0)
+}
+
+func (mock *PostMockRepository) SetMediaStatus(mediaID uint, status string) error {
+	args := mock.Called(mediaID, status)
+	return args.Error(0)
+}
+
+func (mock *PostMockRepository) CountMediaAttempt(mediaID uint) error {
+	args := mock.Called(mediaID)
+	return args.Error(0)
+}
+
 func TestAddingEmptyCaption(t *testing.T) {
 	assert := assert.New(t)
 
@@ -61,7 +153,7 @@ func TestFindAll(t *testing.T) {
 
 	testUsecase := NewPostUsecase(mockRepo)
 
-	result, err := testUsecase.GetPosts()
+	result, err := testUsecase.GetPosts(1)
 
 	// MOCK ASSERTIONS: BEHAVIOUR
 	mockRepo.AssertExpectations(t)
@@ -78,14 +170,256 @@ func TestCreate(t *testing.T) {
 
 	post := models.Post{Caption: "ASDASD", UserID: 1}
 
+	mockUserRepo := new(UserMockRepository)
+
 	// SETUP EXPECTATIONS
 	mockRepo.On("AddPost").Return(nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.AddPost(&post)
+
+	mockRepo.AssertExpectations(t)
+
+	assert.Nil(t, err)
+}
+
+func TestCreatePublishesToFeedReaders(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	post := models.Post{Caption: "ASDASD", UserID: 1}
+
+	mockRepo.On("AddPost").Return(nil)
+	mockRepo.On("GetFeedReaderIDs", uint(1)).Return([]uint{3}, nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	streamUsecase := NewStreamUsecase(stream.NewHub(stream.DefaultBufferSize))
+
+	follower := streamUsecase.Subscribe(3)
+	defer streamUsecase.Unsubscribe(follower)
+
+	err := testUsecase.AddPost(&post)
+
+	event := <-follower.Events
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, stream.EventPost, event.Type)
+	assert.Equal(t, "ASDASD", event.Data.(models.Post).Caption)
+	assert.NotNil(t, event.Data.(models.Post).Author)
+}
+
+func TestCreateUnverified(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+
+	post := models.Post{Caption: "ASDASD", UserID: 1}
+
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil, false)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.AddPost(&post)
+
+	mockRepo.AssertNotCalled(t, "AddPost")
+
+	assert.Equal(t, ErrEmailNotVerified, err)
+}
+
+func TestGetPostsByUserIDPrivateAccount(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := &UserMockRepository{isPrivate: true}
+	mockFollowRepo := new(FollowMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockFollowRepo.On("GetFollow").Return(gorm.ErrRecordNotFound)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+	NewFollowUsecase(mockFollowRepo)
+
+	_, err := testUsecase.GetPostsByUserID(1, 2)
+
+	mockRepo.AssertNotCalled(t, "GetPostsByUserID")
+	assert.Equal(t, ErrPrivateAccount, err)
+}
+
+func TestGetPostsByUserIDApprovedFollower(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := &UserMockRepository{isPrivate: true}
+	mockFollowRepo := &FollowMockRepository{status: models.FollowAccepted}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockFollowRepo.On("GetFollow").Return(nil)
+	mockRepo.On("GetPostsByUserID").Return([]models.Post{{Caption: "ADD", UserID: 2}}, nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+	NewFollowUsecase(mockFollowRepo)
+
+	posts, err := testUsecase.GetPostsByUserID(1, 2)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(posts))
+}
+
+func TestGetPostsByUserIDPendingFollower(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := &UserMockRepository{isPrivate: true}
+	mockFollowRepo := &FollowMockRepository{status: models.FollowPending}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockFollowRepo.On("GetFollow").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+	NewFollowUsecase(mockFollowRepo)
+
+	_, err := testUsecase.GetPostsByUserID(1, 2)
+
+	assert.Equal(t, ErrPrivateAccount, err)
+}
+
+func TestCreateWithTagsAndMentions(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	post := models.Post{Caption: "hi @Budi and @budi #Go #go", UserID: 2}
+
+	mockRepo.On("AddPost").Return(nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("GetUserByUsername").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+
+	err := testUsecase.AddPost(&post)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []models.PostTag{{Tag: "go"}}, post.Tags)
+	assert.Equal(t, []models.PostMention{{UserID: 1, Username: "budi"}}, post.Mentions)
+	assert.Equal(t, 4, len(post.Entities))
+	assert.Equal(t, models.Entity{Type: models.EntityMention, Start: 3, End: 8, Text: "Budi", UserID: 1}, post.Entities[0])
+	assert.Equal(t, models.Entity{Type: models.EntityHashtag, Start: 23, End: 26, Text: "go"}, post.Entities[3])
+}
+
+func TestCreateMentioningUnknownUser(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	post := models.Post{Caption: "hi @nobody", UserID: 2}
+
+	mockRepo.On("AddPost").Return(nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("GetUserByUsername").Return(gorm.ErrRecordNotFound)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.AddPost(&post)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(post.Mentions))
+	assert.Equal(t, 0, len(post.Entities))
+}
+
+func TestCreateMentioningBlockedUser(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	post := models.Post{Caption: "hi @budi", UserID: 2}
+
+	mockRepo.On("AddPost").Return(nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("GetUserByUsername").Return(nil)
 
 	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(&BlockMockRepository{blocked: true})
 
 	err := testUsecase.AddPost(&post)
 
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(post.Mentions))
+}
+
+func TestUpdatePost(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockRepo.On("GetPostByID").Return(nil)
+	mockRepo.On("UpdatePost").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	post, err := testUsecase.UpdatePost(2, 4, "now #rust")
+
 	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, "now #rust", post.Caption)
+	assert.Equal(t, []models.PostTag{{PostID: 4, Tag: "rust"}}, post.Tags)
+	assert.Equal(t, "rust", post.Entities[0].Text)
+}
+
+func TestUpdatePostNotAuthor(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPostByID").Return(nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+
+	_, err := testUsecase.UpdatePost(1, 4, "mine now")
 
+	mockRepo.AssertNotCalled(t, "UpdatePost")
+	assert.Equal(t, ErrNotPostAuthor, err)
+}
+
+func TestUpdatePostNotFound(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPostByID").Return(gorm.ErrRecordNotFound)
+
+	testUsecase := NewPostUsecase(mockRepo)
+
+	_, err := testUsecase.UpdatePost(2, 4, "hello")
+
+	assert.Equal(t, ErrPostNotFound, err)
+}
+
+func TestGetPostsByTag(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+
+	mockRepo.On("GetPostsByTag", "go", uint(9), MaxPageSize).Return([]models.Post{{Caption: "#Go", UserID: 2}}, nil)
+
+	testUsecase := NewPostUsecase(mockRepo)
+
+	posts, err := testUsecase.GetPostsByTag(1, "#GO", 9, 1000)
+
+	mockRepo.AssertExpectations(t)
 	assert.Nil(t, err)
+	assert.Equal(t, "Go", posts[0].Entities[0].Text)
+}
+
+func TestGetPostsByTagInvalid(t *testing.T) {
+	mockRepo := new(PostMockRepository)
+
+	testUsecase := NewPostUsecase(mockRepo)
+
+	_, err := testUsecase.GetPostsByTag(1, "not a tag", 0, 0)
+
+	mockRepo.AssertNotCalled(t, "GetPostsByTag")
+	assert.Equal(t, ErrInvalidTag, err)
 }
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"summer-web/blobstore"
+	"summer-web/models"
+	"summer-web/search/repository"
+	"unicode/utf8"
+)
+
+// SearchUsecase interface defines the methods that are going to be used in usecase
+type SearchUsecase interface {
+	SearchPosts(viewerID uint, query string, offset int, limit int) ([]models.Post, error)
+	SearchUsers(viewerID uint, query string, offset int, limit int) ([]models.User, error)
+}
+
+// MaxQueryLength is the longest query in characters searches accept
+const MaxQueryLength = 200
+
+var (
+	// ErrEmptyQuery is returned when there is nothing to search for
+	ErrEmptyQuery = fmt.Errorf("error: can't be null \"search_q_key\"")
+	// ErrQueryTooLong is returned when the query is longer than MaxQueryLength
+	ErrQueryTooLong = fmt.Errorf("error: too long \"search_q_key\"")
+)
+
+var (
+	searchRepo repository.SearchRepository
+)
+
+type searchUsecase struct{}
+
+// NewSearchUsecase creates a new usecase to fiddle around with repository
+func NewSearchUsecase(repo ...repository.SearchRepository) SearchUsecase {
+	if len(repo) > 0 {
+		searchRepo = repo[0]
+	} else {
+		searchRepo = repository.NewSearchRepository(nil)
+		blobStore = blobstore.NewBlobStore()
+	}
+	return &searchUsecase{}
+}
+
+// SearchPosts returns a page of the posts the viewer may see that match the query, best match first
+func (*searchUsecase) SearchPosts(viewerID uint, query string, offset int, limit int) ([]models.Post, error) {
+	query, err := validateQuery(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := searchRepo.SearchPosts(viewerID, query, offset, PageSize(limit))
+
+	setMediaURLs(posts)
+	setEntities(posts)
+
+	return posts, err
+}
+
+// SearchUsers returns a page of the users that match the query, best match first. Users that blocked each other
+// don't find each other
+func (*searchUsecase) SearchUsers(viewerID uint, query string, offset int, limit int) ([]models.User, error) {
+	query, err := validateQuery(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return searchRepo.SearchUsers(viewerID, query, offset, PageSize(limit))
+}
+
+func validateQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+
+	if query == "" {
+		return "", ErrEmptyQuery
+	}
+
+	if utf8.RuneCountInString(query) > MaxQueryLength {
+		return "", ErrQueryTooLong
+	}
+
+	return query, nil
+}
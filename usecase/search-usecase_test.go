@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"strings"
+	"summer-web/models"
+	"summer-web/search/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSearchUsecase() SearchUsecase {
+	users := []models.User{{ID: 1, Username: "joko", Name: "Joko"}, {ID: 2, Username: "budi", Name: "Budi", IsPrivate: true}}
+	posts := []models.Post{
+		{ID: 1, UserID: 1, Caption: "#summer at the beach"},
+		{ID: 2, UserID: 2, Caption: "summer, privately"},
+		{ID: 3, UserID: 1, Caption: "summer summer"},
+	}
+
+	return NewSearchUsecase(repository.NewMemorySearchRepository(posts, users))
+}
+
+func TestSearchPosts(t *testing.T) {
+	testUsecase := newTestSearchUsecase()
+
+	posts, err := testUsecase.SearchPosts(1, "  Summer ", 0, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(posts))
+	assert.Equal(t, uint(3), posts[0].ID)
+	assert.Equal(t, models.EntityHashtag, posts[1].Entities[0].Type)
+}
+
+func TestSearchPostsPage(t *testing.T) {
+	testUsecase := newTestSearchUsecase()
+
+	posts, err := testUsecase.SearchPosts(2, "summer", 1, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(posts))
+	assert.Equal(t, uint(2), posts[0].ID)
+}
+
+func TestSearchUsers(t *testing.T) {
+	testUsecase := newTestSearchUsecase()
+
+	users, err := testUsecase.SearchUsers(1, "@bu", 0, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "budi", users[0].Username)
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	testUsecase := newTestSearchUsecase()
+
+	_, err := testUsecase.SearchUsers(1, "   ", 0, 10)
+
+	assert.Equal(t, ErrEmptyQuery, err)
+}
+
+func TestSearchQueryTooLong(t *testing.T) {
+	testUsecase := newTestSearchUsecase()
+
+	_, err := testUsecase.SearchPosts(1, strings.Repeat("é", MaxQueryLength+1), 0, 10)
+
+	assert.Equal(t, ErrQueryTooLong, err)
+}
@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"fmt"
+	"os"
+	"summer-web/models"
+	"summer-web/session/repository"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SessionUsecase interface defines the methods that are going to be used in usecase
+type SessionUsecase interface {
+	GetSessions(userID uint) ([]models.Session, error)
+	RevokeSession(userID uint, id uint) error
+	ValidateSession(userID uint, id uint) error
+	RefreshToken(rawToken string) (string, error)
+	PurgeExpiredSessions() error
+}
+
+const (
+	// auth tokens are short lived, clients get new ones with RefreshToken for as long as their session lasts
+	authTokenTTL = 15 * time.Minute
+	// a session ends this long after the login no matter how much it is used
+	sessionTTL = 30 * 24 * time.Hour
+	// and sooner when it isn't used for this long
+	sessionIdleTimeout = 7 * 24 * time.Hour
+	// last_seen_at is written at most this often per session
+	sessionTouchInterval = time.Minute
+)
+
+var (
+	sessionRepo repository.SessionRepository
+)
+
+type sessionUsecase struct{}
+
+// NewSessionUsecase creates a new usecase to fiddle around with repository
+func NewSessionUsecase(repo ...repository.SessionRepository) SessionUsecase {
+	if len(repo) > 0 {
+		sessionRepo = repo[0]
+	} else {
+		sessionRepo = repository.NewSessionRepository(nil)
+	}
+	return &sessionUsecase{}
+}
+
+func (*sessionUsecase) GetSessions(userID uint) ([]models.Session, error) {
+	now := time.Now()
+	sessions, err := sessionRepo.GetActiveSessionsByUserID(userID, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	active := []models.Session{}
+
+	for _, session := range sessions {
+		if now.Sub(session.LastSeenAt) <= sessionIdleTimeout {
+			active = append(active, session)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeSession logs one of the user's devices out, its token is rejected from then on
+func (*sessionUsecase) RevokeSession(userID uint, id uint) error {
+	return sessionRepo.RevokeSession(id, userID, time.Now())
+}
+
+// ValidateSession returns an error unless the session belongs to the user and is neither revoked, expired nor idle
+func (*sessionUsecase) ValidateSession(userID uint, id uint) error {
+	_, err := validateSession(userID, id)
+	return err
+}
+
+// RefreshToken issues a new auth token for the session of the given one, which may have expired already but must be
+// signed by us. The new token expires after authTokenTTL or with the session, whichever comes first
+func (*sessionUsecase) RefreshToken(rawToken string) (string, error) {
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
+	})
+
+	// refreshing is what expired tokens are for, anything else wrong with the token is not
+	if validationErr, ok := err.(*jwt.ValidationError); err != nil && (!ok || validationErr.Errors != jwt.ValidationErrorExpired) {
+		return "", fmt.Errorf("error: invalid \"session\"")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	userID, hasUserID := claims["user_id"].(float64)
+	sessionID, hasSessionID := claims["session_id"].(float64)
+
+	if !hasUserID || !hasSessionID {
+		return "", fmt.Errorf("error: invalid \"session\"")
+	}
+
+	session, err := validateSession(uint(userID), uint(sessionID))
+
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(authTokenTTL)
+
+	if session.ExpiresAt.Before(expiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	return createToken(session.UserID, session.ID, expiresAt)
+}
+
+// PurgeExpiredSessions removes the sessions whose tokens have expired
+func (*sessionUsecase) PurgeExpiredSessions() error {
+	return sessionRepo.DeleteSessionsExpiredBefore(time.Now())
+}
+
+// createSession records a login from the client and returns the first auth token bound to it
+func createSession(userID uint, ip string, userAgent string) (string, error) {
+	now := time.Now()
+
+	session := models.Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+
+	if err := sessionRepo.AddSession(&session); err != nil {
+		return "", err
+	}
+
+	return createToken(userID, session.ID, now.Add(authTokenTTL))
+}
+
+// validateSession returns the session if it belongs to the user and is neither revoked, expired nor idle, and records
+// that it was used
+func validateSession(userID uint, id uint) (models.Session, error) {
+	var session models.Session
+
+	if err := sessionRepo.GetSessionByID(id, &session); err != nil {
+		return models.Session{}, fmt.Errorf("error: invalid \"session\"")
+	}
+
+	now := time.Now()
+
+	if session.UserID != userID || session.RevokedAt != nil || !now.Before(session.ExpiresAt) || now.Sub(session.LastSeenAt) > sessionIdleTimeout {
+		return models.Session{}, fmt.Errorf("error: revoked \"session\"")
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		return session, sessionRepo.TouchSession(id, now)
+	}
+
+	return session, nil
+}
+
+// revokeAllSessions logs the user out everywhere, personal access tokens created before now stop working as well
+func revokeAllSessions(userID uint, now time.Time) error {
+	if err := sessionRepo.RevokeAllSessions(userID, now); err != nil {
+		return err
+	}
+
+	return userRepo.RevokeSessions(userID, now)
+}
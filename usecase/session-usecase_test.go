@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"fmt"
+	"os"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionMockRepository struct {
+	mock.Mock
+	added   models.Session
+	session models.Session
+}
+
+// newSessionMockRepository returns a mock that accepts new sessions and revocations, for the tests that log a user in
+func newSessionMockRepository() *SessionMockRepository {
+	mockRepo := new(SessionMockRepository)
+
+	mockRepo.On("AddSession").Return(nil).Maybe()
+	mockRepo.On("RevokeAllSessions").Return(nil).Maybe()
+
+	return mockRepo
+}
+
+func (mock *SessionMockRepository) AddSession(session *models.Session) error {
+	args := mock.Called()
+
+	session.ID = 9
+	mock.added = *session
+
+	return args.Error(0)
+}
+
+func (mock *SessionMockRepository) GetSessionByID(id uint, session *models.Session) error {
+	args := mock.Called()
+
+	*session = mock.session
+
+	return args.Error(0)
+}
+
+func (mock *SessionMockRepository) GetActiveSessionsByUserID(userID uint, now time.Time) ([]models.Session, error) {
+	args := mock.Called()
+
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (mock *SessionMockRepository) TouchSession(id uint, lastSeenAt time.Time) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func (mock *SessionMockRepository) RevokeSession(id uint, userID uint, revokedAt time.Time) error {
+	args := mock.Called(id, userID)
+
+	return args.Error(0)
+}
+
+func (mock *SessionMockRepository) RevokeAllSessions(userID uint, revokedAt time.Time) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func (mock *SessionMockRepository) DeleteSessionsExpiredBefore(deadline time.Time) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func TestCreateSession(t *testing.T) {
+	mockRepo := newSessionMockRepository()
+
+	NewSessionUsecase(mockRepo)
+
+	token, err := createSession(1, "127.0.0.1", "curl/7.68.0")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "curl/7.68.0", mockRepo.added.UserAgent)
+	assert.Equal(t, "127.0.0.1", mockRepo.added.IP)
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, float64(9), parsed.Claims.(jwt.MapClaims)["session_id"])
+	assert.InDelta(t, float64(time.Now().Add(authTokenTTL).Unix()), parsed.Claims.(jwt.MapClaims)["exp"], 1)
+	assert.WithinDuration(t, time.Now().Add(sessionTTL), mockRepo.added.ExpiresAt, time.Second)
+}
+
+func TestValidateSession(t *testing.T) {
+	now := time.Now()
+	mockRepo := &SessionMockRepository{session: models.Session{ID: 9, UserID: 1, LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Minute)}}
+
+	mockRepo.On("GetSessionByID").Return(nil)
+	mockRepo.On("TouchSession").Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	err := testUsecase.ValidateSession(1, 9)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestValidateRevokedSession(t *testing.T) {
+	now := time.Now()
+	mockRepo := &SessionMockRepository{session: models.Session{ID: 9, UserID: 1, LastSeenAt: now, ExpiresAt: now.Add(time.Minute), RevokedAt: &now}}
+
+	mockRepo.On("GetSessionByID").Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	err := testUsecase.ValidateSession(1, 9)
+
+	assert.Equal(t, "error: revoked \"session\"", err.Error())
+}
+
+func TestValidateIdleSession(t *testing.T) {
+	now := time.Now()
+	mockRepo := &SessionMockRepository{session: models.Session{ID: 9, UserID: 1, LastSeenAt: now.Add(-sessionIdleTimeout - time.Minute), ExpiresAt: now.Add(time.Hour)}}
+
+	mockRepo.On("GetSessionByID").Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	err := testUsecase.ValidateSession(1, 9)
+
+	assert.Equal(t, "error: revoked \"session\"", err.Error())
+}
+
+func TestRefreshExpiredToken(t *testing.T) {
+	now := time.Now()
+	mockRepo := &SessionMockRepository{session: models.Session{ID: 9, UserID: 1, LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}}
+
+	mockRepo.On("GetSessionByID").Return(nil)
+	mockRepo.On("TouchSession").Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	expired, _ := createToken(1, 9, now.Add(-time.Minute))
+
+	token, err := testUsecase.RefreshToken(expired)
+
+	assert.Nil(t, err)
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("SECRET_JWT_KEY")), nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, float64(9), parsed.Claims.(jwt.MapClaims)["session_id"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshTokenOfRevokedSession(t *testing.T) {
+	now := time.Now()
+	mockRepo := &SessionMockRepository{session: models.Session{ID: 9, UserID: 1, LastSeenAt: now, ExpiresAt: now.Add(time.Hour), RevokedAt: &now}}
+
+	mockRepo.On("GetSessionByID").Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	expired, _ := createToken(1, 9, now.Add(-time.Minute))
+
+	_, err := testUsecase.RefreshToken(expired)
+
+	assert.Equal(t, "error: revoked \"session\"", err.Error())
+}
+
+func TestRefreshForgedToken(t *testing.T) {
+	testUsecase := NewSessionUsecase(new(SessionMockRepository))
+
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1, "session_id": 9}).SignedString([]byte("not the key"))
+
+	_, err := testUsecase.RefreshToken(forged)
+
+	assert.Equal(t, "error: invalid \"session\"", err.Error())
+}
+
+func TestValidateSessionOfOtherUser(t *testing.T) {
+	now := time.Now()
+	mockRepo := &SessionMockRepository{session: models.Session{ID: 9, UserID: 2, LastSeenAt: now, ExpiresAt: now.Add(time.Minute)}}
+
+	mockRepo.On("GetSessionByID").Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	err := testUsecase.ValidateSession(1, 9)
+
+	assert.NotNil(t, err)
+}
+
+func TestValidateUnknownSession(t *testing.T) {
+	mockRepo := new(SessionMockRepository)
+
+	mockRepo.On("GetSessionByID").Return(fmt.Errorf("record not found"))
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	err := testUsecase.ValidateSession(1, 9)
+
+	assert.Equal(t, "error: invalid \"session\"", err.Error())
+}
+
+func TestRevokeSession(t *testing.T) {
+	mockRepo := new(SessionMockRepository)
+
+	mockRepo.On("RevokeSession", uint(9), uint(1)).Return(nil)
+
+	testUsecase := NewSessionUsecase(mockRepo)
+
+	err := testUsecase.RevokeSession(1, 9)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"log"
+	"summer-web/models"
+	"summer-web/stream"
+)
+
+// StreamUsecase interface defines the methods that are going to be used in usecase
+type StreamUsecase interface {
+	Subscribe(userID uint) *stream.Subscription
+	Unsubscribe(subscription *stream.Subscription)
+}
+
+var (
+	// the usecases publish to the hub whether or not anyone streams, so it is never nil
+	streamHub = stream.NewHub(stream.DefaultBufferSize)
+)
+
+type streamUsecase struct{}
+
+// NewStreamUsecase creates a new usecase to push notifications and feed posts to connected users
+func NewStreamUsecase(hub ...stream.Hub) StreamUsecase {
+	if len(hub) > 0 {
+		streamHub = hub[0]
+	}
+	return &streamUsecase{}
+}
+
+// Subscribe opens a connection for the user to receive events on until it is unsubscribed
+func (*streamUsecase) Subscribe(userID uint) *stream.Subscription {
+	return streamHub.Subscribe(userID)
+}
+
+// Unsubscribe closes the connection
+func (*streamUsecase) Unsubscribe(subscription *stream.Subscription) {
+	streamHub.Unsubscribe(subscription)
+}
+
+// publishPost pushes the new post to the author and to the followers whose feed shows it, the followers are only
+// looked up while someone is connected
+func publishPost(post models.Post) {
+	if streamHub.Connected() == 0 {
+		return
+	}
+
+	readerIDs, err := postRepo.GetFeedReaderIDs(post.UserID)
+
+	if err != nil {
+		log.Println("could not publish post", post.ID, err)
+		return
+	}
+
+	streamHub.Publish(stream.Event{Type: stream.EventPost, Data: post}, append(readerIDs, post.UserID)...)
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"os"
+	"summer-web/models"
+	"summer-web/suggestion/repository"
+	"time"
+)
+
+// SuggestionUsecase interface defines the methods that are going to be used in usecase
+type SuggestionUsecase interface {
+	GetSuggestions(userID uint, limit int) ([]models.Suggestion, error)
+	PrecomputeSuggestions() error
+}
+
+const (
+	// SuggestionActivityWindow is how far back posts count as recent activity of suggested accounts
+	SuggestionActivityWindow = 30 * 24 * time.Hour
+	// suggestionBatchSize is how many users PrecomputeSuggestions loads at once
+	suggestionBatchSize = 100
+)
+
+var (
+	suggestionRepo repository.SuggestionRepository
+)
+
+type suggestionUsecase struct{}
+
+// NewSuggestionUsecase creates a new usecase to fiddle around with repository
+func NewSuggestionUsecase(repo ...repository.SuggestionRepository) SuggestionUsecase {
+	if len(repo) > 0 {
+		suggestionRepo = repo[0]
+	} else {
+		suggestionRepo = repository.NewSuggestionRepository(nil)
+	}
+	return &suggestionUsecase{}
+}
+
+// SuggestionsPrecomputed tells whether suggestions are read from the ones PrecomputeSuggestions stores, set
+// SUGGESTIONS_PRECOMPUTE=true when the follow graph is too big to walk on every request
+func SuggestionsPrecomputed() bool {
+	return os.Getenv("SUGGESTIONS_PRECOMPUTE") == "true"
+}
+
+// GetSuggestions returns accounts the user may want to follow, the ones followed by most of the accounts the user
+// follows first. Users that were not precomputed yet get their suggestions computed on request
+func (*suggestionUsecase) GetSuggestions(userID uint, limit int) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+	var err error
+
+	if SuggestionsPrecomputed() {
+		suggestions, err = suggestionRepo.GetPrecomputedSuggestions(userID, PageSize(limit))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(suggestions) == 0 {
+		suggestions, err = suggestionRepo.GetSuggestions(userID, time.Now().Add(-SuggestionActivityWindow), PageSize(limit))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// accounts deleted after the suggestions were computed are not loaded
+	loaded := []models.Suggestion{}
+
+	for _, suggestion := range suggestions {
+		if suggestion.Suggested != nil {
+			loaded = append(loaded, suggestion)
+		}
+	}
+
+	return loaded, nil
+}
+
+// PrecomputeSuggestions computes and stores the suggestions of every user in batches, it runs periodically in the
+// background when SuggestionsPrecomputed
+func (*suggestionUsecase) PrecomputeSuggestions() error {
+	if !SuggestionsPrecomputed() {
+		return nil
+	}
+
+	activeSince := time.Now().Add(-SuggestionActivityWindow)
+	afterID := uint(0)
+
+	for {
+		ids, err := suggestionRepo.GetUserIDs(afterID, suggestionBatchSize)
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			suggestions, err := suggestionRepo.GetSuggestions(id, activeSince, MaxPageSize)
+
+			if err != nil {
+				return err
+			}
+
+			if err := suggestionRepo.ReplaceSuggestions(id, suggestions); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) < suggestionBatchSize {
+			return nil
+		}
+
+		afterID = ids[len(ids)-1]
+	}
+}
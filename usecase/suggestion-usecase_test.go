@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"os"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type SuggestionMockRepository struct {
+	mock.Mock
+}
+
+func (mock *SuggestionMockRepository) GetSuggestions(userID uint, activeSince time.Time, limit int) ([]models.Suggestion, error) {
+	args := mock.Called(userID, limit)
+	return args.Get(0).([]models.Suggestion), args.Error(1)
+}
+
+func (mock *SuggestionMockRepository) GetPrecomputedSuggestions(userID uint, limit int) ([]models.Suggestion, error) {
+	args := mock.Called(userID, limit)
+	return args.Get(0).([]models.Suggestion), args.Error(1)
+}
+
+func (mock *SuggestionMockRepository) ReplaceSuggestions(userID uint, suggestions []models.Suggestion) error {
+	args := mock.Called(userID, suggestions)
+	return args.Error(0)
+}
+
+func (mock *SuggestionMockRepository) GetUserIDs(afterID uint, limit int) ([]uint, error) {
+	args := mock.Called(afterID, limit)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func TestGetSuggestions(t *testing.T) {
+	mockRepo := new(SuggestionMockRepository)
+
+	mockRepo.On("GetSuggestions", uint(1), DefaultPageSize).Return([]models.Suggestion{
+		{UserID: 1, SuggestedID: 4, MutualCount: 3, Suggested: &models.User{ID: 4}},
+		{UserID: 1, SuggestedID: 5, MutualCount: 1},
+	}, nil)
+
+	testUsecase := NewSuggestionUsecase(mockRepo)
+
+	suggestions, err := testUsecase.GetSuggestions(1, 0)
+
+	mockRepo.AssertNotCalled(t, "GetPrecomputedSuggestions", uint(1), DefaultPageSize)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(suggestions))
+	assert.Equal(t, uint(4), suggestions[0].SuggestedID)
+}
+
+func TestGetPrecomputedSuggestions(t *testing.T) {
+	os.Setenv("SUGGESTIONS_PRECOMPUTE", "true")
+	defer os.Unsetenv("SUGGESTIONS_PRECOMPUTE")
+
+	mockRepo := new(SuggestionMockRepository)
+
+	mockRepo.On("GetPrecomputedSuggestions", uint(1), 5).Return([]models.Suggestion{{SuggestedID: 4, Suggested: &models.User{ID: 4}}}, nil)
+
+	testUsecase := NewSuggestionUsecase(mockRepo)
+
+	suggestions, err := testUsecase.GetSuggestions(1, 5)
+
+	mockRepo.AssertNotCalled(t, "GetSuggestions", uint(1), 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(suggestions))
+}
+
+func TestGetSuggestionsNotPrecomputedYet(t *testing.T) {
+	os.Setenv("SUGGESTIONS_PRECOMPUTE", "true")
+	defer os.Unsetenv("SUGGESTIONS_PRECOMPUTE")
+
+	mockRepo := new(SuggestionMockRepository)
+
+	mockRepo.On("GetPrecomputedSuggestions", uint(1), 5).Return([]models.Suggestion{}, nil)
+	mockRepo.On("GetSuggestions", uint(1), 5).Return([]models.Suggestion{{SuggestedID: 4, Suggested: &models.User{ID: 4}}}, nil)
+
+	testUsecase := NewSuggestionUsecase(mockRepo)
+
+	suggestions, err := testUsecase.GetSuggestions(1, 5)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(suggestions))
+}
+
+func TestPrecomputeSuggestions(t *testing.T) {
+	os.Setenv("SUGGESTIONS_PRECOMPUTE", "true")
+	defer os.Unsetenv("SUGGESTIONS_PRECOMPUTE")
+
+	mockRepo := new(SuggestionMockRepository)
+
+	batch := make([]uint, suggestionBatchSize)
+
+	for i := range batch {
+		batch[i] = uint(i + 1)
+	}
+
+	suggestions := []models.Suggestion{{SuggestedID: 4, MutualCount: 2}}
+
+	mockRepo.On("GetUserIDs", uint(0), suggestionBatchSize).Return(batch, nil)
+	mockRepo.On("GetUserIDs", uint(suggestionBatchSize), suggestionBatchSize).Return([]uint{}, nil)
+	mockRepo.On("GetSuggestions", mock.Anything, MaxPageSize).Return(suggestions, nil)
+	mockRepo.On("ReplaceSuggestions", mock.Anything, suggestions).Return(nil)
+
+	testUsecase := NewSuggestionUsecase(mockRepo)
+
+	err := testUsecase.PrecomputeSuggestions()
+
+	assert.Nil(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "ReplaceSuggestions", suggestionBatchSize)
+}
+
+func TestPrecomputeSuggestionsDisabled(t *testing.T) {
+	mockRepo := new(SuggestionMockRepository)
+
+	testUsecase := NewSuggestionUsecase(mockRepo)
+
+	err := testUsecase.PrecomputeSuggestions()
+
+	assert.Nil(t, err)
+	mockRepo.AssertNotCalled(t, "GetUserIDs", uint(0), suggestionBatchSize)
+}
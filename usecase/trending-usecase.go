@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	blockRepository "summer-web/block/repository"
+	"summer-web/models"
+	"summer-web/post/repository"
+	"sync"
+	"time"
+)
+
+// TrendingUsecase interface defines the methods that are going to be used in usecase
+type TrendingUsecase interface {
+	ComputeTrending() error
+	GetTrending(viewerID uint, hours int, limit int) ([]models.TrendingTag, error)
+}
+
+const (
+	// TrendingWindow is how far back hashtags are counted to tell whether they trend, unless the viewer asks for another
+	// window of whole hours
+	TrendingWindow = 24 * time.Hour
+	// MaxTrendingHours is the longest trending window viewers can ask for
+	MaxTrendingHours = 7 * 24
+	// the windows before the trending window tell how much a hashtag is usually used
+	trendingBaselineWindows = 7
+	// uses of a hashtag count half as much this many times over the trending window, so the latest hours weigh the most
+	trendingHalfLives = 4
+	// only the best scoring hashtags of a run are kept until the next one
+	maxTrendingCandidates = 500
+)
+
+var (
+	// ErrInvalidTrendingWindow is returned for trending windows longer than MaxTrendingHours
+	ErrInvalidTrendingWindow = fmt.Errorf("error: invalid \"hours\"")
+)
+
+// tagUse is how much an author used a hashtag, recent is the decayed count in the trending window and baseline the
+// hourly count before it
+type tagUse struct {
+	recent   float64
+	baseline float64
+	count    int
+}
+
+type trendingCandidate struct {
+	tag     string
+	authors map[uint]*tagUse
+}
+
+// score returns how much more the hashtag was used in the trending window than usual and how many times it was used,
+// leaving out the uses of the excluded authors
+func (c trendingCandidate) score(excluded map[uint]bool, window time.Duration) (float64, int) {
+	var recent, baseline float64
+	count := 0
+
+	for userID, use := range c.authors {
+		if excluded[userID] {
+			continue
+		}
+
+		recent += use.recent
+		baseline += use.baseline
+		count += use.count
+	}
+
+	return recent - baseline*decayedHours(window), count
+}
+
+// decayedHours is how much a hashtag used once every hour of the trending window adds up to with decay
+func decayedHours(window time.Duration) float64 {
+	total := 0.0
+
+	for hour := 0; hour < int(window/time.Hour); hour++ {
+		total += decay(time.Duration(hour)*time.Hour, window)
+	}
+
+	return total
+}
+
+func decay(age time.Duration, window time.Duration) float64 {
+	return math.Pow(0.5, float64(age)*trendingHalfLives/float64(window))
+}
+
+// trendingCache keeps the candidates of the last run of each trending window viewers asked for, viewers get them
+// scored without the authors they blocked
+type trendingCache struct {
+	mu   sync.RWMutex
+	runs map[time.Duration][]trendingCandidate
+}
+
+var trending = &trendingCache{runs: map[time.Duration][]trendingCandidate{}}
+
+type trendingUsecase struct{}
+
+// NewTrendingUsecase creates a new usecase to fiddle around with repository
+func NewTrendingUsecase(repo ...repository.PostRepository) TrendingUsecase {
+	if len(repo) > 0 {
+		postRepo = repo[0]
+	} else {
+		postRepo = repository.NewPostRepository(nil)
+		blockRepo = blockRepository.NewBlockRepository(nil)
+	}
+	return &trendingUsecase{}
+}
+
+// ComputeTrending counts the hashtags of each cached trending window and the windows before and caches the ones
+// growing fastest, it runs periodically in the background
+func (*trendingUsecase) ComputeTrending() error {
+	windows := map[time.Duration]bool{TrendingWindow: true}
+
+	trending.mu.RLock()
+	for window := range trending.runs {
+		windows[window] = true
+	}
+	trending.mu.RUnlock()
+
+	for window := range windows {
+		if err := computeTrending(window); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTrending returns the hashtags growing fastest in the last hours, or in TrendingWindow when hours is 0. Uses by
+// accounts the viewer blocked or that blocked the viewer are not counted
+func (*trendingUsecase) GetTrending(viewerID uint, hours int, limit int) ([]models.TrendingTag, error) {
+	if hours < 0 || hours > MaxTrendingHours {
+		return nil, ErrInvalidTrendingWindow
+	}
+
+	window := TrendingWindow
+
+	if hours > 0 {
+		window = time.Duration(hours) * time.Hour
+	}
+
+	trending.mu.RLock()
+	_, computed := trending.runs[window]
+	trending.mu.RUnlock()
+
+	if !computed {
+		if err := computeTrending(window); err != nil {
+			return nil, err
+		}
+	}
+
+	blocks, err := blockRepo.GetBlocksByUserID(viewerID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[uint]bool{}
+
+	for _, block := range blocks {
+		excluded[block.BlockerID] = true
+		excluded[block.BlockedID] = true
+	}
+
+	delete(excluded, viewerID)
+
+	trending.mu.RLock()
+	tags := rankTrending(trending.runs[window], excluded, window)
+	trending.mu.RUnlock()
+
+	if limit = PageSize(limit); len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+// computeTrending counts the hashtags of the trending window and the windows before and caches the ones growing fastest
+func computeTrending(window time.Duration) error {
+	now := time.Now()
+
+	counts, err := postRepo.GetTagCounts(now.Add(-window * (trendingBaselineWindows + 1)))
+
+	if err != nil {
+		return err
+	}
+
+	candidates := trendingCandidates(counts, now, window)
+
+	trending.mu.Lock()
+	trending.runs[window] = candidates
+	trending.mu.Unlock()
+
+	return nil
+}
+
+// trendingCandidates adds up the counts per hashtag and author and keeps the hashtags that score best
+func trendingCandidates(counts []models.TagCount, now time.Time, window time.Duration) []trendingCandidate {
+	byTag := map[string]map[uint]*tagUse{}
+	baselineHours := float64(window/time.Hour) * trendingBaselineWindows
+
+	for _, count := range counts {
+		if byTag[count.Tag] == nil {
+			byTag[count.Tag] = map[uint]*tagUse{}
+		}
+
+		use := byTag[count.Tag][count.UserID]
+
+		if use == nil {
+			use = &tagUse{}
+			byTag[count.Tag][count.UserID] = use
+		}
+
+		if age := now.Sub(count.Hour); age < window {
+			use.recent += float64(count.Count) * decay(age, window)
+			use.count += count.Count
+		} else {
+			use.baseline += float64(count.Count) / baselineHours
+		}
+	}
+
+	candidates := []trendingCandidate{}
+
+	for tag, authors := range byTag {
+		candidates = append(candidates, trendingCandidate{tag: tag, authors: authors})
+	}
+
+	scores := map[string]float64{}
+
+	for _, candidate := range candidates {
+		scores[candidate.tag], _ = candidate.score(nil, window)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i].tag] != scores[candidates[j].tag] {
+			return scores[candidates[i].tag] > scores[candidates[j].tag]
+		}
+		return candidates[i].tag < candidates[j].tag
+	})
+
+	if len(candidates) > maxTrendingCandidates {
+		candidates = candidates[:maxTrendingCandidates]
+	}
+
+	return candidates
+}
+
+// rankTrending scores the candidates without the excluded authors, best first. Hashtags used as much as usual or less
+// are left out
+func rankTrending(candidates []trendingCandidate, excluded map[uint]bool, window time.Duration) []models.TrendingTag {
+	tags := []models.TrendingTag{}
+
+	for _, candidate := range candidates {
+		score, count := candidate.score(excluded, window)
+
+		if score <= 0 || count == 0 {
+			continue
+		}
+
+		tags = append(tags, models.TrendingTag{Tag: candidate.tag, Score: math.Round(score*100) / 100, Count: count})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Score > tags[j].Score
+	})
+
+	return tags
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrendingGrowth(t *testing.T) {
+	now := time.Date(2020, 6, 10, 12, 30, 0, 0, time.UTC)
+	hour := now.Truncate(time.Hour)
+
+	counts := []models.TagCount{
+		// steady: used as much in the window as in every window before
+		{Tag: "steady", UserID: 1, Hour: hour.Add(-48 * time.Hour), Count: 168},
+		{Tag: "steady", UserID: 1, Hour: hour.Add(-12 * time.Hour), Count: 24},
+		// new and growing
+		{Tag: "summer", UserID: 2, Hour: hour, Count: 5},
+		{Tag: "summer", UserID: 3, Hour: hour.Add(-time.Hour), Count: 2},
+		// used a lot, but a day ago
+		{Tag: "yesterday", UserID: 2, Hour: hour.Add(-23 * time.Hour), Count: 10},
+	}
+
+	tags := rankTrending(trendingCandidates(counts, now, TrendingWindow), nil, TrendingWindow)
+
+	assert.Equal(t, []string{"summer", "yesterday"}, trendingNames(tags))
+	assert.Equal(t, 7, tags[0].Count)
+	assert.True(t, tags[0].Score > tags[1].Score)
+}
+
+func TestTrendingExcludesAuthors(t *testing.T) {
+	now := time.Date(2020, 6, 10, 12, 30, 0, 0, time.UTC)
+	hour := now.Truncate(time.Hour)
+
+	counts := []models.TagCount{
+		{Tag: "summer", UserID: 2, Hour: hour, Count: 5},
+		{Tag: "summer", UserID: 3, Hour: hour, Count: 1},
+		{Tag: "spam", UserID: 4, Hour: hour, Count: 50},
+	}
+
+	tags := rankTrending(trendingCandidates(counts, now, TrendingWindow), map[uint]bool{4: true, 3: true}, TrendingWindow)
+
+	assert.Equal(t, []string{"summer"}, trendingNames(tags))
+	assert.Equal(t, 5, tags[0].Count)
+}
+
+func TestGetTrending(t *testing.T) {
+	trending = &trendingCache{runs: map[time.Duration][]trendingCandidate{}}
+
+	mockRepo := new(PostMockRepository)
+	mockBlockRepo := new(BlockMockRepository)
+
+	hour := time.Now().Truncate(time.Hour)
+
+	mockRepo.On("GetTagCounts").Return([]models.TagCount{
+		{Tag: "summer", UserID: 2, Hour: hour, Count: 5},
+		{Tag: "blocked", UserID: 3, Hour: hour, Count: 9},
+		{Tag: "mine", UserID: 1, Hour: hour, Count: 1},
+	}, nil).Once()
+	mockBlockRepo.On("GetBlocksByUserID").Return([]models.Block{{BlockerID: 1, BlockedID: 3}}, nil)
+
+	testUsecase := NewTrendingUsecase(mockRepo)
+	NewBlockUsecase(mockBlockRepo)
+
+	tags, err := testUsecase.GetTrending(1, 0, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"summer", "mine"}, trendingNames(tags))
+
+	// the cached run is used until the next one
+	tags, err = testUsecase.GetTrending(1, 0, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"summer"}, trendingNames(tags))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetTrendingWindows(t *testing.T) {
+	trending = &trendingCache{runs: map[time.Duration][]trendingCandidate{}}
+
+	mockRepo := new(PostMockRepository)
+	mockBlockRepo := new(BlockMockRepository)
+
+	hour := time.Now().Truncate(time.Hour)
+
+	mockRepo.On("GetTagCounts").Return([]models.TagCount{
+		{Tag: "summer", UserID: 2, Hour: hour, Count: 5},
+		{Tag: "earlier", UserID: 3, Hour: hour.Add(-72 * time.Hour), Count: 9},
+	}, nil).Twice()
+	mockBlockRepo.On("GetBlocksByUserID").Return([]models.Block{}, nil)
+
+	testUsecase := NewTrendingUsecase(mockRepo)
+	NewBlockUsecase(mockBlockRepo)
+
+	tags, err := testUsecase.GetTrending(1, 0, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"summer"}, trendingNames(tags))
+
+	tags, err = testUsecase.GetTrending(1, 96, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"summer", "earlier"}, trendingNames(tags))
+
+	// each window is cached on its own
+	tags, err = testUsecase.GetTrending(1, 0, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"summer"}, trendingNames(tags))
+	mockRepo.AssertExpectations(t)
+
+	_, err = testUsecase.GetTrending(1, MaxTrendingHours+1, 0)
+
+	assert.Equal(t, ErrInvalidTrendingWindow, err)
+}
+
+func trendingNames(tags []models.TrendingTag) []string {
+	names := []string{}
+
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+
+	return names
+}
@@ -2,25 +2,63 @@ package usecase
 
 import (
 	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"regexp"
+	"strings"
+	auditRepository "summer-web/audit/repository"
+	"summer-web/blobstore"
+	blockRepository "summer-web/block/repository"
+	"summer-web/mailer"
 	"summer-web/models"
+	notificationRepository "summer-web/notification/repository"
+	sessionRepository "summer-web/session/repository"
 	"summer-web/user/repository"
+	webhookRepository "summer-web/webhook/repository"
 	"time"
+	"unicode/utf8"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm"
 )
 
 // UserUsecase interface defines the methods that are going to be used in usecase
 type UserUsecase interface {
 	GetUserByID(id uint, user *models.User) error
+	GetUserProfile(viewerID uint, id uint, user *models.User) error
+	GetUserByUsername(viewerID uint, username string, user *models.User) error
 	AddUser(user *models.User) error
-	Login(loginData models.User) (string, error)
+	Login(loginData models.User, ip string, userAgent string) (string, bool, error)
 	UpdateUser(updatedData models.User) error
+	SetAvatar(id uint, image []byte, user *models.User) error
+	DeleteUser(id uint, password string) error
+	PurgeDeletedUsers() error
+	ChangePassword(id uint, oldPassword string, newPassword string, ip string, userAgent string) (string, error)
 }
 
 var (
-	userRepo repository.UserRepository
+	// ErrUserNotFound is returned when there is no user, or only a deleted one, with the id or username
+	ErrUserNotFound = fmt.Errorf("error: not found \"users_id_key\"")
+	// ErrIncorrectPassword is returned when the password confirming a change to the account is wrong
+	ErrIncorrectPassword = fmt.Errorf("error: incorrect \"users_password_key\"")
+	// ErrUnchangedPassword is returned when the new password is the old one
+	ErrUnchangedPassword = fmt.Errorf("error: unchanged \"users_password_key\"")
+	// ErrWeakPassword is returned for passwords that don't follow the password policy
+	ErrWeakPassword = fmt.Errorf("error: too weak \"users_password_key\"")
+)
+
+const (
+	maxBioLength      = 160
+	maxLocationLength = 60
+)
+
+// AccountDeletionGracePeriod is how long a deleted account can still be reactivated by logging in
+const AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
+var (
+	userRepo  repository.UserRepository
+	auditRepo auditRepository.AuditRepository
 )
 
 type userUsecase struct{}
@@ -31,42 +69,307 @@ func NewUserUsecase(repo ...repository.UserRepository) UserUsecase {
 		userRepo = repo[0]
 	} else {
 		userRepo = repository.NewUserRepository(nil)
+		auditRepo = auditRepository.NewAuditRepository(nil)
+		sessionRepo = sessionRepository.NewSessionRepository(nil)
+		blockRepo = blockRepository.NewBlockRepository(nil)
+		notificationRepo = notificationRepository.NewNotificationRepository(nil)
+		webhookRepo = webhookRepository.NewWebhookRepository(nil)
+		blobStore = blobstore.NewBlobStore()
+	}
+	if userMailer == nil {
+		userMailer = mailer.NewMailer()
 	}
 	return &userUsecase{}
 }
 
 func (*userUsecase) GetUserByID(id uint, user *models.User) error {
-	return userRepo.GetUserByID(id, user)
+	return notFoundAsErrUserNotFound(userRepo.GetUserByID(id, user))
+}
+
+// GetUserProfile returns the user as seen by the viewer, users that blocked each other are not found
+func (*userUsecase) GetUserProfile(viewerID uint, id uint, user *models.User) error {
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByID(id, user)); err != nil {
+		return err
+	}
+
+	return errIfBlocked(viewerID, user.ID)
+}
+
+// GetUserByUsername returns the user with the username as seen by the viewer, users that blocked each other are not found
+func (*userUsecase) GetUserByUsername(viewerID uint, username string, user *models.User) error {
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByUsername(username, user)); err != nil {
+		return err
+	}
+
+	return errIfBlocked(viewerID, user.ID)
 }
 
 func (*userUsecase) AddUser(user *models.User) error {
 	if err := validateUser(user); err != nil {
 		return err
 	}
-	return userRepo.AddUser(user)
+
+	if err := validateProfile(user); err != nil {
+		return err
+	}
+
+	if err := errIfLoginTaken(*user); err != nil {
+		return err
+	}
+
+	user.EmailVerifiedAt = nil
+
+	if err := userRepo.AddUser(user); err != nil {
+		return err
+	}
+
+	trySendVerificationEmail(*user)
+	publishWebhook(models.WebhookUserCreated, userWebhookData(*user))
+
+	return nil
 }
 
+// UpdateUser saves the updated data and profile fields, a changed email has to be verified again
 func (*userUsecase) UpdateUser(updatedData models.User) error {
-	return userRepo.UpdateUser(updatedData)
+	var currentUser models.User
+
+	if err := userRepo.GetUserByID(updatedData.ID, &currentUser); err != nil {
+		return err
+	}
+
+	if err := validateProfile(&updatedData); err != nil {
+		return err
+	}
+
+	if strings.Contains(updatedData.Username, "@") {
+		return fmt.Errorf("error: invalid \"users_username_key\"")
+	}
+
+	if err := errIfLoginTaken(updatedData); err != nil {
+		return err
+	}
+
+	if err := userRepo.UpdateUser(updatedData); err != nil {
+		return err
+	}
+
+	if err := userRepo.UpdateProfile(updatedData); err != nil {
+		return err
+	}
+
+	if updatedData.Email == "" || updatedData.Email == currentUser.Email {
+		return nil
+	}
+
+	if err := userRepo.SetEmailVerifiedAt(updatedData.ID, nil); err != nil {
+		return err
+	}
+
+	trySendVerificationEmail(updatedData)
+
+	return nil
 }
 
-func (*userUsecase) Login(loginData models.User) (string, error) {
+// SetAvatar crops the image to a square and stores it in every size, replacing the previous avatar and the avatar URL
+// set on the profile. The user is modified to the updated account
+func (*userUsecase) SetAvatar(id uint, image []byte, user *models.User) error {
+	if err := notFoundAsErrUserNotFound(userRepo.GetUserByID(id, user)); err != nil {
+		return err
+	}
+
+	key, err := storeAvatar(id, image)
+
+	if err != nil {
+		return err
+	}
+
+	if err := userRepo.SetAvatar(id, key); err != nil {
+		deleteAvatar(key)
+		return err
+	}
+
+	deleteAvatar(user.AvatarKey)
+
+	user.AvatarKey = key
+	user.AvatarURL = ""
+
+	return nil
+}
+
+func (*userUsecase) DeleteUser(id uint, password string) error {
+	var user models.User
+
+	if err := userRepo.GetUserByID(id, &user); err != nil {
+		return err
+	}
+
+	if password == "" || password != user.Password {
+		return ErrIncorrectPassword
+	}
+
+	if err := userRepo.DeleteUser(id); err != nil {
+		return err
+	}
+
+	// personal access tokens are revoked too, so they don't come back to life when the account is reactivated
+	return revokeAllSessions(id, time.Now())
+}
+
+// ChangePassword replaces the password after checking the old one, logs out every session and returns the token of a new one
+func (*userUsecase) ChangePassword(id uint, oldPassword string, newPassword string, ip string, userAgent string) (string, error) {
+	var user models.User
+
+	if err := userRepo.GetUserByID(id, &user); err != nil {
+		return "", err
+	}
+
+	if oldPassword == "" || oldPassword != user.Password {
+		return "", ErrIncorrectPassword
+	}
+
+	if newPassword == oldPassword {
+		return "", ErrUnchangedPassword
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return "", err
+	}
+
+	if err := userRepo.UpdateUser(models.User{ID: id, Password: newPassword}); err != nil {
+		return "", err
+	}
+
+	if err := revokeAllSessions(id, time.Now()); err != nil {
+		return "", err
+	}
+
+	return createSession(id, ip, userAgent)
+}
+
+// PurgeDeletedUsers permanently removes the accounts whose grace period has passed along with their posts
+func (*userUsecase) PurgeDeletedUsers() error {
+	users, err := userRepo.GetUsersDeletedBefore(time.Now().Add(-AccountDeletionGracePeriod))
+
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		userMedia, err := postRepo.GetMediaByUserID(user.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if err := postRepo.DeletePostsByUserID(user.ID); err != nil {
+			return err
+		}
+
+		if err := notificationRepo.DeleteNotificationsByUserID(user.ID); err != nil {
+			return err
+		}
+
+		deleteBlobs(userMedia)
+
+		if err := userRepo.PurgeUser(user.ID); err != nil {
+			return err
+		}
+
+		deleteAvatar(user.AvatarKey)
+	}
+
+	return nil
+}
+
+// Login accepts the username or the email in loginData.Username, failed attempts are throttled per account and per IP.
+// When the user has two factor login enabled it returns an mfa pending token and true instead of an auth token
+func (*userUsecase) Login(loginData models.User, ip string, userAgent string) (string, bool, error) {
 	var attemptedUser models.User
 
-	err := userRepo.GetUserByUsername(loginData.Username, &attemptedUser)
+	deleted := false
+	err := userRepo.GetUserByLogin(loginData.Username, &attemptedUser)
+
+	if err != nil {
+		err = userRepo.GetDeletedUserByLogin(loginData.Username, &attemptedUser)
+		deleted = err == nil && attemptedUser.DeletedAt != nil
+	}
+
+	accountKey := "login:" + strings.ToLower(loginData.Username)
+	if err == nil {
+		accountKey = userThrottleKey(attemptedUser.ID)
+	}
+	ipKey := "ip:" + ip
+
+	now := time.Now()
+	wait := loginAttempts.retryAfter(accountKey, now)
+
+	if ipWait := loginAttempts.retryAfter(ipKey, now); ipWait > wait {
+		wait = ipWait
+	}
+
+	if wait > 0 {
+		return "", false, &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	// deleted accounts can only be reactivated within the grace period
+	expired := deleted && now.Sub(*attemptedUser.DeletedAt) > AccountDeletionGracePeriod
+
+	if err != nil || expired || loginData.Password == "" || loginData.Password != attemptedUser.Password {
+		recordFailedLogin(attemptedUser.ID, accountKey, ipKey, ip, now)
+		return "", false, fmt.Errorf("please provide a correct credentials")
+	}
+
+	// the failed attempts are only forgotten, and a deleted account only reactivated, once the second factor is
+	// verified too
+	if attemptedUser.TOTPEnabled {
+		mfaToken, err := createMFAPendingToken(attemptedUser.ID)
+		return mfaToken, true, err
+	}
+
+	if deleted {
+		if err := userRepo.RestoreUser(attemptedUser.ID); err != nil {
+			return "", false, err
+		}
+	}
+
+	loginAttempts.reset(accountKey)
+
+	token, err := createSession(attemptedUser.ID, ip, userAgent)
+
+	return token, false, err
+}
+
+// recordFailedLogin counts the failure against the account and the IP, and writes an audit entry when either gets locked out
+func recordFailedLogin(userID uint, accountKey string, ipKey string, ip string, now time.Time) {
+	if lockout := loginAttempts.fail(accountKey, accountAttemptLimit, now); lockout > 0 {
+		addAuditLog(models.AuditLog{UserID: userID, Action: "login_lockout", IP: ip, Detail: accountKey + " locked out for " + lockout.String()})
+	}
+
+	if lockout := loginAttempts.fail(ipKey, ipAttemptLimit, now); lockout > 0 {
+		addAuditLog(models.AuditLog{UserID: userID, Action: "login_lockout", IP: ip, Detail: ipKey + " locked out for " + lockout.String()})
+	}
+}
 
-	if err != nil || loginData.Password != attemptedUser.Password {
-		return "", fmt.Errorf("please provide a correct credentials")
+func notFoundAsErrUserNotFound(err error) error {
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrUserNotFound
 	}
+	return err
+}
 
-	return createToken(attemptedUser.ID)
+func addAuditLog(entry models.AuditLog) {
+	if err := auditRepo.AddAuditLog(&entry); err != nil {
+		log.Println("could not write audit log", entry.Action, err)
+	}
 }
 
-func createToken(id uint) (string, error) {
+func createToken(id uint, sessionID uint, expiresAt time.Time) (string, error) {
 	atClaims := jwt.MapClaims{}
 	atClaims["authorized"] = true
 	atClaims["user_id"] = id
-	atClaims["exp"] = time.Now().Add(time.Minute * 15).Unix()
+	atClaims["session_id"] = sessionID
+	atClaims["iat"] = time.Now().Unix()
+	atClaims["exp"] = expiresAt.Unix()
 	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
 	token, err := at.SignedString([]byte(os.Getenv("SECRET_JWT_KEY")))
 	if err != nil {
@@ -75,6 +378,17 @@ func createToken(id uint) (string, error) {
 	return token, err
 }
 
+// validatePassword enforces the password policy: at least 8 characters with both letters and digits
+func validatePassword(password string) error {
+	hasLetter := regexp.MustCompile("[a-zA-Z]").MatchString(password)
+	hasDigit := regexp.MustCompile("[0-9]").MatchString(password)
+
+	if len(password) < 8 || !hasLetter || !hasDigit {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
 func validateUser(user *models.User) error {
 	re := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
@@ -90,5 +404,67 @@ func validateUser(user *models.User) error {
 	if !re.MatchString(user.Email) {
 		return fmt.Errorf("error: invalid \"users_email_key\"")
 	}
+	// usernames and emails both log in, a username that looks like an email could shadow someone's email
+	if strings.Contains(user.Username, "@") {
+		return fmt.Errorf("error: invalid \"users_username_key\"")
+	}
 	return nil
 }
+
+// errIfLoginTaken returns an error if another user has the username or the email of the user in any case, empty
+// fields are not checked
+func errIfLoginTaken(user models.User) error {
+	if user.Username != "" {
+		taken, err := userRepo.IsUsernameTaken(user.Username, user.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if taken {
+			return fmt.Errorf("error: already exists \"users_username_key\"")
+		}
+	}
+
+	if user.Email != "" {
+		taken, err := userRepo.IsEmailTaken(user.Email, user.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if taken {
+			return fmt.Errorf("error: already exists \"users_email_key\"")
+		}
+	}
+
+	return nil
+}
+
+// validateProfile trims the profile fields and checks their lengths, empty fields are allowed and clear the field
+func validateProfile(user *models.User) error {
+	user.Bio = strings.TrimSpace(user.Bio)
+	user.Website = strings.TrimSpace(user.Website)
+	user.Location = strings.TrimSpace(user.Location)
+	user.AvatarURL = strings.TrimSpace(user.AvatarURL)
+
+	if utf8.RuneCountInString(user.Bio) > maxBioLength {
+		return fmt.Errorf("error: too long \"users_bio_key\"")
+	}
+	if utf8.RuneCountInString(user.Location) > maxLocationLength {
+		return fmt.Errorf("error: too long \"users_location_key\"")
+	}
+	if user.Website != "" && !isWebURL(user.Website) {
+		return fmt.Errorf("error: invalid \"users_website_key\"")
+	}
+	if user.AvatarURL != "" && !isWebURL(user.AvatarURL) {
+		return fmt.Errorf("error: invalid \"users_avatar_url_key\"")
+	}
+	return nil
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
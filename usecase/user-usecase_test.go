@@ -1,15 +1,29 @@
 package usecase
 
 import (
+	"fmt"
+	"strings"
 	"summer-web/models"
 	"testing"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 type UserMockRepository struct {
 	mock.Mock
+	totpSecret  string
+	totpEnabled bool
+	// the TOTP step and mfa pending token nonce the repository keeps, like it would in the users table
+	totpLastStep int64
+	mfaNonce     string
+	isPrivate    bool
+	isAdmin      bool
+	avatarKey    string
+	// usernames and emails other users have, ignoring case
+	taken []string
 }
 
 func (mock *UserMockRepository) GetUserByID(id uint, user *models.User) error {
@@ -23,6 +37,20 @@ func (mock *UserMockRepository) GetUserByID(id uint, user *models.User) error {
 	user.Name = "joko"
 	user.Password = "123"
 
+	user.TOTPSecret = mock.totpSecret
+	user.TOTPEnabled = mock.totpEnabled
+	user.TOTPLastStep = mock.totpLastStep
+	user.MFANonce = mock.mfaNonce
+	user.IsPrivate = mock.isPrivate
+	user.IsAdmin = mock.isAdmin
+	user.AvatarKey = mock.avatarKey
+
+	// a second return value of false leaves the email unverified
+	if len(args) < 2 || args.Bool(1) {
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+
 	return args.Error(0)
 }
 
@@ -52,6 +80,183 @@ func (mock *UserMockRepository) UpdateUser(updatedData models.User) error {
 	return args.Error(0)
 }
 
+func (mock *UserMockRepository) UpdateProfile(user models.User) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) SetAvatar(id uint, key string) error {
+	args := mock.Called(id, key)
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) IsUsernameTaken(username string, exceptID uint) (bool, error) {
+	return mock.isTaken(username), nil
+}
+
+func (mock *UserMockRepository) IsEmailTaken(email string, exceptID uint) (bool, error) {
+	return mock.isTaken(email), nil
+}
+
+func (mock *UserMockRepository) isTaken(login string) bool {
+	for _, taken := range mock.taken {
+		if strings.EqualFold(taken, login) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mock *UserMockRepository) AdjustFollowCounts(followerID uint, followeeID uint, delta int) error {
+	args := mock.Called(delta)
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) DeleteUser(id uint) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) GetUserByLogin(login string, user *models.User) error {
+	args := mock.Called()
+
+	user.ID = 1
+	user.Username = "joko"
+	user.Email = "joko@joko.com"
+	user.Name = "joko"
+	user.Password = "123"
+	user.TOTPSecret = mock.totpSecret
+	user.TOTPEnabled = mock.totpEnabled
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) GetDeletedUserByLogin(login string, user *models.User) error {
+	args := mock.Called()
+
+	deletedAt := args.Get(0).(time.Time)
+
+	user.Username = login
+	user.ID = 1
+	user.Password = "123"
+	user.DeletedAt = &deletedAt
+
+	return args.Error(1)
+}
+
+func (mock *UserMockRepository) GetDeletedUserByID(id uint, user *models.User) error {
+	args := mock.Called()
+
+	deletedAt := args.Get(0).(time.Time)
+
+	user.ID = id
+	user.DeletedAt = &deletedAt
+	user.TOTPSecret = mock.totpSecret
+	user.TOTPEnabled = mock.totpEnabled
+	user.TOTPLastStep = mock.totpLastStep
+	user.MFANonce = mock.mfaNonce
+
+	return args.Error(1)
+}
+
+func (mock *UserMockRepository) RestoreUser(id uint) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) GetUsersDeletedBefore(deadline time.Time) ([]models.User, error) {
+	args := mock.Called()
+
+	result := args.Get(0)
+
+	return result.([]models.User), args.Error(1)
+}
+
+func (mock *UserMockRepository) PurgeUser(id uint) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) SetEmailVerifiedAt(id uint, verifiedAt *time.Time) error {
+	args := mock.Called(verifiedAt == nil)
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) GetUserByEmail(email string, user *models.User) error {
+	args := mock.Called()
+
+	user.ID = 1
+	user.Username = "joko123"
+	user.Email = email
+	user.Name = "joko"
+
+	// a second return value of true marks the email verified
+	if len(args) > 1 && args.Bool(1) {
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) RevokeSessions(id uint, revokedAt time.Time) error {
+	args := mock.Called()
+
+	return args.Error(0)
+}
+
+type AuditMockRepository struct {
+	mock.Mock
+	entries []models.AuditLog
+}
+
+func (mock *AuditMockRepository) AddAuditLog(entry *models.AuditLog) error {
+	args := mock.Called()
+
+	mock.entries = append(mock.entries, *entry)
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) SetTOTP(id uint, secret string, enabled bool) error {
+	args := mock.Called(enabled)
+
+	mock.totpSecret = secret
+	mock.totpEnabled = enabled
+
+	return args.Error(0)
+}
+
+func (mock *UserMockRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	if step <= mock.totpLastStep {
+		return false, nil
+	}
+
+	mock.totpLastStep = step
+	return true, nil
+}
+
+func (mock *UserMockRepository) SetMFANonce(id uint, nonce string) error {
+	mock.mfaNonce = nonce
+	return nil
+}
+
+func (mock *UserMockRepository) UseMFANonce(id uint, nonce string) (bool, error) {
+	if nonce == "" || nonce != mock.mfaNonce {
+		return false, nil
+	}
+
+	mock.mfaNonce = ""
+	return true, nil
+}
+
 func TestAddingEmptyUsername(t *testing.T) {
 	assert := assert.New(t)
 
@@ -125,6 +330,18 @@ func TestGetUserByID(t *testing.T) {
 	assert.Equal(t, id, user.ID)
 }
 
+func TestGetUserByUsernameNotFound(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+
+	mockRepo.On("GetUserByUsername").Return(gorm.ErrRecordNotFound)
+
+	var user models.User
+	err := testUsecase.GetUserByUsername(1, "nobody", &user)
+
+	assert.Equal(t, ErrUserNotFound, err)
+}
+
 func TestAddUser(t *testing.T) {
 	mockRepo := new(UserMockRepository)
 
@@ -132,12 +349,57 @@ func TestAddUser(t *testing.T) {
 
 	mockRepo.On("AddUser").Return(nil)
 
+	mockMailer := new(MockMailer)
+	mockMailer.On("Send").Return(nil)
+
 	testUsecase := NewUserUsecase(mockRepo)
+	NewEmailUsecase(mockMailer)
 
 	err := testUsecase.AddUser(&user)
 
 	mockRepo.AssertExpectations(t)
+	mockMailer.AssertExpectations(t)
 	assert.Nil(t, err)
+	assert.Equal(t, "asdasd@asd", mockMailer.to)
+}
+
+func TestAddUserLoginTakenIgnoringCase(t *testing.T) {
+	mockRepo := &UserMockRepository{taken: []string{"alice", "bob@example.com"}}
+
+	testUsecase := NewUserUsecase(mockRepo)
+
+	err := testUsecase.AddUser(&models.User{Email: "alice@example.com", Name: "Alice", Username: "Alice", Password: "ABcd"})
+
+	assert.Equal(t, fmt.Errorf("error: already exists \"users_username_key\""), err)
+
+	err = testUsecase.AddUser(&models.User{Email: "Bob@Example.com", Name: "Bob", Username: "bob", Password: "ABcd"})
+
+	assert.Equal(t, fmt.Errorf("error: already exists \"users_email_key\""), err)
+	mockRepo.AssertNotCalled(t, "AddUser")
+}
+
+func TestAddUserUsernameLikeEmail(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	testUsecase := NewUserUsecase(mockRepo)
+
+	err := testUsecase.AddUser(&models.User{Email: "alice@example.com", Name: "Alice", Username: "bob@example.com", Password: "ABcd"})
+
+	assert.Equal(t, fmt.Errorf("error: invalid \"users_username_key\""), err)
+	mockRepo.AssertNotCalled(t, "AddUser")
+}
+
+func TestUpdateUserEmailTaken(t *testing.T) {
+	mockRepo := &UserMockRepository{taken: []string{"bob@example.com"}}
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewUserUsecase(mockRepo)
+
+	err := testUsecase.UpdateUser(models.User{ID: 1, Email: "BOB@example.com"})
+
+	assert.Equal(t, fmt.Errorf("error: already exists \"users_email_key\""), err)
+	mockRepo.AssertNotCalled(t, "UpdateUser")
 }
 
 func TestUpdateUser(t *testing.T) {
@@ -145,7 +407,49 @@ func TestUpdateUser(t *testing.T) {
 
 	updatedData := models.User{ID: 1, Email: "asdasd@asd.com", Name: "joko too", Username: "joko", FollowerCount: 1, FollowingCount: 2, Password: "ABcd"}
 
+	mockRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("UpdateUser").Return(nil)
+	mockRepo.On("UpdateProfile").Return(nil)
+	mockRepo.On("SetEmailVerifiedAt", true).Return(nil)
+
+	mockMailer := new(MockMailer)
+	mockMailer.On("Send").Return(nil)
+
+	testUsecase := NewUserUsecase(mockRepo)
+	NewEmailUsecase(mockMailer)
+
+	err := testUsecase.UpdateUser(updatedData)
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, updatedData.Email, mockMailer.to)
+}
+
+func TestUpdateUserSameEmail(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	updatedData := models.User{ID: 1, Email: "joko@joko.com", Name: "joko too"}
+
+	mockRepo.On("GetUserByID").Return(nil)
 	mockRepo.On("UpdateUser").Return(nil)
+	mockRepo.On("UpdateProfile").Return(nil)
+
+	testUsecase := NewUserUsecase(mockRepo)
+
+	err := testUsecase.UpdateUser(updatedData)
+
+	mockRepo.AssertNotCalled(t, "SetEmailVerifiedAt", true)
+	assert.Nil(t, err)
+}
+
+func TestUpdateUserProfile(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+
+	updatedData := models.User{ID: 1, Email: "joko@joko.com", Bio: "  hello  ", Website: "https://joko.dev", IsPrivate: true}
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("UpdateUser").Return(nil)
+	mockRepo.On("UpdateProfile").Return(nil)
 
 	testUsecase := NewUserUsecase(mockRepo)
 
@@ -155,17 +459,250 @@ func TestUpdateUser(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestUpdateUserInvalidProfile(t *testing.T) {
+	tests := []struct {
+		user models.User
+		err  string
+	}{
+		{models.User{ID: 1, Bio: strings.Repeat("a", 161)}, "error: too long \"users_bio_key\""},
+		{models.User{ID: 1, Location: strings.Repeat("a", 61)}, "error: too long \"users_location_key\""},
+		{models.User{ID: 1, Website: "javascript:alert(1)"}, "error: invalid \"users_website_key\""},
+		{models.User{ID: 1, Website: "joko.dev"}, "error: invalid \"users_website_key\""},
+		{models.User{ID: 1, AvatarURL: "ftp://joko.dev/a.png"}, "error: invalid \"users_avatar_url_key\""},
+	}
+
+	for _, test := range tests {
+		mockRepo := new(UserMockRepository)
+		mockRepo.On("GetUserByID").Return(nil)
+
+		testUsecase := NewUserUsecase(mockRepo)
+
+		err := testUsecase.UpdateUser(test.user)
+
+		mockRepo.AssertNotCalled(t, "UpdateUser")
+		assert.NotNil(t, err)
+		assert.Equal(t, test.err, err.Error())
+	}
+}
+
 func TestLogin(t *testing.T) {
 	mockRepo := new(UserMockRepository)
 	testUsecase := NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
 
-	mockRepo.On("GetUserByUsername").Return(nil)
+	mockRepo.On("GetUserByLogin").Return(nil)
 
 	loginData := models.User{Username: "joko", Password: "123"}
 
-	token, err := testUsecase.Login(loginData)
+	token, mfaRequired, err := testUsecase.Login(loginData, "127.0.0.1", "curl/7.68.0")
 
 	mockRepo.AssertExpectations(t)
 	assert.Nil(t, err)
+	assert.False(t, mfaRequired)
 	assert.NotNil(t, token)
 }
+
+func TestDeleteUser(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("DeleteUser").Return(nil)
+	mockRepo.On("RevokeSessions").Return(nil)
+
+	err := testUsecase.DeleteUser(1, "123")
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func TestDeleteUserWrongPassword(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	err := testUsecase.DeleteUser(1, "wrong")
+
+	mockRepo.AssertNotCalled(t, "DeleteUser")
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: incorrect \"users_password_key\"", err.Error())
+}
+
+func TestLoginReactivatesDeletedUser(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+
+	mockRepo.On("GetUserByLogin").Return(fmt.Errorf("record not found"))
+	mockRepo.On("GetDeletedUserByLogin").Return(time.Now().Add(-time.Hour), nil)
+	mockRepo.On("RestoreUser").Return(nil)
+
+	token, _, err := testUsecase.Login(models.User{Username: "joko", Password: "123"}, "127.0.0.1", "curl/7.68.0")
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+}
+
+func TestLoginDeletedUserWithTOTPEnabled(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := &UserMockRepository{totpSecret: "JBSWY3DPEHPK3PXP", totpEnabled: true}
+	mockCodeRepo := new(RecoveryCodeMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+	mfaUsecase := NewMFAUsecase(mockCodeRepo)
+	NewSessionUsecase(newSessionMockRepository())
+
+	mockRepo.On("GetUserByLogin").Return(fmt.Errorf("record not found"))
+	mockRepo.On("GetDeletedUserByLogin").Return(time.Now().Add(-time.Hour), nil)
+	mockRepo.On("GetUserByID").Return(fmt.Errorf("record not found"))
+	mockRepo.On("GetDeletedUserByID").Return(time.Now().Add(-time.Hour), nil)
+	mockCodeRepo.On("UseRecoveryCode", mock.Anything).Return(nil)
+
+	// the password alone doesn't bring the account back
+	mfaToken, mfaRequired, err := testUsecase.Login(models.User{Username: "joko", Password: "123"}, "127.0.0.1", "curl/7.68.0")
+
+	assert.Nil(t, err)
+	assert.True(t, mfaRequired)
+	mockRepo.AssertNotCalled(t, "RestoreUser")
+
+	mockRepo.On("RestoreUser").Return(nil)
+
+	token, err := mfaUsecase.VerifyLogin(mfaToken, "abcde-fghij", "127.0.0.1", "curl/7.68.0")
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+	mockRepo.AssertCalled(t, "RestoreUser")
+}
+
+func TestLoginAfterGracePeriod(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+
+	mockRepo.On("GetUserByLogin").Return(fmt.Errorf("record not found"))
+	mockRepo.On("GetDeletedUserByLogin").Return(time.Now().Add(-AccountDeletionGracePeriod-time.Hour), nil)
+
+	_, _, err := testUsecase.Login(models.User{Username: "joko", Password: "123"}, "127.0.0.1", "curl/7.68.0")
+
+	mockRepo.AssertNotCalled(t, "RestoreUser")
+	assert.NotNil(t, err)
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	mockPostRepo := new(PostMockRepository)
+	mockNotificationRepo := new(NotificationMockRepository)
+
+	NewPostUsecase(mockPostRepo)
+	NewNotificationUsecase(mockNotificationRepo)
+	testUsecase := NewUserUsecase(mockRepo)
+
+	mockRepo.On("GetUsersDeletedBefore").Return([]models.User{{ID: 1}, {ID: 2}}, nil)
+	mockRepo.On("PurgeUser").Return(nil)
+	mockPostRepo.On("GetMediaByUserID").Return([]models.Media{}, nil)
+	mockPostRepo.On("DeletePostsByUserID").Return(nil)
+
+	err := testUsecase.PurgeDeletedUsers()
+
+	mockRepo.AssertNumberOfCalls(t, "PurgeUser", 2)
+	mockPostRepo.AssertNumberOfCalls(t, "DeletePostsByUserID", 2)
+	assert.Equal(t, uint(2), mockNotificationRepo.deletedUserID)
+	assert.Nil(t, err)
+}
+
+func TestChangePassword(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+
+	mockRepo.On("GetUserByID").Return(nil)
+	mockRepo.On("UpdateUser").Return(nil)
+	mockRepo.On("RevokeSessions").Return(nil)
+
+	token, err := testUsecase.ChangePassword(1, "123", "newpassword1", "127.0.0.1", "curl/7.68.0")
+
+	mockRepo.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+}
+
+func TestChangePasswordWrongOldPassword(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	_, err := testUsecase.ChangePassword(1, "wrong", "newpassword1", "127.0.0.1", "curl/7.68.0")
+
+	mockRepo.AssertNotCalled(t, "UpdateUser")
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: incorrect \"users_password_key\"", err.Error())
+}
+
+func TestChangePasswordWeakPassword(t *testing.T) {
+	mockRepo := new(UserMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+
+	mockRepo.On("GetUserByID").Return(nil)
+
+	_, err := testUsecase.ChangePassword(1, "123", "password", "127.0.0.1", "curl/7.68.0")
+
+	mockRepo.AssertNotCalled(t, "UpdateUser")
+	assert.NotNil(t, err)
+	assert.Equal(t, "error: too weak \"users_password_key\"", err.Error())
+}
+
+func TestLoginLocksOutAccount(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := new(UserMockRepository)
+	mockAuditRepo := new(AuditMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+	auditRepo = mockAuditRepo
+
+	mockRepo.On("GetUserByLogin").Return(nil)
+	mockAuditRepo.On("AddAuditLog").Return(nil)
+
+	for i := 0; i < accountAttemptLimit; i++ {
+		_, _, err := testUsecase.Login(models.User{Username: "JOKO@joko.com", Password: "wrong"}, "127.0.0.1", "curl/7.68.0")
+		assert.Equal(t, "please provide a correct credentials", err.Error())
+	}
+
+	_, _, err := testUsecase.Login(models.User{Username: "joko", Password: "123"}, "127.0.0.2", "curl/7.68.0")
+
+	lockedOut, ok := err.(*TooManyAttemptsError)
+
+	assert.True(t, ok)
+	assert.True(t, lockedOut.RetryAfter > 0 && lockedOut.RetryAfter <= baseLockout)
+	mockAuditRepo.AssertNumberOfCalls(t, "AddAuditLog", 1)
+	assert.Equal(t, "login_lockout", mockAuditRepo.entries[0].Action)
+	assert.Equal(t, uint(1), mockAuditRepo.entries[0].UserID)
+}
+
+func TestLoginLocksOutIP(t *testing.T) {
+	loginAttempts = newLoginThrottle()
+
+	mockRepo := new(UserMockRepository)
+	mockAuditRepo := new(AuditMockRepository)
+	testUsecase := NewUserUsecase(mockRepo)
+	NewSessionUsecase(newSessionMockRepository())
+	auditRepo = mockAuditRepo
+
+	mockRepo.On("GetUserByLogin").Return(fmt.Errorf("record not found"))
+	mockRepo.On("GetDeletedUserByLogin").Return(time.Time{}, fmt.Errorf("record not found"))
+	mockAuditRepo.On("AddAuditLog").Return(nil)
+
+	for i := 0; i < ipAttemptLimit; i++ {
+		testUsecase.Login(models.User{Username: fmt.Sprintf("user%d", i), Password: "wrong"}, "10.0.0.1", "curl/7.68.0")
+	}
+
+	_, _, err := testUsecase.Login(models.User{Username: "someone else", Password: "wrong"}, "10.0.0.1", "curl/7.68.0")
+
+	_, ok := err.(*TooManyAttemptsError)
+
+	assert.True(t, ok)
+	mockAuditRepo.AssertNumberOfCalls(t, "AddAuditLog", 1)
+}
@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"summer-web/models"
+	"summer-web/webhook/repository"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// WebhookUsecase interface defines the methods that are going to be used in usecase
+type WebhookUsecase interface {
+	AddWebhook(userID uint, rawURL string, events []string) (models.Webhook, error)
+	GetWebhooks(userID uint) ([]models.Webhook, error)
+	DeleteWebhook(userID uint, id uint) error
+	GetDeliveries(userID uint, webhookID uint, beforeID uint, limit int) ([]models.WebhookDelivery, error)
+	DeliverWebhooks() error
+}
+
+var (
+	// ErrNotAdmin is returned when someone who is not an admin manages webhooks
+	ErrNotAdmin = fmt.Errorf("error: forbidden \"users_is_admin_key\"")
+	// ErrWebhookNotFound is returned when there is no webhook with the id
+	ErrWebhookNotFound = fmt.Errorf("error: not found \"webhooks_id_key\"")
+	// ErrInvalidWebhookURL is returned when the webhook URL is not an absolute http or https URL
+	ErrInvalidWebhookURL = fmt.Errorf("error: invalid \"webhooks_url_key\"")
+	// ErrInvalidWebhookEvents is returned when the webhook subscribes to no event or to an unknown one
+	ErrInvalidWebhookEvents = fmt.Errorf("error: invalid \"webhooks_events_key\"")
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{models.WebhookUserCreated, models.WebhookPostCreated, models.WebhookFollow}
+
+const (
+	// MaxWebhookAttempts is how many times a delivery is attempted before it is given up as dead
+	MaxWebhookAttempts = 10
+	// the wait before the second attempt, it doubles after every failed attempt
+	webhookRetryDelay = 30 * time.Second
+	// due deliveries are attempted in batches of this size
+	webhookBatchSize = 100
+	// at most this many webhooks are delivered to at the same time
+	webhookWorkers = 8
+	// errors longer than this are cut in the delivery log
+	maxWebhookErrorLength = 500
+)
+
+const (
+	// WebhookSignatureHeader carries the hex HMAC-SHA256 of the timestamp, a dot and the request body keyed with the
+	// webhook secret
+	WebhookSignatureHeader = "X-Summer-Signature"
+	// WebhookTimestampHeader carries the unix time the request was signed at, receivers reject old ones so a captured
+	// request can't be replayed later
+	WebhookTimestampHeader = "X-Summer-Timestamp"
+)
+
+var (
+	webhookRepo repository.WebhookRepository
+
+	webhookClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+type webhookUsecase struct{}
+
+// NewWebhookUsecase creates a new usecase to manage webhooks and deliver events to them
+func NewWebhookUsecase(repo ...repository.WebhookRepository) WebhookUsecase {
+	if len(repo) > 0 {
+		webhookRepo = repo[0]
+	} else {
+		webhookRepo = repository.NewWebhookRepository(nil)
+	}
+	return &webhookUsecase{}
+}
+
+// AddWebhook subscribes the URL to the events, the returned webhook carries the secret its payloads are signed with
+func (*webhookUsecase) AddWebhook(userID uint, rawURL string, events []string) (models.Webhook, error) {
+	if err := errIfNotAdmin(userID); err != nil {
+		return models.Webhook{}, err
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return models.Webhook{}, ErrInvalidWebhookURL
+	}
+
+	events, err = normalizeWebhookEvents(events)
+
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	secret, err := generateSecureToken()
+
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook := models.Webhook{UserID: userID, URL: parsed.String(), Events: strings.Join(events, ","), Secret: secret}
+
+	if err := webhookRepo.AddWebhook(&webhook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks lists every webhook
+func (*webhookUsecase) GetWebhooks(userID uint) ([]models.Webhook, error) {
+	if err := errIfNotAdmin(userID); err != nil {
+		return nil, err
+	}
+
+	return webhookRepo.GetWebhooks()
+}
+
+// DeleteWebhook stops deliveries to the webhook and forgets its delivery log
+func (*webhookUsecase) DeleteWebhook(userID uint, id uint) error {
+	if _, err := getWebhook(userID, id); err != nil {
+		return err
+	}
+
+	return webhookRepo.DeleteWebhook(id)
+}
+
+// GetDeliveries returns the delivery log of the webhook, newest first, up to limit deliveries older than beforeID
+func (*webhookUsecase) GetDeliveries(userID uint, webhookID uint, beforeID uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := getWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	return webhookRepo.GetDeliveries(webhookID, beforeID, PageSize(limit))
+}
+
+// DeliverWebhooks attempts the due deliveries. Failed deliveries are retried with exponential backoff until they run
+// out of attempts and are marked dead. Deliveries to the same webhook are sent one after another in order, different
+// webhooks are delivered to in parallel so a slow receiver only holds up its own deliveries
+func (*webhookUsecase) DeliverWebhooks() error {
+	deliveries, err := webhookRepo.GetDueDeliveries(time.Now(), webhookBatchSize)
+
+	if err != nil {
+		return err
+	}
+
+	queues := map[uint][]models.WebhookDelivery{}
+	webhooks := map[uint]*models.Webhook{}
+	var webhookIDs []uint
+
+	for _, delivery := range deliveries {
+		if _, ok := webhooks[delivery.WebhookID]; !ok {
+			webhook := &models.Webhook{}
+
+			if err := webhookRepo.GetWebhookByID(delivery.WebhookID, webhook); err != nil {
+				if !gorm.IsRecordNotFoundError(err) {
+					return err
+				}
+				webhook = nil
+			}
+
+			webhooks[delivery.WebhookID] = webhook
+			webhookIDs = append(webhookIDs, delivery.WebhookID)
+		}
+
+		queues[delivery.WebhookID] = append(queues[delivery.WebhookID], delivery)
+	}
+
+	jobs := make(chan uint)
+	errs := make(chan error, len(webhookIDs))
+	var wg sync.WaitGroup
+
+	for i := 0; i < webhookWorkers && i < len(webhookIDs); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for webhookID := range jobs {
+				errs <- deliverQueue(queues[webhookID], webhooks[webhookID])
+			}
+		}()
+	}
+
+	for _, webhookID := range webhookIDs {
+		jobs <- webhookID
+	}
+
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliverQueue attempts the deliveries to the webhook in order, it stops at the first one it can't record
+func deliverQueue(deliveries []models.WebhookDelivery, webhook *models.Webhook) error {
+	for i := range deliveries {
+		attemptDelivery(&deliveries[i], webhook, time.Now())
+
+		if err := webhookRepo.UpdateDelivery(&deliveries[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attemptDelivery posts the payload to the webhook and records the outcome on the delivery
+func attemptDelivery(delivery *models.WebhookDelivery, webhook *models.Webhook, now time.Time) {
+	delivery.Attempts++
+
+	if webhook == nil {
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.Error = "webhook was deleted"
+		return
+	}
+
+	status, err := postWebhook(*webhook, *delivery)
+	delivery.ResponseStatus = status
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.Error = err.Error()
+
+	if len(delivery.Error) > maxWebhookErrorLength {
+		delivery.Error = delivery.Error[:maxWebhookErrorLength]
+	}
+
+	if delivery.Attempts >= MaxWebhookAttempts {
+		delivery.Status = models.WebhookDeliveryDead
+		return
+	}
+
+	delivery.NextAttemptAt = now.Add(webhookRetryDelay << uint(delivery.Attempts-1))
+}
+
+// postWebhook returns the response status, any status other than 2xx is an error
+func postWebhook(webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewBufferString(delivery.Payload))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "summer-web-webhooks")
+	req.Header.Set("X-Summer-Event", delivery.Event)
+	req.Header.Set("X-Summer-Delivery", strconv.Itoa(int(delivery.ID)))
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := webhookClient.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "timestamp.payload" keyed with the secret, receivers compute the
+// same to check a payload came from us and was signed at that time
+func SignWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// publishWebhook queues a delivery of the event to every webhook subscribed to it, the worker sends them
+func publishWebhook(event string, data interface{}) {
+	webhooks, err := webhookRepo.GetWebhooksForEvent(event)
+
+	if err != nil {
+		log.Println("could not find webhooks for", event, err)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	payload, err := json.Marshal(map[string]interface{}{"event": event, "created_at": now, "data": data})
+
+	if err != nil {
+		log.Println("could not encode", event, err)
+		return
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(webhooks))
+
+	for i, webhook := range webhooks {
+		deliveries[i] = models.WebhookDelivery{WebhookID: webhook.ID, Event: event, Payload: string(payload), Status: models.WebhookDeliveryPending, NextAttemptAt: now}
+	}
+
+	if err := webhookRepo.AddDeliveries(deliveries); err != nil {
+		log.Println("could not queue", event, err)
+	}
+}
+
+// what webhooks are told about a new user, the rest of the account stays private
+func userWebhookData(user models.User) map[string]interface{} {
+	return map[string]interface{}{"id": user.ID, "username": user.Username, "name": user.Name, "created_at": user.CreatedAt}
+}
+
+func postWebhookData(post models.Post) map[string]interface{} {
+	return map[string]interface{}{"id": post.ID, "user_id": post.UserID, "caption": post.Caption, "created_at": post.CreatedAt}
+}
+
+func followWebhookData(follow models.Follow) map[string]interface{} {
+	return map[string]interface{}{"id": follow.ID, "follower_id": follow.FollowerID, "followee_id": follow.FolloweeID}
+}
+
+func errIfNotAdmin(userID uint) error {
+	var user models.User
+
+	if err := userRepo.GetUserByID(userID, &user); err != nil {
+		return err
+	}
+
+	if !user.IsAdmin {
+		return ErrNotAdmin
+	}
+
+	return nil
+}
+
+func getWebhook(userID uint, id uint) (models.Webhook, error) {
+	var webhook models.Webhook
+
+	if err := errIfNotAdmin(userID); err != nil {
+		return webhook, err
+	}
+
+	if err := webhookRepo.GetWebhookByID(id, &webhook); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return webhook, ErrWebhookNotFound
+		}
+		return webhook, err
+	}
+
+	return webhook, nil
+}
+
+// normalizeWebhookEvents trims and dedups the events, they all have to be known
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	var normalized []string
+	seen := map[string]bool{}
+
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+
+		if event == "" || seen[event] {
+			continue
+		}
+
+		if !isWebhookEvent(event) {
+			return nil, ErrInvalidWebhookEvents
+		}
+
+		seen[event] = true
+		normalized = append(normalized, event)
+	}
+
+	if len(normalized) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+
+	return normalized, nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"summer-web/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// WebhookMockRepository keeps webhooks and deliveries in memory
+type WebhookMockRepository struct {
+	mu         sync.Mutex
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery
+	updated    []models.WebhookDelivery
+	deletedID  uint
+}
+
+func (mock *WebhookMockRepository) AddWebhook(webhook *models.Webhook) error {
+	webhook.ID = uint(len(mock.webhooks) + 1)
+	mock.webhooks = append(mock.webhooks, *webhook)
+	return nil
+}
+
+func (mock *WebhookMockRepository) GetWebhooks() ([]models.Webhook, error) {
+	return mock.webhooks, nil
+}
+
+func (mock *WebhookMockRepository) GetWebhookByID(id uint, webhook *models.Webhook) error {
+	for _, candidate := range mock.webhooks {
+		if candidate.ID == id {
+			*webhook = candidate
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (mock *WebhookMockRepository) GetWebhooksForEvent(event string) ([]models.Webhook, error) {
+	return mock.webhooks, nil
+}
+
+func (mock *WebhookMockRepository) DeleteWebhook(id uint) error {
+	mock.deletedID = id
+	return nil
+}
+
+func (mock *WebhookMockRepository) AddDeliveries(deliveries []models.WebhookDelivery) error {
+	mock.deliveries = append(mock.deliveries, deliveries...)
+	return nil
+}
+
+func (mock *WebhookMockRepository) GetDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return mock.deliveries, nil
+}
+
+func (mock *WebhookMockRepository) GetDeliveries(webhookID uint, beforeID uint, limit int) ([]models.WebhookDelivery, error) {
+	return mock.deliveries, nil
+}
+
+func (mock *WebhookMockRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+
+	mock.updated = append(mock.updated, *delivery)
+	return nil
+}
+
+// updatedDelivery returns the last update of the delivery, webhooks are delivered to in no particular order
+func (mock *WebhookMockRepository) updatedDelivery(id uint) models.WebhookDelivery {
+	for i := len(mock.updated) - 1; i >= 0; i-- {
+		if mock.updated[i].ID == id {
+			return mock.updated[i]
+		}
+	}
+	return models.WebhookDelivery{}
+}
+
+// users, posts and follows are published to webhooks, tests that don't look at them drop them here
+func init() {
+	NewWebhookUsecase(new(WebhookMockRepository))
+}
+
+func TestAddWebhook(t *testing.T) {
+	mockRepo := new(WebhookMockRepository)
+	mockUserRepo := &UserMockRepository{isAdmin: true}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewWebhookUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	webhook, err := testUsecase.AddWebhook(1, " https://partner.example/hooks ", []string{"post.created", " follow.created", "post.created", ""})
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), webhook.ID)
+	assert.Equal(t, "https://partner.example/hooks", webhook.URL)
+	assert.Equal(t, "post.created,follow.created", webhook.Events)
+	assert.Equal(t, 64, len(webhook.Secret))
+}
+
+func TestAddWebhookNotAdmin(t *testing.T) {
+	mockRepo := new(WebhookMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewWebhookUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	_, err := testUsecase.AddWebhook(1, "https://partner.example/hooks", []string{"post.created"})
+
+	assert.Equal(t, ErrNotAdmin, err)
+	assert.Empty(t, mockRepo.webhooks)
+}
+
+func TestAddWebhookInvalid(t *testing.T) {
+	mockUserRepo := &UserMockRepository{isAdmin: true}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewWebhookUsecase(new(WebhookMockRepository))
+	NewUserUsecase(mockUserRepo)
+
+	_, err := testUsecase.AddWebhook(1, "ftp://partner.example/hooks", []string{"post.created"})
+	assert.Equal(t, ErrInvalidWebhookURL, err)
+
+	_, err = testUsecase.AddWebhook(1, "/hooks", []string{"post.created"})
+	assert.Equal(t, ErrInvalidWebhookURL, err)
+
+	_, err = testUsecase.AddWebhook(1, "https://partner.example/hooks", []string{"post.liked"})
+	assert.Equal(t, ErrInvalidWebhookEvents, err)
+
+	_, err = testUsecase.AddWebhook(1, "https://partner.example/hooks", []string{""})
+	assert.Equal(t, ErrInvalidWebhookEvents, err)
+}
+
+func TestDeleteWebhookNotFound(t *testing.T) {
+	mockRepo := new(WebhookMockRepository)
+	mockUserRepo := &UserMockRepository{isAdmin: true}
+
+	mockUserRepo.On("GetUserByID").Return(nil)
+
+	testUsecase := NewWebhookUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+
+	err := testUsecase.DeleteWebhook(1, 3)
+
+	assert.Equal(t, ErrWebhookNotFound, err)
+	assert.Equal(t, uint(0), mockRepo.deletedID)
+}
+
+func TestPublishWebhookOnFollow(t *testing.T) {
+	mockRepo := &WebhookMockRepository{webhooks: []models.Webhook{{ID: 3}, {ID: 4}}}
+	mockFollowRepo := new(FollowMockRepository)
+	mockUserRepo := new(UserMockRepository)
+
+	mockFollowRepo.On("GetFollow").Return(gorm.ErrRecordNotFound)
+	mockFollowRepo.On("AddFollow", models.FollowAccepted).Return(nil)
+	mockUserRepo.On("GetUserByID").Return(nil)
+	mockUserRepo.On("AdjustFollowCounts", 1).Return(nil)
+
+	NewWebhookUsecase(mockRepo)
+	NewUserUsecase(mockUserRepo)
+	NewBlockUsecase(new(BlockMockRepository))
+	testUsecase := NewFollowUsecase(mockFollowRepo)
+
+	_, err := testUsecase.Follow(1, 2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(mockRepo.deliveries))
+	assert.Equal(t, uint(4), mockRepo.deliveries[1].WebhookID)
+	assert.Equal(t, models.WebhookFollow, mockRepo.deliveries[0].Event)
+	assert.Equal(t, models.WebhookDeliveryPending, mockRepo.deliveries[0].Status)
+	assert.Contains(t, mockRepo.deliveries[0].Payload, `"follower_id":1`)
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	var signature, timestamp, event string
+	var body []byte
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		signature = req.Header.Get(WebhookSignatureHeader)
+		timestamp = req.Header.Get(WebhookTimestampHeader)
+		event = req.Header.Get("X-Summer-Event")
+		body, _ = ioutil.ReadAll(req.Body)
+	}))
+	defer receiver.Close()
+
+	mockRepo := &WebhookMockRepository{
+		webhooks:   []models.Webhook{{ID: 3, URL: receiver.URL, Secret: "s3cret"}},
+		deliveries: []models.WebhookDelivery{{ID: 7, WebhookID: 3, Event: models.WebhookPostCreated, Payload: `{"event":"post.created"}`, Status: models.WebhookDeliveryPending}},
+	}
+
+	testUsecase := NewWebhookUsecase(mockRepo)
+
+	err := testUsecase.DeliverWebhooks()
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"event":"post.created"}`, string(body))
+	assert.Equal(t, models.WebhookPostCreated, event)
+	assert.Equal(t, "sha256="+SignWebhookPayload("s3cret", timestamp, body), signature)
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+
+	assert.Nil(t, err)
+	assert.InDelta(t, time.Now().Unix(), signedAt, 5)
+	assert.Equal(t, models.WebhookDeliveryDelivered, mockRepo.updated[0].Status)
+	assert.Equal(t, http.StatusOK, mockRepo.updated[0].ResponseStatus)
+	assert.Equal(t, 1, mockRepo.updated[0].Attempts)
+	assert.NotNil(t, mockRepo.updated[0].DeliveredAt)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// receivers compute the HMAC of the timestamp, a dot and the body
+	assert.Equal(t, "27ea0c444df424d4e9bdb205d272b4d5517671648a24442a5c6cdc22275b7048", SignWebhookPayload("s3cret", "1600000000", []byte(`{}`)))
+}
+
+func TestDeliverWebhooksRetriesWithBackoff(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	mockRepo := &WebhookMockRepository{
+		webhooks: []models.Webhook{{ID: 3, URL: receiver.URL}},
+		deliveries: []models.WebhookDelivery{
+			{ID: 7, WebhookID: 3, Status: models.WebhookDeliveryPending, Attempts: 2},
+			{ID: 8, WebhookID: 3, Status: models.WebhookDeliveryPending, Attempts: MaxWebhookAttempts - 1},
+			{ID: 9, WebhookID: 5, Status: models.WebhookDeliveryPending},
+		},
+	}
+
+	testUsecase := NewWebhookUsecase(mockRepo)
+
+	before := time.Now()
+	err := testUsecase.DeliverWebhooks()
+
+	assert.Nil(t, err)
+
+	retried := mockRepo.updatedDelivery(7)
+	assert.Equal(t, models.WebhookDeliveryPending, retried.Status)
+	assert.Equal(t, 3, retried.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, retried.ResponseStatus)
+	assert.Equal(t, "unexpected response status 503", retried.Error)
+	assert.WithinDuration(t, before.Add(4*webhookRetryDelay), retried.NextAttemptAt, time.Second)
+
+	assert.Equal(t, models.WebhookDeliveryDead, mockRepo.updatedDelivery(8).Status)
+	assert.Equal(t, MaxWebhookAttempts, mockRepo.updatedDelivery(8).Attempts)
+
+	assert.Equal(t, models.WebhookDeliveryDead, mockRepo.updatedDelivery(9).Status)
+	assert.Equal(t, "webhook was deleted", mockRepo.updatedDelivery(9).Error)
+}
+
+func TestDeliverWebhooksInParallel(t *testing.T) {
+	fastDelivered := make(chan struct{})
+
+	// the slow receiver only answers once the fast one got its delivery, which never happens one webhook after another
+	slow := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		select {
+		case <-fastDelivered:
+		case <-time.After(5 * time.Second):
+			resp.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		close(fastDelivered)
+	}))
+	defer fast.Close()
+
+	mockRepo := &WebhookMockRepository{
+		webhooks: []models.Webhook{{ID: 3, URL: slow.URL}, {ID: 5, URL: fast.URL}},
+		deliveries: []models.WebhookDelivery{
+			{ID: 7, WebhookID: 3, Status: models.WebhookDeliveryPending},
+			{ID: 8, WebhookID: 5, Status: models.WebhookDeliveryPending},
+		},
+	}
+
+	testUsecase := NewWebhookUsecase(mockRepo)
+
+	err := testUsecase.DeliverWebhooks()
+
+	assert.Nil(t, err)
+	assert.Equal(t, models.WebhookDeliveryDelivered, mockRepo.updatedDelivery(7).Status)
+	assert.Equal(t, models.WebhookDeliveryDelivered, mockRepo.updatedDelivery(8).Status)
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"summer-web/models"
 
@@ -17,6 +19,31 @@ type UserRepository interface {
 	AddUser(user *models.User) error
 	GetUserByUsername(username string, user *models.User) error
 	UpdateUser(updatedUser models.User) error
+	DeleteUser(id uint) error
+	GetUserByLogin(login string, user *models.User) error
+	GetDeletedUserByLogin(login string, user *models.User) error
+	GetDeletedUserByID(id uint, user *models.User) error
+	RestoreUser(id uint) error
+	GetUsersDeletedBefore(deadline time.Time) ([]models.User, error)
+	PurgeUser(id uint) error
+	SetEmailVerifiedAt(id uint, verifiedAt *time.Time) error
+	GetUserByEmail(email string, user *models.User) error
+	RevokeSessions(id uint, revokedAt time.Time) error
+	SetTOTP(id uint, secret string, enabled bool) error
+	UseTOTPStep(id uint, step int64) (bool, error)
+	SetMFANonce(id uint, nonce string) error
+	UseMFANonce(id uint, nonce string) (bool, error)
+	UpdateProfile(user models.User) error
+	SetAvatar(id uint, key string) error
+	AdjustFollowCounts(followerID uint, followeeID uint, delta int) error
+	IsUsernameTaken(username string, exceptID uint) (bool, error)
+	IsEmailTaken(email string, exceptID uint) (bool, error)
+}
+
+// logins are looked up ignoring case, so they have to be unique ignoring case too
+var migrations = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_lower_username ON users (LOWER(username))`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_lower_email ON users (LOWER(email))`,
 }
 
 func init() {
@@ -30,6 +57,12 @@ func init() {
 	defer db.Close()
 
 	db.AutoMigrate(&models.User{})
+
+	for _, migration := range migrations {
+		if err := db.Exec(migration).Error; err != nil {
+			fmt.Println(err.Error())
+		}
+	}
 }
 
 type repo struct {
@@ -59,11 +92,187 @@ func (r *repo) AddUser(user *models.User) error {
 	return r.db.Create(&user).Error
 }
 
-// GetUserByUsername returns an error if there is any, otherwise modifies the user parameter with the found record
+// GetUserByUsername returns an error if there is any, otherwise modifies the user parameter with the found record, the username is matched case-insensitively
 func (r *repo) GetUserByUsername(username string, user *models.User) error {
-	return r.db.Where("username = ?", username).Find(&user).Error
+	return r.db.Where("LOWER(username) = LOWER(?)", username).Find(&user).Error
 }
 
 func (r *repo) UpdateUser(updatedData models.User) error {
 	return r.db.Model(&updatedData).Updates(updatedData).Error
 }
+
+// DeleteUser soft deletes the user record by setting its deleted_at column
+func (r *repo) DeleteUser(id uint) error {
+	return r.db.Delete(&models.User{ID: id}).Error
+}
+
+// GetUserByLogin returns an error if there is any, otherwise modifies the user parameter with the record whose username or email matches, ignoring case
+func (r *repo) GetUserByLogin(login string, user *models.User) error {
+	return r.db.Where("LOWER(username) = LOWER(?) OR LOWER(email) = LOWER(?)", login, login).Find(user).Error
+}
+
+// GetDeletedUserByLogin returns an error if there is any, otherwise modifies the user parameter with the soft deleted record whose username or email matches
+func (r *repo) GetDeletedUserByLogin(login string, user *models.User) error {
+	return r.db.Unscoped().Where("(LOWER(username) = LOWER(?) OR LOWER(email) = LOWER(?)) AND deleted_at IS NOT NULL", login, login).Find(user).Error
+}
+
+// GetDeletedUserByID returns an error unless the user with the id is soft deleted, otherwise modifies the user parameter
+func (r *repo) GetDeletedUserByID(id uint, user *models.User) error {
+	return r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Find(user).Error
+}
+
+// RestoreUser clears the deleted_at column of a soft deleted user record
+func (r *repo) RestoreUser(id uint) error {
+	return r.db.Unscoped().Model(&models.User{ID: id}).Update("deleted_at", nil).Error
+}
+
+// GetUsersDeletedBefore returns all soft deleted user records that were deleted before the deadline
+func (r *repo) GetUsersDeletedBefore(deadline time.Time) ([]models.User, error) {
+	var users []models.User
+
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", deadline).Find(&users).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// PurgeUser permanently removes the user record, freeing its username and email for reuse. The follows, blocks, mutes,
+// sessions, tokens, identities, recovery codes, suggestions and webhooks of the user go with it, and the users on the
+// other side of its follows are counted one follow less
+func (r *repo) PurgeUser(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.User{}).Where("id IN (SELECT followee_id FROM follows WHERE follower_id = ? AND status = ?)", id, models.FollowAccepted).
+			UpdateColumn("follower_count", gorm.Expr("follower_count - 1")).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.User{}).Where("id IN (SELECT follower_id FROM follows WHERE followee_id = ? AND status = ?)", id, models.FollowAccepted).
+			UpdateColumn("following_count", gorm.Expr("following_count - 1")).Error; err != nil {
+			return err
+		}
+
+		owned := []struct {
+			model interface{}
+			where string
+		}{
+			{&models.Follow{}, "follower_id = ? OR followee_id = ?"},
+			{&models.Block{}, "blocker_id = ? OR blocked_id = ?"},
+			{&models.Mute{}, "muter_id = ? OR muted_id = ?"},
+			{&models.Suggestion{}, "user_id = ? OR suggested_id = ?"},
+			{&models.Session{}, "user_id = ?"},
+			{&models.AccessToken{}, "user_id = ?"},
+			{&models.Identity{}, "user_id = ?"},
+			{&models.RecoveryCode{}, "user_id = ?"},
+			{&models.PasswordReset{}, "user_id = ?"},
+			{&models.WebhookDelivery{}, "webhook_id IN (SELECT id FROM webhooks WHERE user_id = ?)"},
+			{&models.Webhook{}, "user_id = ?"},
+		}
+
+		for _, rows := range owned {
+			// every placeholder is the user
+			args := make([]interface{}, strings.Count(rows.where, "?"))
+
+			for i := range args {
+				args[i] = id
+			}
+
+			if err := tx.Where(rows.where, args...).Delete(rows.model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&models.User{ID: id}).Error
+	})
+}
+
+// SetEmailVerifiedAt marks the user's email as verified at the given time, a nil time marks it as unverified
+func (r *repo) SetEmailVerifiedAt(id uint, verifiedAt *time.Time) error {
+	return r.db.Model(&models.User{ID: id}).Update("email_verified_at", verifiedAt).Error
+}
+
+// GetUserByEmail returns an error if there is any, otherwise modifies the user parameter with the found record
+func (r *repo) GetUserByEmail(email string, user *models.User) error {
+	return r.db.Where("LOWER(email) = LOWER(?)", email).Find(user).Error
+}
+
+// RevokeSessions invalidates every personal access token of the user that was created before revokedAt, login sessions are revoked in the sessions table
+func (r *repo) RevokeSessions(id uint, revokedAt time.Time) error {
+	return r.db.Model(&models.User{ID: id}).Update("sessions_revoked_at", revokedAt).Error
+}
+
+// UpdateProfile saves the profile fields including empty and false values, which UpdateUser skips
+func (r *repo) UpdateProfile(user models.User) error {
+	return r.db.Model(&models.User{ID: user.ID}).Updates(map[string]interface{}{
+		"bio":        user.Bio,
+		"website":    user.Website,
+		"location":   user.Location,
+		"avatar_url": user.AvatarURL,
+		"is_private": user.IsPrivate,
+	}).Error
+}
+
+// SetAvatar stores the key the uploaded avatar sizes are derived from, the avatar URL set on the profile is cleared
+// since the upload replaces it
+func (r *repo) SetAvatar(id uint, key string) error {
+	return r.db.Model(&models.User{ID: id}).Updates(map[string]interface{}{"avatar_key": key, "avatar_url": ""}).Error
+}
+
+// AdjustFollowCounts adds delta to the following count of the follower and to the follower count of the followed user
+func (r *repo) AdjustFollowCounts(followerID uint, followeeID uint, delta int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", followerID).UpdateColumn("following_count", gorm.Expr("following_count + ?", delta)).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", followeeID).UpdateColumn("follower_count", gorm.Expr("follower_count + ?", delta)).Error
+	})
+}
+
+// SetTOTP stores the user's TOTP secret and whether it is required when logging in
+func (r *repo) SetTOTP(id uint, secret string, enabled bool) error {
+	return r.db.Model(&models.User{ID: id}).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": enabled}).Error
+}
+
+// UseTOTPStep records that a code of the time step was accepted, it returns false if a code of the step or of a later one
+// was accepted before, so every code works only once
+func (r *repo) UseTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Unscoped().Model(&models.User{}).Where("id = ? AND totp_last_step < ?", id, step).UpdateColumn("totp_last_step", step)
+
+	return result.RowsAffected == 1, result.Error
+}
+
+// SetMFANonce stores the nonce of the user's latest mfa pending token, the ones issued before stop working. Deleted
+// users log in with one too, to be reactivated
+func (r *repo) SetMFANonce(id uint, nonce string) error {
+	return r.db.Unscoped().Model(&models.User{}).Where("id = ?", id).UpdateColumn("mfa_nonce", nonce).Error
+}
+
+// UseMFANonce clears the nonce of the mfa pending token, it returns false if the token was used already or replaced
+func (r *repo) UseMFANonce(id uint, nonce string) (bool, error) {
+	result := r.db.Unscoped().Model(&models.User{}).Where("id = ? AND mfa_nonce = ? AND mfa_nonce <> ''", id, nonce).UpdateColumn("mfa_nonce", "")
+
+	return result.RowsAffected == 1, result.Error
+}
+
+// IsUsernameTaken tells whether a user other than exceptID has the username in any case, deleted users keep theirs
+// until they are purged
+func (r *repo) IsUsernameTaken(username string, exceptID uint) (bool, error) {
+	var count int
+
+	err := r.db.Unscoped().Model(&models.User{}).Where("LOWER(username) = LOWER(?) AND id <> ?", username, exceptID).Count(&count).Error
+
+	return count > 0, err
+}
+
+// IsEmailTaken tells whether a user other than exceptID has the email in any case, deleted users keep theirs until
+// they are purged
+func (r *repo) IsEmailTaken(email string, exceptID uint) (bool, error) {
+	var count int
+
+	err := r.db.Unscoped().Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", email, exceptID).Count(&count).Error
+
+	return count > 0, err
+}
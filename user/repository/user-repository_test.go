@@ -62,14 +62,14 @@ func TestGetUserByUsername(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "username", "name", "email", "password", "follower_count", "following_count", "created_at", "updated_at", "deleted_at"}).
 		AddRow(1, "test1", "test1", "test1@test1.com", "test1", 1, 1, time.Now(), time.Now(), nil)
 
-	const sqlSelectByUsername = `SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL AND ((username = $1))`
+	const sqlSelectByUsername = `SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL AND ((LOWER(username) = LOWER($1)))`
 	const username = "test1"
 
 	user := models.User{}
 
-	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectByUsername)).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectByUsername)).WithArgs("TEST1").WillReturnRows(rows)
 
-	err := userRepo.GetUserByUsername(username, &user)
+	err := userRepo.GetUserByUsername("TEST1", &user)
 
 	assert.Nil(t, err)
 	assert.Equal(t, username, user.Username)
@@ -80,10 +80,10 @@ func TestAddUser(t *testing.T) {
 
 	user := models.User{Username: "test1", Name: "test1", Email: "test1@test1.com", Password: "test1", FollowerCount: 1, FollowingCount: 1, CreatedAt: time.Now(), UpdatedAt: time.Now(), DeletedAt: nil}
 	newID := uint(1)
-	const sqlInsert = `INSERT INTO "users" ("username","name","email","password","follower_count","following_count","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING "users"."id"`
+	const sqlInsert = `INSERT INTO "users" ("username","name","email","password","follower_count","following_count","bio","website","location","avatar_url","avatar_key","is_private","email_verified_at","sessions_revoked_at","totp_secret","totp_enabled","totp_last_step","mfa_nonce","is_admin","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22) RETURNING "users"."id"`
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(user.Username, user.Name, user.Email, user.Password, user.FollowerCount, user.FollowingCount, user.CreatedAt, user.UpdatedAt, user.DeletedAt).
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(user.Username, user.Name, user.Email, user.Password, user.FollowerCount, user.FollowingCount, user.Bio, user.Website, user.Location, user.AvatarURL, user.AvatarKey, user.IsPrivate, user.EmailVerifiedAt, user.SessionsRevokedAt, user.TOTPSecret, user.TOTPEnabled, user.TOTPLastStep, user.MFANonce, user.IsAdmin, user.CreatedAt, user.UpdatedAt, user.DeletedAt).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(newID))
 	mock.ExpectCommit()
 
@@ -109,3 +109,265 @@ func TestUpdateUser(t *testing.T) {
 
 	assert.Nil(t, err)
 }
+
+func TestDeleteUser(t *testing.T) {
+	setup()
+
+	const sqlSoftDelete = `UPDATE "users" SET "deleted_at"=$1  WHERE "users"."deleted_at" IS NULL AND "users"."id" = $2`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlSoftDelete)).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.DeleteUser(1)
+
+	assert.Nil(t, err)
+}
+
+func TestGetUserByLogin(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "username", "name", "email"}).
+		AddRow(1, "test1", "test1", "test1@test1.com")
+
+	const sqlSelectByLogin = `SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL AND ((LOWER(username) = LOWER($1) OR LOWER(email) = LOWER($2)))`
+
+	user := models.User{}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectByLogin)).WithArgs("TEST1@test1.com", "TEST1@test1.com").WillReturnRows(rows)
+
+	err := userRepo.GetUserByLogin("TEST1@test1.com", &user)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "test1", user.Username)
+}
+
+func TestGetDeletedUserByLogin(t *testing.T) {
+	setup()
+
+	deletedAt := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "username", "name", "email", "password", "follower_count", "following_count", "created_at", "updated_at", "deleted_at"}).
+		AddRow(1, "test1", "test1", "test1@test1.com", "test1", 1, 1, time.Now(), time.Now(), deletedAt)
+
+	const sqlSelectDeleted = `SELECT * FROM "users" WHERE ((LOWER(username) = LOWER($1) OR LOWER(email) = LOWER($2)) AND deleted_at IS NOT NULL)`
+
+	user := models.User{}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectDeleted)).WithArgs("test1", "test1").WillReturnRows(rows)
+
+	err := userRepo.GetDeletedUserByLogin("test1", &user)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, user.DeletedAt)
+}
+
+func TestGetUsersDeletedBefore(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "username", "deleted_at"}).
+		AddRow(1, "test1", time.Now()).AddRow(2, "test2", time.Now())
+
+	const sqlSelectExpired = `SELECT * FROM "users" WHERE (deleted_at IS NOT NULL AND deleted_at < $1)`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectExpired)).WillReturnRows(rows)
+
+	users, err := userRepo.GetUsersDeletedBefore(time.Now())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(users))
+}
+
+func TestPurgeUser(t *testing.T) {
+	setup()
+
+	const sqlFollowees = `UPDATE "users" SET "follower_count" = follower_count - 1 WHERE (id IN (SELECT followee_id FROM follows WHERE follower_id = $1 AND status = $2))`
+	const sqlFollowers = `UPDATE "users" SET "following_count" = following_count - 1 WHERE (id IN (SELECT follower_id FROM follows WHERE followee_id = $1 AND status = $2))`
+	const sqlDelete = `DELETE FROM "users"  WHERE "users"."id" = $1`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlFollowees)).WithArgs(1, models.FollowAccepted).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(sqlFollowers)).WithArgs(1, models.FollowAccepted).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "follows"  WHERE (follower_id = $1 OR followee_id = $2)`)).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "blocks"  WHERE (blocker_id = $1 OR blocked_id = $2)`)).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mutes"  WHERE (muter_id = $1 OR muted_id = $2)`)).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "suggestions"  WHERE (user_id = $1 OR suggested_id = $2)`)).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "sessions"  WHERE (user_id = $1)`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "access_tokens"  WHERE (user_id = $1)`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "identities"  WHERE (user_id = $1)`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "recovery_codes"  WHERE (user_id = $1)`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "password_resets"  WHERE (user_id = $1)`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhook_deliveries"  WHERE (webhook_id IN (SELECT id FROM webhooks WHERE user_id = $1))`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhooks"  WHERE (user_id = $1)`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDelete)).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.PurgeUser(1)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSetEmailVerifiedAt(t *testing.T) {
+	setup()
+
+	verifiedAt := time.Now()
+	const sqlUpdate = `UPDATE "users" SET "email_verified_at" = $1, "updated_at" = $2 WHERE "users"."deleted_at" IS NULL AND "users"."id" = $3`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(&verifiedAt, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.SetEmailVerifiedAt(1, &verifiedAt)
+
+	assert.Nil(t, err)
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	setup()
+
+	rows := sqlmock.NewRows([]string{"id", "username", "name", "email"}).
+		AddRow(1, "test1", "test1", "test1@test1.com")
+
+	const sqlSelectByEmail = `SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL AND ((LOWER(email) = LOWER($1)))`
+
+	user := models.User{}
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelectByEmail)).WithArgs("Test1@Test1.com").WillReturnRows(rows)
+
+	err := userRepo.GetUserByEmail("Test1@Test1.com", &user)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), user.ID)
+}
+
+func TestRevokeSessions(t *testing.T) {
+	setup()
+
+	revokedAt := time.Now()
+	const sqlUpdate = `UPDATE "users" SET "sessions_revoked_at" = $1, "updated_at" = $2 WHERE "users"."deleted_at" IS NULL AND "users"."id" = $3`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(revokedAt, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.RevokeSessions(1, revokedAt)
+
+	assert.Nil(t, err)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "users" SET "avatar_url" = $1, "bio" = $2, "is_private" = $3, "location" = $4, "updated_at" = $5, "website" = $6 WHERE "users"."deleted_at" IS NULL AND "users"."id" = $7`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs("", "", false, "Jakarta", sqlmock.AnyArg(), "", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.UpdateProfile(models.User{ID: 1, Location: "Jakarta"})
+
+	assert.Nil(t, err)
+}
+
+func TestSetAvatar(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "users" SET "avatar_key" = $1, "avatar_url" = $2, "updated_at" = $3 WHERE "users"."deleted_at" IS NULL AND "users"."id" = $4`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs("avatars/1/abc.png", "", sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.SetAvatar(1, "avatars/1/abc.png")
+
+	assert.Nil(t, err)
+}
+
+func TestAdjustFollowCounts(t *testing.T) {
+	setup()
+
+	const sqlFollowing = `UPDATE "users" SET "following_count" = following_count + $1 WHERE "users"."deleted_at" IS NULL AND ((id = $2))`
+	const sqlFollower = `UPDATE "users" SET "follower_count" = follower_count + $1 WHERE "users"."deleted_at" IS NULL AND ((id = $2))`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlFollowing)).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(sqlFollower)).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.AdjustFollowCounts(1, 2, 1)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSetTOTP(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "users" SET "totp_enabled" = $1, "totp_secret" = $2, "updated_at" = $3 WHERE "users"."deleted_at" IS NULL AND "users"."id" = $4`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(true, "SECRET", sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := userRepo.SetTOTP(1, "SECRET", true)
+
+	assert.Nil(t, err)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "users" SET "totp_last_step" = $1 WHERE (id = $2 AND totp_last_step < $3)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(53333333, 1, 53333333).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	accepted, err := userRepo.UseTOTPStep(1, 53333333)
+
+	assert.Nil(t, err)
+	assert.False(t, accepted)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUseMFANonce(t *testing.T) {
+	setup()
+
+	const sqlUpdate = `UPDATE "users" SET "mfa_nonce" = $1 WHERE (id = $2 AND mfa_nonce = $3 AND mfa_nonce <> '')`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs("", 1, "n0nce").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	used, err := userRepo.UseMFANonce(1, "n0nce")
+
+	assert.Nil(t, err)
+	assert.True(t, used)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestIsUsernameTaken(t *testing.T) {
+	setup()
+
+	const sqlCount = `SELECT count(*) FROM "users" WHERE (LOWER(username) = LOWER($1) AND id <> $2)`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCount)).WithArgs("Alice", 0).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	taken, err := userRepo.IsUsernameTaken("Alice", 0)
+
+	assert.Nil(t, err)
+	assert.True(t, taken)
+}
+
+func TestIsEmailTaken(t *testing.T) {
+	setup()
+
+	const sqlCount = `SELECT count(*) FROM "users" WHERE (LOWER(email) = LOWER($1) AND id <> $2)`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlCount)).WithArgs("Alice@Example.com", 3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	taken, err := userRepo.IsEmailTaken("Alice@Example.com", 3)
+
+	assert.Nil(t, err)
+	assert.False(t, taken)
+}
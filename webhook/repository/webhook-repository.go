@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"summer-web/models"
+
+	"github.com/jinzhu/gorm"
+	// import postgres dialect from gorm lib
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// WebhookRepository is the repository interface for webhook
+type WebhookRepository interface {
+	AddWebhook(webhook *models.Webhook) error
+	GetWebhooks() ([]models.Webhook, error)
+	GetWebhookByID(id uint, webhook *models.Webhook) error
+	GetWebhooksForEvent(event string) ([]models.Webhook, error)
+	DeleteWebhook(id uint) error
+	AddDeliveries(deliveries []models.WebhookDelivery) error
+	GetDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	GetDeliveries(webhookID uint, beforeID uint, limit int) ([]models.WebhookDelivery, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+}
+
+func init() {
+	db, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+
+	if err != nil {
+		fmt.Println(err.Error())
+		panic("Failed to connect to database")
+	}
+
+	defer db.Close()
+
+	db.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{})
+}
+
+type repo struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository create a new webhook repository to fiddle around with database
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	if db == nil {
+		gdb, err := gorm.Open("postgres", os.Getenv("DB_CONNECTION_STRING"))
+		if err != nil {
+			fmt.Println(err.Error())
+			panic("Could not connect to database")
+		}
+		return &repo{db: gdb}
+	}
+	return &repo{db: db}
+}
+
+// AddWebhook returns an error if there is any, otherwise creates a new webhook record into database
+func (r *repo) AddWebhook(webhook *models.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// GetWebhooks returns every webhook, oldest first
+func (r *repo) GetWebhooks() ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+
+	err := r.db.Order("id").Find(&webhooks).Error
+
+	return webhooks, err
+}
+
+// GetWebhookByID returns an error if there is no webhook with the id, otherwise modifies the webhook parameter
+func (r *repo) GetWebhookByID(id uint, webhook *models.Webhook) error {
+	return r.db.Where("id = ?", id).First(webhook).Error
+}
+
+// GetWebhooksForEvent returns the webhooks subscribed to the event
+func (r *repo) GetWebhooksForEvent(event string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+
+	err := r.db.Where("',' || events || ',' LIKE ?", "%,"+event+",%").Order("id").Find(&webhooks).Error
+
+	return webhooks, err
+}
+
+// DeleteWebhook deletes the webhook along with its delivery log
+func (r *repo) DeleteWebhook(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("id = ?", id).Delete(&models.Webhook{}).Error
+	})
+}
+
+// AddDeliveries creates the deliveries of one event all at once, or none of them
+func (r *repo) AddDeliveries(deliveries []models.WebhookDelivery) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range deliveries {
+			if err := tx.Create(&deliveries[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetDueDeliveries returns up to limit pending deliveries whose next attempt is due, oldest first
+func (r *repo) GetDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("id").Limit(limit).Find(&deliveries).Error
+
+	return deliveries, err
+}
+
+// GetDeliveries returns the delivery log of the webhook, newest first, up to limit of them and older than the
+// delivery beforeID unless it is 0
+func (r *repo) GetDeliveries(webhookID uint, beforeID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	query := r.db.Where("webhook_id = ?", webhookID)
+
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	err := query.Order("id desc").Limit(limit).Find(&deliveries).Error
+
+	return deliveries, err
+}
+
+// UpdateDelivery saves the outcome of the last attempt of the delivery
+func (r *repo) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
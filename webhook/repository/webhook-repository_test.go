@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"summer-web/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	webhookRepo WebhookRepository
+	mock        sqlmock.Sqlmock
+	db          *sql.DB
+	gdb         *gorm.DB
+	err         error
+)
+
+func setup() {
+	db, mock, err = sqlmock.New()
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	gdb, err = gorm.Open("postgres", db)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	webhookRepo = NewWebhookRepository(gdb)
+}
+
+func TestAddWebhook(t *testing.T) {
+	setup()
+
+	webhook := models.Webhook{UserID: 1, URL: "https://partner.example/hooks", Events: "user.created,follow.created", Secret: "s3cret"}
+
+	const sqlInsert = `INSERT INTO "webhooks" ("user_id","url","events","secret","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "webhooks"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(1, webhook.URL, webhook.Events, "s3cret", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	err := webhookRepo.AddWebhook(&webhook)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), webhook.ID)
+}
+
+func TestGetWebhooksForEvent(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "webhooks"  WHERE (',' || events || ',' LIKE $1) ORDER BY "id"`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("%,post.created,%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "events"}).AddRow(3, "https://partner.example/hooks", "post.created"))
+
+	webhooks, err := webhookRepo.GetWebhooksForEvent(models.WebhookPostCreated)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(webhooks))
+	assert.Equal(t, uint(3), webhooks[0].ID)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	setup()
+
+	const sqlDeleteDeliveries = `DELETE FROM "webhook_deliveries"  WHERE (webhook_id = $1)`
+	const sqlDeleteWebhook = `DELETE FROM "webhooks"  WHERE (id = $1)`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteDeliveries)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(sqlDeleteWebhook)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := webhookRepo.DeleteWebhook(3)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestAddDeliveries(t *testing.T) {
+	setup()
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	deliveries := []models.WebhookDelivery{
+		{WebhookID: 3, Event: models.WebhookFollow, Payload: "{}", Status: models.WebhookDeliveryPending, NextAttemptAt: now},
+		{WebhookID: 4, Event: models.WebhookFollow, Payload: "{}", Status: models.WebhookDeliveryPending, NextAttemptAt: now},
+	}
+
+	const sqlInsert = `INSERT INTO "webhook_deliveries" ("webhook_id","event","payload","status","attempts","next_attempt_at","response_status","error","delivered_at","created_at","updated_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING "webhook_deliveries"."id"`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(3, models.WebhookFollow, "{}", models.WebhookDeliveryPending, 0, now, 0, "", nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(4, models.WebhookFollow, "{}", models.WebhookDeliveryPending, 0, now, 0, "", nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectCommit()
+
+	err := webhookRepo.AddDeliveries(deliveries)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(8), deliveries[1].ID)
+}
+
+func TestGetDueDeliveries(t *testing.T) {
+	setup()
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	const sqlSelect = `SELECT * FROM "webhook_deliveries"  WHERE (status = $1 AND next_attempt_at <= $2) ORDER BY "id" LIMIT 100`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(models.WebhookDeliveryPending, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status"}).AddRow(7, 3, models.WebhookDeliveryPending))
+
+	deliveries, err := webhookRepo.GetDueDeliveries(now, 100)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(7), deliveries[0].ID)
+}
+
+func TestGetDeliveries(t *testing.T) {
+	setup()
+
+	const sqlSelect = `SELECT * FROM "webhook_deliveries"  WHERE (webhook_id = $1) AND (id < $2) ORDER BY id desc LIMIT 20`
+
+	mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(3, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id"}).AddRow(8, 3).AddRow(7, 3))
+
+	deliveries, err := webhookRepo.GetDeliveries(3, 9, 20)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(deliveries))
+}
+
+func TestUpdateDelivery(t *testing.T) {
+	setup()
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	delivery := models.WebhookDelivery{ID: 7, WebhookID: 3, Event: models.WebhookFollow, Payload: "{}", Status: models.WebhookDeliveryDelivered, Attempts: 1, NextAttemptAt: now, ResponseStatus: 200, DeliveredAt: &now, CreatedAt: now}
+
+	const sqlUpdate = `UPDATE "webhook_deliveries" SET "webhook_id" = $1, "event" = $2, "payload" = $3, "status" = $4, "attempts" = $5, "next_attempt_at" = $6, "response_status" = $7, "error" = $8, "delivered_at" = $9, "created_at" = $10, "updated_at" = $11  WHERE "webhook_deliveries"."id" = $12`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(sqlUpdate)).WithArgs(3, models.WebhookFollow, "{}", models.WebhookDeliveryDelivered, 1, now, 200, "", now, now, sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := webhookRepo.UpdateDelivery(&delivery)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}